@@ -4,25 +4,84 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/mapassets"
 	"github.com/mihnpro/Hackathon_TMX/internal/services"
 )
 
 func main() {
 	// Парсим аргументы командной строки
 	var (
-		task       = flag.String("task", "all", "Задача для выполнения: 1, 2, 3, all")
+		task       = flag.String("task", "all", "Задача для выполнения: 1, 2, 3, diff, util, all")
 		dataPath   = flag.String("data", "./data/locomotives_displacement.csv", "Путь к файлу с данными")
 		depoForMap = flag.String("depo", "940006", "ID депо для визуализации (для задачи 3)")
 		maxLoco    = flag.Int("max", 10, "Максимальное количество локомотивов на карте")
+		similarity = flag.Float64("similarity", 0.5, "Порог схожести маршрутов 0..1 (для задачи 2)")
+		variants   = flag.Bool("variants", false, "Считать варианты маршрута отдельными направлениями (для задачи 2)")
+		baseline   = flag.String("baseline", "depot", "Базовое распределение для проверки любимого направления: depot, uniform (для задачи 2)")
+		basemap    = flag.String("basemap", "osm", "Подложка карт: osm - тайлы OpenStreetMap, local - без тайлов, по данным (для задачи 3)")
+		heatMetric = flag.String("heat-metric", "visits", "Метрика тепловой карты: visits, total_visits, locomotives, dwell, trips_per_day (для задачи 3)")
+		heatSeries = flag.String("series", "", "Серии локомотивов тепловой карты через запятую, пусто - все (для задачи 3)")
+		topSegs    = flag.Int("top-segments", 20, "Размер таблицы самых загруженных перегонов (для задачи 3)")
+		strict     = flag.Bool("strict", false, "Не выдумывать координаты станций: без station_info.csv - ошибка (для задачи 3)")
+
+		// Параметры отчета об использовании парка (для util)
+		utilDepo = flag.String("util-depo", "", "Код депо для отчета об использовании, пусто - все депо (для util)")
+
+		// Параметры сравнения веток (для diff)
+		dataPath2 = flag.String("data2", "", "Путь ко второму файлу с данными (для diff)")
+		from      = flag.String("from", "", "Начало базового периода включительно, YYYY-MM-DD (для diff, util и тепловой карты задачи 3)")
+		to        = flag.String("to", "", "Конец базового периода не включительно, YYYY-MM-DD: -to 2024-02-01 - по 31 января (для diff, util и тепловой карты задачи 3)")
+		from2     = flag.String("from2", "", "Начало сравниваемого периода включительно, YYYY-MM-DD (для diff)")
+		to2       = flag.String("to2", "", "Конец сравниваемого периода не включительно, YYYY-MM-DD (для diff)")
 	)
 	flag.Parse()
 
+	periodFrom, periodTo := mustParsePeriod(*from, *to, "-from", "-to")
+	period2From, period2To := mustParsePeriod(*from2, *to2, "-from2", "-to2")
+
+	directionOpts := domain.DirectionOptions{
+		MinSimilarity: *similarity,
+		SplitVariants: *variants,
+		Baseline:      *baseline,
+	}
+	mapOpts := domain.MapOptions{
+		Basemap:     *basemap,
+		HeatMetric:  *heatMetric,
+		From:        periodFrom,
+		To:          periodTo,
+		TopSegments: *topSegs,
+		Strict:      *strict,
+	}
+	if *heatSeries != "" {
+		mapOpts.Series = strings.Split(*heatSeries, ",")
+	}
+	switch directionOpts.Baseline {
+	case "depot", "uniform":
+	default:
+		log.Fatalf("Неизвестное базовое распределение: %s", directionOpts.Baseline)
+	}
+	switch mapOpts.HeatMetric {
+	case domain.HeatMetricVisits, domain.HeatMetricTotalVisits, domain.HeatMetricLocomotives,
+		domain.HeatMetricDwell, domain.HeatMetricTripsPerDay:
+	default:
+		log.Fatalf("Неизвестная метрика тепловой карты: %s", mapOpts.HeatMetric)
+	}
+	if *task == "3" || *task == "all" {
+		// Консольные карты встраивают Leaflet в HTML
+		if err := mapassets.Check(); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+
 	// Создаем сервисы
 	algorithmSvc := services.NewAlgorithmService(*dataPath, "./data/station_info.csv")
 	popularTripSvc := services.NewMostPopularTripService(*dataPath, "./data/station_info.csv")
 	visualizationSvc := services.NewVisualizationService(*dataPath)
+	locomotiveSvc := services.NewLocomotiveService(*dataPath, "./data/station_info.csv", algorithmSvc, popularTripSvc)
 
 	// Засекаем время выполнения
 	startTime := time.Now()
@@ -44,14 +103,44 @@ func main() {
 		// Только пункт 1
 		algorithmSvc.RunAlgorithm()
 
+	case "diff":
+		// Сравнение веток двух наборов данных или периодов
+		base := domain.BranchRun{
+			DataPath: *dataPath,
+			From:     periodFrom,
+			To:       periodTo,
+		}
+		target := domain.BranchRun{
+			DataPath: *dataPath,
+			From:     period2From,
+			To:       period2To,
+		}
+		if *dataPath2 != "" {
+			target.DataPath = *dataPath2
+		}
+		if base == target {
+			log.Fatalf("Для diff укажите -data2 или разные периоды (-from/-to и -from2/-to2)")
+		}
+		if err := algorithmSvc.RunBranchDiff(base, target); err != nil {
+			log.Fatalf("Ошибка сравнения веток: %v", err)
+		}
+
+	case "util":
+		// Отчет об использовании парка
+		locomotiveSvc.RunUtilizationReport(domain.UtilizationOptions{
+			Depo: *utilDepo,
+			From: periodFrom,
+			To:   periodTo,
+		})
+
 	case "2":
 		// Только пункт 2
-		popularTripSvc.RunMostPopularTrip()
+		popularTripSvc.RunMostPopularTrip(directionOpts)
 
 	case "3":
 		// Только пункт 3 - визуализация
 		fmt.Println("Запуск визуализации...")
-		if err := visualizationSvc.GenerateMap(*depoForMap, *maxLoco); err != nil {
+		if err := visualizationSvc.GenerateMap(*depoForMap, *maxLoco, mapOpts); err != nil {
 			log.Fatalf("Ошибка визуализации: %v", err)
 		}
 
@@ -61,18 +150,39 @@ func main() {
 		algorithmSvc.RunAlgorithm()
 
 		fmt.Println("\n=== ПУНКТ 2 ===")
-		popularTripSvc.RunMostPopularTrip()
+		popularTripSvc.RunMostPopularTrip(directionOpts)
 
 		fmt.Println("\n=== ПУНКТ 3 ===")
-		if err := visualizationSvc.GenerateAllMaps(*depoForMap, *maxLoco); err != nil {
+		if err := visualizationSvc.GenerateAllMaps(*depoForMap, *maxLoco, mapOpts); err != nil {
 			log.Fatalf("Ошибка визуализации: %v", err)
 		}
 
 	default:
-		log.Fatalf("Неизвестная задача: %s. Используйте 1, 2, 3 или all", *task)
+		log.Fatalf("Неизвестная задача: %s. Используйте 1, 2, 3, diff, util или all", *task)
 	}
 
 	// Итоговое время
 	elapsed := time.Since(startTime)
 	fmt.Printf("\n✅ Анализ завершен за %s\n", elapsed)
-}
\ No newline at end of file
+}
+
+// mustParseDate разбирает дату из флага (пустая строка - без ограничения)
+func mustParseDate(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		log.Fatalf("Некорректная дата %q, ожидается формат YYYY-MM-DD", value)
+	}
+	return t
+}
+
+// mustParsePeriod разбирает период [from, to); пустой период - ошибка
+func mustParsePeriod(from, to, fromFlag, toFlag string) (time.Time, time.Time) {
+	start, end := mustParseDate(from), mustParseDate(to)
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		log.Fatalf("Пустой период: %s %s должно быть раньше %s %s (%s не включительно)", fromFlag, from, toFlag, to, toFlag)
+	}
+	return start, end
+}
@@ -10,16 +10,26 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gin-contrib/cors"
 	
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/mapassets"
+	"github.com/mihnpro/Hackathon_TMX/internal/mbtiles"
 	"github.com/mihnpro/Hackathon_TMX/internal/services"
 	"github.com/mihnpro/Hackathon_TMX/internal/transport/handlers"
 	"github.com/mihnpro/Hackathon_TMX/internal/transport/routes"
 )
 
 func main() {
+	// Карты работают в изолированной сети: Leaflet должен быть встроен в сборку
+	if err := mapassets.Check(); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+
 	// Создаем сервисы
 	task1Service := services.NewAlgorithmService("./data/locomotives_displacement.csv",	"./data/station_info.csv")
 	task2Service := services.NewMostPopularTripService("./data/locomotives_displacement.csv", "./data/station_info.csv")
 	task3Service := services.NewVisualizationService("./data/locomotives_displacement.csv")
+	locomotiveService := services.NewLocomotiveService("./data/locomotives_displacement.csv", "./data/station_info.csv",
+		task1Service, task2Service)
 	
 	// ИЗМЕНЕНО: получаем URL ML сервиса из переменной окружения
 	mlServiceURL := os.Getenv("WEAR_PREDICTION_URL")
@@ -34,16 +44,48 @@ func main() {
 	task1Handler := handlers.NewTask1Handler(task1Service)
 	task2Handler := handlers.NewTask2Handler(task2Service)
 	task3Handler := handlers.NewTask3Handler(task3Service)
+	locomotiveHandler := handlers.NewLocomotiveHandler(locomotiveService)
 	
 	// Создаем ML обработчик
 	mlHandler := handlers.NewMLHandler(mlService)
 	
+	// Локальные тайлы подложки (MBTiles), если файл задан
+	var tiles *mbtiles.Reader
+	var tileHandler *handlers.TileHandler
+	if tilesPath := os.Getenv("MBTILES_PATH"); tilesPath != "" {
+		var err error
+		tiles, err = mbtiles.Open(tilesPath)
+		if err != nil {
+			log.Printf("⚠️ Не удалось открыть MBTiles, используются тайлы OpenStreetMap: %v", err)
+		} else {
+			minZoom, maxZoom := tiles.ZoomRange()
+			log.Printf("🗺️ Тайлы подложки: %s (%s, зум %d-%d)", tilesPath, tiles.Format(), minZoom, maxZoom)
+			task3Service.SetTileLayer(domain.TileLayer{
+				URL:     "/tiles/{z}/{x}/{y}." + tiles.Format(),
+				MinZoom: minZoom,
+				MaxZoom: maxZoom,
+			})
+			tileHandler = handlers.NewTileHandler(tiles)
+		}
+	}
+	
 	// Создаем временную директорию для карт
 	mapsDir := "./maps"
 	if err := os.MkdirAll(mapsDir, 0755); err != nil {
 		log.Printf("⚠️ Не удалось создать директорию для карт: %v", err)
 	}
 	
+	// Задания генерации карт хранятся MAPS_TTL (по умолчанию 1 час), затем удаляются
+	mapsTTL := time.Hour
+	if value := os.Getenv("MAPS_TTL"); value != "" {
+		if ttl, err := time.ParseDuration(value); err == nil && ttl > 0 {
+			mapsTTL = ttl
+		} else {
+			log.Printf("⚠️ Некорректное значение MAPS_TTL=%q, используется %s", value, mapsTTL)
+		}
+	}
+	task3Service.StartJanitor(mapsTTL)
+	
 	// Создаем директорию для загружаемых файлов
 	os.MkdirAll("./uploads", 0755)
 	
@@ -70,7 +112,9 @@ func main() {
 		task1Handler, 
 		task2Handler, 
 		task3Handler, 
+		locomotiveHandler,
 		mlHandler,
+		tileHandler,
 		mapsDir,
 	)
 	
@@ -81,11 +125,11 @@ func main() {
 		<-c
 		log.Println("🛑 Получен сигнал завершения, очищаем ресурсы...")
 		
-		if vs, ok := task3Service.(interface{ Cleanup() }); ok {
-			vs.Cleanup()
+		// Карты не удаляем: задания переживают перезапуск и удаляются по MAPS_TTL
+		if tiles != nil {
+			tiles.Close()
 		}
 		
-		os.RemoveAll(mapsDir)
 		os.RemoveAll("./uploads")
 		
 		os.Exit(0)
@@ -109,16 +153,39 @@ func main() {
 	log.Println("      GET    /api/v1/task1/branches           - все ветки")
 	log.Println("      GET    /api/v1/task1/depots             - список депо")
 	log.Println("      GET    /api/v1/task1/depots/:depo/branches - ветки депо")
+	log.Println("      GET    /api/v1/task1/depots/:depo/branches/:branchId - детализация ветки")
+	log.Println("      GET    /api/v1/task1/locomotives/:series/:number/branches - ветки локомотива")
+	log.Println("      GET    /api/v1/task1/diff               - сравнение веток двух периодов")
 	log.Println()
 	log.Println("   🔹 API Задание 2:")
 	log.Println("      GET    /api/v1/popular-direction                 - все направления")
 	log.Println("      GET    /api/v1/locomotives/:series/:number/popular-direction - направление локомотива")
+	log.Println("      GET    /api/v1/locomotives/:series/:number/direction-history - история направлений локомотива")
+	log.Println("      GET    /api/v1/locomotives/:series/:number/next-direction - прогноз следующего направления")
+	log.Println("      GET    /api/v1/locomotive-clusters              - кластеры локомотивов по профилю")
+	log.Println()
+	log.Println("   🔹 API Локомотивы:")
+	log.Println("      GET    /api/v1/locomotives              - каталог локомотивов")
+	log.Println("      GET    /api/v1/locomotives/:series/:number - профиль локомотива")
+	log.Println("      GET    /api/v1/series                   - сводка по сериям")
+	log.Println("      GET    /api/v1/series/:series           - аналитика серии")
+	log.Println("      GET    /api/v1/utilization              - использование парка")
 	log.Println()
 	log.Println("   🔹 API Задание 3:")
 	log.Println("      GET    /api/v1/task3/depots             - список депо")
 	log.Println("      GET    /api/v1/task3/depots/:depo       - информация о депо")
-	log.Println("      POST   /api/v1/task3/generate           - генерация карт")
+	log.Println("      GET    /api/v1/task3/depots/:depo/stations.geojson - станции депо (GeoJSON)")
+	log.Println("      GET    /api/v1/task3/depots/:depo/routes.geojson - маршруты депо (GeoJSON)")
+	log.Println("      GET    /api/v1/task3/depots/:depo/heat.geojson - тепловая карта депо (GeoJSON)")
+	log.Println("      GET    /api/v1/task3/depots/:depo/map.png - статичная карта депо (PNG)")
+	log.Println("      GET    /api/v1/task3/depots/:depo/map.svg - статичная карта депо (SVG)")
+	log.Println("      GET    /api/v1/task3/locomotives/:series/:number/track.geojson - посещения станций локомотивом (GeoJSON со временем)")
+	log.Println("      POST   /api/v1/task3/generate           - генерация карт (в фоне, 202)")
+	log.Println("      GET    /api/v1/task3/jobs/:id/events    - прогресс генерации (SSE)")
+	log.Println("      GET    /api/v1/task3/maps/:jobId        - файлы задания генерации карт")
 	log.Println("      GET    /maps/*                           - сгенерированные карты")
+	log.Println("      GET    /static/map/*                     - ресурсы Leaflet для карт")
+	log.Println("      GET    /tiles/:z/:x/:y.png               - тайлы подложки (MBTILES_PATH)")
 	log.Println()
 	log.Println("   🔹 ML Wear Prediction:")
 	log.Println("      POST   /api/v1/ml/predict        - предсказание (JSON в теле)")
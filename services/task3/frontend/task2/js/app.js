@@ -3,6 +3,9 @@ let apiData = null;
 let currentView = 'cards';
 let currentFilter = 'all';
 let searchTerm = '';
+let currentSort = '-trips';
+let currentPage = 1;
+const pageSize = 50;
 
 // Загрузка данных при старте
 document.addEventListener('DOMContentLoaded', () => {
@@ -15,8 +18,18 @@ document.addEventListener('DOMContentLoaded', () => {
 async function fetchData() {
     showLoading();
     try {
-        console.log('Запрос к API:', '/api/v1/popular-direction');
-        const response = await fetch('/api/v1/popular-direction');
+        const params = new URLSearchParams({
+            page: currentPage,
+            limit: pageSize,
+            sort: currentSort
+        });
+        if (currentFilter !== 'all') {
+            params.set('depo', currentFilter);
+        }
+        
+        const url = `/api/v1/popular-direction?${params}`;
+        console.log('Запрос к API:', url);
+        const response = await fetch(url);
         
         if (!response.ok) {
             throw new Error(`HTTP error! status: ${response.status}`);
@@ -41,21 +54,20 @@ function updateFilterButtons() {
     const container = document.getElementById('filterButtons');
     if (!container) return;
     
-    const depots = apiData.depots.map(d => d.depo_code);
+    const depots = apiData.available_depots || apiData.depots.map(d => d.depo_code);
     
-    let html = '<button class="filter-btn active" data-filter="all">Все депо</button>';
+    let html = `<button class="filter-btn ${currentFilter === 'all' ? 'active' : ''}" data-filter="all">Все депо</button>`;
     depots.forEach(depo => {
-        html += `<button class="filter-btn" data-filter="${depo}">Депо ${depo}</button>`;
+        html += `<button class="filter-btn ${currentFilter === depo ? 'active' : ''}" data-filter="${depo}">Депо ${depo}</button>`;
     });
     
     container.innerHTML = html;
     
     container.querySelectorAll('.filter-btn').forEach(btn => {
         btn.addEventListener('click', () => {
-            container.querySelectorAll('.filter-btn').forEach(b => b.classList.remove('active'));
-            btn.classList.add('active');
             currentFilter = btn.dataset.filter;
-            renderContent();
+            currentPage = 1;
+            fetchData();
         });
     });
 }
@@ -70,6 +82,15 @@ function setupEventListeners() {
         });
     }
     
+    const sortSelect = document.getElementById('sortSelect');
+    if (sortSelect) {
+        sortSelect.addEventListener('change', (e) => {
+            currentSort = e.target.value;
+            currentPage = 1;
+            fetchData();
+        });
+    }
+    
     document.querySelectorAll('.view-btn').forEach(btn => {
         btn.addEventListener('click', () => {
             document.querySelectorAll('.view-btn').forEach(b => b.classList.remove('active'));
@@ -132,6 +153,40 @@ function renderContent() {
         default:
             content.innerHTML = renderCardsView(filteredDepots);
     }
+    
+    renderPagination();
+}
+
+// Пагинация списка локомотивов
+function renderPagination() {
+    const container = document.getElementById('pagination');
+    if (!container) return;
+    
+    const pagination = apiData && apiData.pagination;
+    if (!pagination || pagination.total_pages <= 1) {
+        container.innerHTML = pagination && pagination.total > 0
+            ? `<span class="page-info">Локомотивов: ${pagination.total}</span>`
+            : '';
+        return;
+    }
+    
+    container.innerHTML = `
+        <button class="page-btn" ${pagination.page <= 1 ? 'disabled' : ''} onclick="goToPage(${pagination.page - 1})">
+            <i class="fas fa-chevron-left"></i>
+        </button>
+        <span class="page-info">
+            Страница ${pagination.page} из ${pagination.total_pages} · локомотивов: ${pagination.total}
+        </span>
+        <button class="page-btn" ${pagination.page >= pagination.total_pages ? 'disabled' : ''} onclick="goToPage(${pagination.page + 1})">
+            <i class="fas fa-chevron-right"></i>
+        </button>
+    `;
+}
+
+function goToPage(page) {
+    currentPage = page;
+    fetchData();
+    window.scrollTo({ top: 0, behavior: 'smooth' });
 }
 
 function filterDepots() {
@@ -649,6 +704,21 @@ function showModal(loco, depoCode) {
                 </div>
             ` : ''}
             
+            <div class="detail-section">
+                <h3>История направлений</h3>
+                <div style="display: flex; justify-content: flex-end; margin-bottom: 10px;">
+                    <select id="historyBucket" onchange="loadDirectionHistory('${loco.model}', '${loco.number}')">
+                        <option value="week">По неделям</option>
+                        <option value="month" selected>По месяцам</option>
+                        <option value="quarter">По кварталам</option>
+                    </select>
+                </div>
+                <div class="history-chart">
+                    <canvas id="historyChart"></canvas>
+                </div>
+                <div id="historySwitches" class="stats-list"></div>
+            </div>
+            
             <div class="detail-section">
                 <h3>Дополнительная информация</h3>
                 <div style="text-align: center; padding: 10px;">
@@ -662,6 +732,108 @@ function showModal(loco, depoCode) {
     
     modal.classList.add('show');
     document.body.style.overflow = 'hidden';
+    
+    loadDirectionHistory(loco.model, loco.number);
+}
+
+// Загрузка истории любимого направления локомотива
+async function loadDirectionHistory(model, number) {
+    const bucketSelect = document.getElementById('historyBucket');
+    const bucket = bucketSelect ? bucketSelect.value : 'month';
+    const switchesEl = document.getElementById('historySwitches');
+    
+    try {
+        const url = `/api/v1/locomotives/${encodeURIComponent(model)}/${encodeURIComponent(number)}/direction-history?bucket=${bucket}`;
+        const response = await fetch(url);
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+        const history = await response.json();
+        createHistoryChart(history);
+        
+        if (switchesEl) {
+            switchesEl.innerHTML = history.switches.length > 0
+                ? history.switches.map(sw => `
+                    <li style="padding: 6px 0;">
+                        <strong>${sw.period_start}</strong>: ${sw.from_name} → ${sw.to_name}
+                        (${sw.share.toFixed(1)}%)
+                    </li>
+                `).join('')
+                : '<li style="padding: 6px 0;">Любимое направление не менялось</li>';
+        }
+    } catch (error) {
+        console.error('Error fetching direction history:', error);
+        if (switchesEl) {
+            switchesEl.innerHTML = '<li style="padding: 6px 0;">Не удалось загрузить историю направлений</li>';
+        }
+    }
+}
+
+// График долей направлений по периодам
+function createHistoryChart(history) {
+    const canvas = document.getElementById('historyChart');
+    if (!canvas) return;
+    
+    const ctx = canvas.getContext('2d');
+    const existingChart = Chart.getChart(canvas);
+    if (existingChart) existingChart.destroy();
+    
+    const colors = ['#667eea', '#2ecc71', '#f39c12', '#e74c3c', '#9b59b6', '#1abc9c', '#34495e', '#e67e22'];
+    
+    // Все направления, встречавшиеся в периодах
+    const directionNames = {};
+    history.periods.forEach(period => {
+        period.directions.forEach(dir => {
+            directionNames[dir.id] = dir.name;
+        });
+    });
+    
+    const datasets = Object.keys(directionNames).map((dirId, i) => ({
+        label: directionNames[dirId],
+        data: history.periods.map(period => {
+            const dir = period.directions.find(d => d.id === dirId);
+            return dir ? dir.share : 0;
+        }),
+        backgroundColor: colors[i % colors.length]
+    }));
+    
+    datasets.push({
+        label: 'Без направления',
+        data: history.periods.map(period => period.trips > 0 ? period.unmatched_trips / period.trips * 100 : 0),
+        backgroundColor: '#bdc3c7'
+    });
+    
+    new Chart(ctx, {
+        type: 'bar',
+        data: {
+            labels: history.periods.map(period => period.period_start),
+            datasets: datasets
+        },
+        options: {
+            responsive: true,
+            maintainAspectRatio: false,
+            plugins: {
+                legend: { display: false },
+                tooltip: {
+                    callbacks: {
+                        label: (context) => `${context.dataset.label}: ${context.raw.toFixed(1)}%`
+                    }
+                }
+            },
+            scales: {
+                x: { stacked: true },
+                y: {
+                    stacked: true,
+                    beginAtZero: true,
+                    max: 100,
+                    title: {
+                        display: true,
+                        text: 'Доля поездок, %'
+                    }
+                }
+            }
+        }
+    });
 }
 
 function calculateDepotAverage(depoCode) {
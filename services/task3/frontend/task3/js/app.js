@@ -71,7 +71,7 @@ function setupEventListeners() {
 
 // Настройка слушателей для основных вкладок
 function setupMainTabsListeners() {
-    const mainTabs = document.querySelectorAll('.tab-btn[data-tab="overview"], .tab-btn[data-tab="heatmap"]');
+    const mainTabs = document.querySelectorAll('.tab-btn');
     mainTabs.forEach(btn => {
         btn.removeEventListener('click', handleMainTabClick);
         btn.addEventListener('click', handleMainTabClick);
@@ -160,7 +160,9 @@ async function generateMaps() {
             throw new Error(error.error || 'Failed to generate maps');
         }
         
-        currentMaps = await response.json();
+        // Генерация идет в фоне, результат приходит последним событием
+        const job = await response.json();
+        currentMaps = await waitForMapsJob(job.events_url);
         
         // Отображаем карты
         displayMaps(currentMaps);
@@ -178,6 +180,37 @@ async function generateMaps() {
     }
 }
 
+// Ожидание задания генерации карт: прогресс по Server-Sent Events
+function waitForMapsJob(eventsUrl) {
+    return new Promise((resolve, reject) => {
+        const source = new EventSource(eventsUrl);
+        
+        source.addEventListener('progress', (e) => {
+            const event = JSON.parse(e.data);
+            setLoadingMessage(`Этап ${event.stage} из ${event.total}: ${event.message}`);
+        });
+        
+        source.addEventListener('done', (e) => {
+            source.close();
+            resolve(JSON.parse(e.data).result);
+        });
+        
+        source.addEventListener('error', (e) => {
+            source.close();
+            // Событие error от сервера содержит причину, без данных - обрыв соединения
+            const event = e.data ? JSON.parse(e.data) : null;
+            reject(new Error(event ? event.error : 'Соединение с сервером прервано'));
+        });
+    });
+}
+
+// Текст под индикатором загрузки
+function setLoadingMessage(message) {
+    const loading = document.getElementById('loading');
+    const text = loading ? loading.querySelector('p') : null;
+    if (text) text.textContent = message;
+}
+
 // Отображение карт
 function displayMaps(maps) {
     // Проверяем наличие данных
@@ -197,6 +230,8 @@ function displayMaps(maps) {
     // Устанавливаем URL для iframe
     const overviewFrame = document.getElementById('overviewFrame');
     const heatmapFrame = document.getElementById('heatmapFrame');
+    const branchesFrame = document.getElementById('branchesFrame');
+    const flowFrame = document.getElementById('flowFrame');
     
     if (overviewFrame && maps.maps.overview) {
         overviewFrame.src = maps.maps.overview;
@@ -206,6 +241,14 @@ function displayMaps(maps) {
         heatmapFrame.src = maps.maps.heatmap;
     }
     
+    if (branchesFrame && maps.maps.branches) {
+        branchesFrame.src = maps.maps.branches;
+    }
+    
+    if (flowFrame && maps.maps.flow) {
+        flowFrame.src = maps.maps.flow;
+    }
+    
     // Создаем вкладки для локомотивов
     if (maps.maps.locomotives && Array.isArray(maps.maps.locomotives)) {
         createLocomotiveTabs(maps.maps.locomotives);
@@ -265,7 +308,7 @@ function createLocomotiveTabs(locomotives) {
                 console.log('Клик по вкладке локомотива:', tabId);
                 
                 // Деактивируем все основные вкладки
-                document.querySelectorAll('.tab-btn[data-tab="overview"], .tab-btn[data-tab="heatmap"]').forEach(btn => {
+                document.querySelectorAll('.tab-btn').forEach(btn => {
                     btn.classList.remove('active');
                 });
                 
@@ -337,6 +380,10 @@ function activateTab(tabId) {
         paneId = 'overviewTab';
     } else if (tabId === 'heatmap') {
         paneId = 'heatmapTab';
+    } else if (tabId === 'branches') {
+        paneId = 'branchesTab';
+    } else if (tabId === 'flow') {
+        paneId = 'flowTab';
     } else {
         paneId = `${tabId}Tab`; // для локомотивов: loco-0Tab, loco-1Tab и т.д.
     }
@@ -355,6 +402,7 @@ function activateTab(tabId) {
 function showLoading() {
     const loading = document.getElementById('loading');
     if (loading) loading.style.display = 'block';
+    setLoadingMessage('Генерация карт...');
 }
 
 // Скрыть загрузку
@@ -445,6 +493,15 @@ function showGenerationInfo() {
         `<li>
             <i class="fas fa-train"></i>
             <a href="${loco.url || '#'}" target="_blank">${loco.model || 'Локомотив'}-${loco.number || '??'} (${loco.trip_count || 0} поездок)</a>
+            ${loco.playback_url ? `| <a href="${loco.playback_url}" target="_blank">воспроизведение</a>` : ''}
+        </li>`
+    ).join('');
+    
+    const unmapped = currentMaps.unmapped_stations || [];
+    const unmappedList = unmapped.map(st =>
+        `<li>
+            <i class="fas fa-map-marker-alt"></i>
+            ${st.station_id}: ${st.records} отметок, ${st.locomotives} локомотивов
         </li>`
     ).join('');
     
@@ -461,13 +518,23 @@ function showGenerationInfo() {
             <span class="info-label">Карты:</span>
             <span class="info-value">
                 <a href="${currentMaps.maps?.overview || '#'}" target="_blank">Общая</a> | 
-                <a href="${currentMaps.maps?.heatmap || '#'}" target="_blank">Тепловая</a>
+                <a href="${currentMaps.maps?.heatmap || '#'}" target="_blank">Тепловая</a> | 
+                <a href="${currentMaps.maps?.branches || '#'}" target="_blank">Ветки</a> | 
+                <a href="${currentMaps.maps?.flow || '#'}" target="_blank">Потоки</a> | 
+                <a href="${currentMaps.maps?.segments || '#'}" target="_blank">Перегоны (CSV)</a>
             </span>
         </div>
         <h4>Локомотивы (${locomotives.length}):</h4>
         <ul class="maps-list">
             ${locoList || '<li>Нет данных о локомотивах</li>'}
         </ul>
+        ${currentMaps.coordinates_generated ? `
+        <p class="geo-warning"><i class="fas fa-exclamation-triangle"></i> Координаты станций вымышленные: station_info.csv не загружен</p>` : ''}
+        ${unmapped.length ? `
+        <h4>Станции без координат (${unmapped.length}, отметок: ${currentMaps.unmapped_records || 0}):</h4>
+        <ul class="maps-list">
+            ${unmappedList}
+        </ul>` : ''}
     `;
     
     modal.classList.add('show');
@@ -487,6 +554,13 @@ style.textContent = `
         box-shadow: 0 10px 20px rgba(76, 175, 80, 0.3);
     }
     
+    .geo-warning {
+        padding: 10px;
+        background: #fff3cd;
+        color: #664d03;
+        border-radius: 6px;
+    }
+    
     .no-locomotives {
         padding: 20px;
         text-align: center;
@@ -0,0 +1,7 @@
+package domain
+
+type BranchQuality struct {
+	Cohesion   float64  // средняя схожесть путей внутри ветки (0..1)
+	Separation *float64 // отличие от ближайшей другой ветки депо (0..1), nil - других веток в депо нет
+	Coverage   float64  // доля путей, полностью покрытых CoreStations (0..1)
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// BranchRun описывает набор данных для одного прогона анализа веток
+type BranchRun struct {
+	DataPath string    // путь к CSV с перемещениями (пусто - данные сервиса)
+	Dataset  string    // имя CSV в директории данных сервиса (API), заменяет DataPath
+	From     time.Time // начало окна (включительно), нулевое значение - без ограничения
+	To       time.Time // конец окна (не включительно), нулевое значение - без ограничения
+}
+
+// Contains проверяет, попадает ли момент времени в окно прогона
+func (r BranchRun) Contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !t.Before(r.To) {
+		return false
+	}
+	return true
+}
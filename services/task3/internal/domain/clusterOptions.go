@@ -0,0 +1,7 @@
+package domain
+
+// ClusterOptions параметры кластеризации локомотивов по профилю эксплуатации (задача 2)
+type ClusterOptions struct {
+	Depo string // код депо, пусто - кластеризация по каждому депо отдельно
+	K    int    // количество кластеров, 0 - подбирается автоматически
+}
@@ -10,4 +10,6 @@ type Direction struct {
     RouteNames  []string        // названия станций маршрута
     Frequency   int             // как часто используется
     Locomotives map[string]bool // локомотивы, использующие это направление
+    ParentID    string          // направление до конечной станции (если варианты разделены)
+    Variants    []RouteVariant  // варианты маршрута по убыванию частоты
 }
\ No newline at end of file
@@ -0,0 +1,8 @@
+package domain
+
+// DirectionOptions параметры анализа направлений (задача 2)
+type DirectionOptions struct {
+	MinSimilarity float64 // порог схожести маршрута поездки с направлением (0 - по умолчанию)
+	SplitVariants bool    // считать каждый вариант маршрута отдельным направлением
+	Baseline      string  // базовое распределение для проверки значимости: "depot" (по умолчанию) или "uniform"
+}
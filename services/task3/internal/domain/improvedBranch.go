@@ -1,11 +1,23 @@
 package domain
 
+import "time"
 
 type ImprovedBranch struct {
 	Depo          string
 	CoreStations  []string      // уникальные станции в порядке от депо
 	BranchID      string        // уникальный идентификатор ветки
 	AllPaths      [][]string    // все варианты проезда по ветке
+	Trips         []BranchTrip  // поездки, из которых получены AllPaths (по тому же индексу)
 	Terminals     map[string]int // конечные станции и частота посещения
 	Length        int           // длина ветки в станциях
+	Quality       BranchQuality // метрики качества кластеризации
+}
+
+// BranchTrip - поездка локомотива, отнесенная к ветке
+type BranchTrip struct {
+	LocomotiveKey string
+	Series        string
+	Number        string
+	StartTime     time.Time
+	EndTime       time.Time
 }
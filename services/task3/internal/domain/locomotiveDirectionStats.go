@@ -13,6 +13,17 @@ type LocomotiveDirectionStats struct {
     MostPopularDirection string
     MostPopularName     string
     MaxVisits           int
+    UnmatchedTrips      int               // поездки, не сопоставленные ни с одним направлением
+    Trips               []Trip            // поездки с направлением и уверенностью сопоставления
+
+    // Значимость любимого направления
+    Baseline            string            // базовое распределение: "depot" или "uniform"
+    ChiSquare           float64           // статистика хи-квадрат против базового распределения
+    DegreesOfFreedom    int
+    PValue              float64
+    Entropy             float64           // энтропия Шеннона распределения по направлениям (биты)
+    NormalizedEntropy   float64           // энтропия, нормированная на log2(числа направлений депо)
+    FavoriteLabel       string            // strong_favorite, weak_favorite или no_favorite
 }
 
 type DirectionInfo struct {
@@ -22,4 +33,5 @@ type DirectionInfo struct {
     TerminalName string
     Visits      int
     Percentage  float64
+    AvgConfidence float64 // средняя уверенность сопоставления поездок
 }
\ No newline at end of file
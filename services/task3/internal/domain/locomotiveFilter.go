@@ -0,0 +1,10 @@
+package domain
+
+// LocomotiveFilter поиск и пагинация каталога локомотивов
+type LocomotiveFilter struct {
+	Series string // префикс серии (без учета регистра)
+	Number string // префикс номера
+	Depo   string // код депо приписки
+	Page   int    // номер страницы (с 1)
+	Limit  int    // размер страницы
+}
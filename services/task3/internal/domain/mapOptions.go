@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// Метрики тепловой карты
+const (
+	HeatMetricVisits      = "visits"        // посещения: станция считается один раз за поездку (по умолчанию)
+	HeatMetricTotalVisits = "total_visits"  // все заезды на станцию
+	HeatMetricLocomotives = "locomotives"   // разные локомотивы на станции
+	HeatMetricDwell       = "dwell"         // суммарное время стоянки, часы
+	HeatMetricTripsPerDay = "trips_per_day" // поездок через станцию в сутки
+)
+
+// MapOptions параметры генерации HTML карт (задача 3)
+type MapOptions struct {
+	Basemap string // подложка: "osm" (тайлы OpenStreetMap, по умолчанию) или "local" (без тайлов, по данным)
+
+	// Тепловая карта
+	HeatMetric string    // метрика из HeatMetric*, пусто - HeatMetricVisits
+	From       time.Time // начало периода (включительно), нулевое значение - без ограничения
+	To         time.Time // конец периода (не включительно), нулевое значение - без ограничения
+	Series     []string  // серии локомотивов, пусто - все серии
+
+	TopSegments int // размер таблицы самых загруженных перегонов, 0 - по умолчанию (20)
+
+	Strict bool // строгий режим: без station_info.csv генерация завершается ошибкой, координаты не выдумываются
+}
+
+// Contains проверяет, попадает ли момент времени в период тепловой карты
+func (o MapOptions) Contains(t time.Time) bool {
+	if !o.From.IsZero() && t.Before(o.From) {
+		return false
+	}
+	if !o.To.IsZero() && !t.Before(o.To) {
+		return false
+	}
+	return true
+}
+
+// HasSeries проверяет, попадает ли серия локомотива в фильтр тепловой карты
+func (o MapOptions) HasSeries(series string) bool {
+	if len(o.Series) == 0 {
+		return true
+	}
+	for _, s := range o.Series {
+		if s == series {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package domain
+
+// PopularDirectionFilter фильтры, сортировка и пагинация списка локомотивов (задача 2)
+type PopularDirectionFilter struct {
+	Depo      string // код депо
+	Series    string // серия локомотива (без учета регистра)
+	MinTrips  int    // минимальное количество поездок
+	Direction string // ID направления, по которому ездил локомотив
+	Sort      string // поле сортировки: trips, series, number, share, entropy; "-" в начале - по убыванию
+	Page      int    // номер страницы (с 1)
+	Limit     int    // размер страницы, 0 - без ограничения
+}
@@ -0,0 +1,10 @@
+package domain
+
+// RouteVariant - конкретный вариант проезда по направлению
+type RouteVariant struct {
+    ID          string
+    Route       []string        // очищенный маршрут варианта
+    RouteNames  []string        // названия станций маршрута
+    Frequency   int             // сколько поездок прошло по варианту
+    Locomotives map[string]bool // локомотивы, использующие вариант
+}
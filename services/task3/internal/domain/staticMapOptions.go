@@ -0,0 +1,29 @@
+package domain
+
+// Слои статичной карты депо
+const (
+	StaticLayerStations = "stations" // станции, депо выделено
+	StaticLayerHeat     = "heat"     // круги по числу посещений
+	StaticLayerRoutes   = "routes"   // маршруты самых активных локомотивов
+)
+
+// StaticMapOptions параметры статичной карты депо (PNG/SVG, задача 3)
+type StaticMapOptions struct {
+	Layers         []string // слои из StaticLayer*, пусто - все слои
+	Width          int      // ширина изображения в пикселях
+	Height         int      // высота изображения в пикселях
+	MaxLocomotives int      // количество локомотивов в слое routes
+}
+
+// Has - включен ли слой
+func (o StaticMapOptions) Has(layer string) bool {
+	if len(o.Layers) == 0 {
+		return true
+	}
+	for _, l := range o.Layers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
@@ -8,4 +8,5 @@ type StationStats struct {
 	VisitCount   int
 	Locomotives  []string
 	Popularity   float64 // от 0 до 1
+	Weight       float64 // значение метрики тепловой карты (см. MapOptions.HeatMetric)
 }
\ No newline at end of file
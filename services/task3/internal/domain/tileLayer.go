@@ -0,0 +1,8 @@
+package domain
+
+// TileLayer источник тайлов подложки HTML карт (задача 3)
+type TileLayer struct {
+	URL     string // шаблон адреса тайла с {z}, {x}, {y}
+	MinZoom int    // зумы, для которых есть тайлы; на остальных тайлы масштабируются
+	MaxZoom int
+}
@@ -9,4 +9,6 @@ type Trip struct {
     StationNames []string     // названия станций для отображения
     Route       []string      // очищенный маршрут (без повторов)
     DirectionID string        // ID направления этой поездки
+    VariantID   string        // ID варианта маршрута внутри направления
+    Confidence  float64       // уверенность сопоставления с направлением (0..1)
 }
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// UtilizationOptions параметры отчета об использовании парка
+type UtilizationOptions struct {
+	Depo string    // код депо, пусто - все депо
+	From time.Time // начало периода (включительно), нулевое значение - без ограничения
+	To   time.Time // конец периода (не включительно), нулевое значение - без ограничения
+}
+
+// Contains проверяет, попадает ли момент времени в период отчета
+func (o UtilizationOptions) Contains(t time.Time) bool {
+	if !o.From.IsZero() && t.Before(o.From) {
+		return false
+	}
+	if !o.To.IsZero() && !t.Before(o.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+// Package mapassets содержит встроенные в бинарник ресурсы HTML карт:
+// Leaflet, leaflet.heat и шаблоны страниц (html/template).
+package mapassets
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+//go:embed vendor templates
+var files embed.FS
+
+// Templates - шаблоны HTML карт (overview.html, heatmap.html, locomotive.html, playback.html, branches.html, flow.html)
+var Templates = template.Must(template.ParseFS(files, "templates/*.html"))
+
+// Required - ресурсы Leaflet, без которых карты не отображаются
+var Required = []string{"leaflet.css", "leaflet.js", "leaflet-heat.js"}
+
+// Has проверяет, встроен ли ресурс в сборку
+func Has(name string) bool {
+	_, err := fs.Stat(files, path.Join("vendor", name))
+	return err == nil
+}
+
+// Read возвращает содержимое встроенного ресурса
+func Read(name string) ([]byte, error) {
+	return files.ReadFile(path.Join("vendor", name))
+}
+
+// Check проверяет, что все ресурсы Leaflet встроены в сборку.
+// Карты не подключают ресурсы из интернета, поэтому без них страницы карт не отображаются;
+// вызывающий код выводит ошибку как предупреждение, пока файлы не добавлены в репозиторий.
+func Check() error {
+	var missing []string
+	for _, name := range Required {
+		if !Has(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("ресурсы карт не встроены в сборку: %s (карты не будут отображаться; скачайте их scripts/fetch_map_assets.sh и пересоберите)",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Handler раздает встроенные ресурсы
+func Handler(prefix string) http.Handler {
+	vendor, _ := fs.Sub(files, "vendor")
+	return http.StripPrefix(prefix, http.FileServer(http.FS(vendor)))
+}
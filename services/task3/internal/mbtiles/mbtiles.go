@@ -0,0 +1,423 @@
+// Package mbtiles читает растровые тайлы подложки из локального файла MBTiles
+// (SQLite) без внешних зависимостей, чтобы карты работали без доступа к интернету.
+package mbtiles
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tileKey - координаты тайла в схеме TMS (как хранятся в MBTiles)
+type tileKey struct {
+	z, x, y int
+}
+
+func (k tileKey) less(o tileKey) bool {
+	if k.z != o.z {
+		return k.z < o.z
+	}
+	if k.x != o.x {
+		return k.x < o.x
+	}
+	return k.y < o.y
+}
+
+// tileEntry - строка таблицы с тайлом в индексе в памяти
+type tileEntry struct {
+	key   tileKey
+	rowid int64
+}
+
+// table - таблица SQLite и ее столбцы
+type table struct {
+	root        uint32
+	columns     []string
+	rowidColumn int
+}
+
+func newTable(obj schemaObject) table {
+	columns, rowidColumn := tableColumns(obj.sql)
+	return table{root: obj.root, columns: columns, rowidColumn: rowidColumn}
+}
+
+// has проверяет наличие столбцов
+func (t table) has(names ...string) bool {
+	for _, name := range names {
+		if indexOf(t.columns, name) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// value - значение столбца name строки
+func (t table) value(values []interface{}, rowid int64, name string) interface{} {
+	return column(values, rowid, indexOf(t.columns, name), t.rowidColumn)
+}
+
+// Reader - открытый файл MBTiles. Тайлы ищутся при запросе по индексу координат SQLite
+// (tile_index в mbutil/tippecanoe), данные тайлов читаются только для запрошенного тайла.
+// Если индекса в файле нет, при открытии строится индекс координат в памяти
+// по заголовкам записей, без чтения данных тайлов.
+type Reader struct {
+	db       *sqliteFile
+	metadata map[string]string
+
+	coords      table       // строки с координатами тайлов: tiles или map
+	coordsIndex uint32      // индекс SQLite (zoom_level, tile_column, tile_row) по coords; 0 - нет
+	entries     []tileEntry // индекс в памяти по возрастанию координат, если индекса SQLite нет
+
+	// Схема map/images: tile_id строки map -> строка images с данными
+	images      *table
+	imagesIndex uint32           // индекс SQLite по images.tile_id; 0 - нет
+	imageRows   map[string]int64 // tile_id -> rowid images, если нет ни индекса, ни INTEGER PRIMARY KEY
+
+	minZoom, maxZoom int
+}
+
+// Open открывает файл MBTiles с растровыми тайлами. Поддерживаются таблица tiles
+// и распространенная схема с представлением tiles над таблицами map и images.
+// Векторные тайлы (pbf) не поддерживаются: L.tileLayer их не отображает.
+//
+// Читается только основной файл базы: журналы SQLite (-wal, -journal) не применяются.
+// Если рядом есть непустой журнал, часть тайлов в файле отсутствовала бы, поэтому Open
+// возвращает ошибку - перед использованием перенесите изменения в файл
+// (sqlite3 file.mbtiles "PRAGMA wal_checkpoint(TRUNCATE)"). Файл не должен изменяться,
+// пока открыт.
+func Open(path string) (*Reader, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{
+		db:       db,
+		metadata: make(map[string]string),
+		minZoom:  -1,
+	}
+	if err := r.load(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return r, nil
+}
+
+// Close закрывает файл
+func (r *Reader) Close() error {
+	return r.db.Close()
+}
+
+// Metadata - содержимое таблицы metadata (name, format, bounds, ...)
+func (r *Reader) Metadata() map[string]string {
+	return r.metadata
+}
+
+// Format - формат тайлов из metadata (по умолчанию png)
+func (r *Reader) Format() string {
+	if format := r.metadata["format"]; format != "" {
+		return format
+	}
+	return "png"
+}
+
+// HasExtension проверяет, что расширение файла тайла (без точки) соответствует формату тайлов
+func (r *Reader) HasExtension(ext string) bool {
+	format := strings.ToLower(r.Format())
+	ext = strings.ToLower(ext)
+	if format == "jpeg" {
+		format = "jpg"
+	}
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	return ext == format
+}
+
+// ContentType - MIME тип тайлов
+func (r *Reader) ContentType() string {
+	switch r.Format() {
+	case "jpg", "jpeg":
+		return "image/jpeg"
+	case "webp":
+		return "image/webp"
+	default:
+		return "image/png"
+	}
+}
+
+// ZoomRange - минимальный и максимальный зум имеющихся тайлов
+func (r *Reader) ZoomRange() (int, int) {
+	if r.minZoom < 0 {
+		return 0, 0
+	}
+	return r.minZoom, r.maxZoom
+}
+
+// Tile возвращает тайл по координатам XYZ (как в Leaflet); nil - тайла нет
+func (r *Reader) Tile(z, x, y int) ([]byte, error) {
+	if z < 0 || z > 30 || x < 0 || y < 0 || x >= 1<<z || y >= 1<<z {
+		return nil, nil
+	}
+
+	// В MBTiles ось Y направлена снизу вверх (TMS)
+	rowid, found, err := r.findCoords(tileKey{z, x, (1 << z) - 1 - y})
+	if err != nil || !found {
+		return nil, err
+	}
+
+	values, err := r.db.lookup(r.coords.root, rowid)
+	if err != nil || values == nil {
+		return nil, err
+	}
+	if r.images == nil {
+		return blob(r.coords.value(values, rowid, "tile_data")), nil
+	}
+
+	imageRowid, found, err := r.findImage(r.coords.value(values, rowid, "tile_id"))
+	if err != nil || !found {
+		return nil, err
+	}
+	values, err = r.db.lookup(r.images.root, imageRowid)
+	if err != nil || values == nil {
+		return nil, err
+	}
+	return blob(r.images.value(values, imageRowid, "tile_data")), nil
+}
+
+// findCoords - rowid строки coords с тайлом
+func (r *Reader) findCoords(key tileKey) (int64, bool, error) {
+	if r.coordsIndex != 0 {
+		values, err := r.db.seekIndex(r.coordsIndex, []interface{}{int64(key.z), int64(key.x), int64(key.y)})
+		if err != nil || values == nil {
+			return 0, false, err
+		}
+		rowid, ok := values[len(values)-1].(int64)
+		return rowid, ok, nil
+	}
+
+	i := sort.Search(len(r.entries), func(i int) bool { return !r.entries[i].key.less(key) })
+	if i < len(r.entries) && r.entries[i].key == key {
+		return r.entries[i].rowid, true, nil
+	}
+	return 0, false, nil
+}
+
+// findImage - rowid строки images по tile_id
+func (r *Reader) findImage(tileID interface{}) (int64, bool, error) {
+	switch {
+	case indexOf(r.images.columns, "tile_id") == r.images.rowidColumn:
+		rowid, ok := tileID.(int64)
+		return rowid, ok, nil
+	case r.imagesIndex != 0:
+		values, err := r.db.seekIndex(r.imagesIndex, []interface{}{tileID})
+		if err != nil || values == nil {
+			return 0, false, err
+		}
+		rowid, ok := values[len(values)-1].(int64)
+		return rowid, ok, nil
+	default:
+		rowid, ok := r.imageRows[fmt.Sprint(tileID)]
+		return rowid, ok, nil
+	}
+}
+
+// load читает metadata, находит индексы тайлов и диапазон зумов
+func (r *Reader) load() error {
+	schema, err := r.db.schema()
+	if err != nil {
+		return err
+	}
+
+	if meta, exists := schema["metadata"]; exists && meta.kind == "table" {
+		metaTable := newTable(meta)
+		if metaTable.has("name", "value") {
+			err := r.db.scan(meta.root, -1, func(rowid int64, values []interface{}) error {
+				key := fmt.Sprint(metaTable.value(values, rowid, "name"))
+				r.metadata[key] = fmt.Sprint(metaTable.value(values, rowid, "value"))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	switch r.Format() {
+	case "pbf", "mvt":
+		return fmt.Errorf("векторные тайлы (%s) не поддерживаются, нужен растровый MBTiles (png, jpg, webp)", r.Format())
+	}
+
+	tiles, exists := schema["tiles"]
+	if !exists {
+		return fmt.Errorf("таблица tiles не найдена")
+	}
+
+	coordsName := "tiles"
+	if tiles.kind == "table" {
+		r.coords = newTable(tiles)
+		if !r.coords.has("zoom_level", "tile_column", "tile_row", "tile_data") {
+			return fmt.Errorf("в таблице tiles нет нужных столбцов")
+		}
+	} else {
+		// Представление tiles над map (координаты -> tile_id) и images (tile_id -> данные)
+		mapTable, mapExists := schema["map"]
+		images, imagesExists := schema["images"]
+		if !mapExists || !imagesExists || mapTable.kind != "table" || images.kind != "table" {
+			return fmt.Errorf("неподдерживаемая схема MBTiles: tiles - %s", tiles.kind)
+		}
+		imagesTable := newTable(images)
+		r.coords, r.images = newTable(mapTable), &imagesTable
+		if !r.coords.has("zoom_level", "tile_column", "tile_row", "tile_id") || !r.images.has("tile_id", "tile_data") {
+			return fmt.Errorf("в таблицах map/images нет нужных столбцов")
+		}
+		coordsName = "map"
+
+		if indexOf(r.images.columns, "tile_id") != r.images.rowidColumn {
+			if r.imagesIndex = findIndex(schema, "images", "tile_id"); r.imagesIndex == 0 {
+				if err := r.indexImages(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if r.coordsIndex = findIndex(schema, coordsName, "zoom_level", "tile_column", "tile_row"); r.coordsIndex != 0 {
+		return r.zoomRangeFromIndex()
+	}
+	return r.indexCoords()
+}
+
+// findIndex - корневая страница индекса таблицы, начинающегося со столбцов columns; 0 - нет
+func findIndex(schema map[string]schemaObject, tableName string, columns ...string) uint32 {
+	for _, obj := range schema {
+		if obj.kind != "index" || obj.table != tableName || obj.root == 0 {
+			continue
+		}
+		indexed := indexColumns(obj.sql)
+		if len(indexed) < len(columns) {
+			continue
+		}
+		matches := true
+		for i, name := range columns {
+			if indexed[i] != name {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return obj.root
+		}
+	}
+	return 0
+}
+
+// zoomRangeFromIndex - диапазон зумов по первой и последней записи индекса координат
+func (r *Reader) zoomRangeFromIndex() error {
+	first, err := r.db.edgeIndex(r.coordsIndex, false)
+	if err != nil || first == nil {
+		return err
+	}
+	last, err := r.db.edgeIndex(r.coordsIndex, true)
+	if err != nil || last == nil {
+		return err
+	}
+	minZoom, okMin := toInt(first[0])
+	maxZoom, okMax := toInt(last[0])
+	if okMin && okMax {
+		r.minZoom, r.maxZoom = minZoom, maxZoom
+	}
+	return nil
+}
+
+// indexCoords строит индекс координат в памяти (файл без индекса SQLite).
+// Разбираются только столбцы до координат, данные тайлов не читаются.
+func (r *Reader) indexCoords() error {
+	z := indexOf(r.coords.columns, "zoom_level")
+	x := indexOf(r.coords.columns, "tile_column")
+	y := indexOf(r.coords.columns, "tile_row")
+	limit := max(z, x, y) + 1
+
+	err := r.db.scan(r.coords.root, limit, func(rowid int64, values []interface{}) error {
+		key, ok := tileCoords(values, rowid, r.coords.rowidColumn, z, x, y)
+		if ok {
+			r.entries = append(r.entries, tileEntry{key, rowid})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(r.entries, func(i, j int) bool { return r.entries[i].key.less(r.entries[j].key) })
+	if len(r.entries) > 0 {
+		r.minZoom, r.maxZoom = r.entries[0].key.z, r.entries[len(r.entries)-1].key.z
+	}
+	return nil
+}
+
+// indexImages строит соответствие tile_id -> rowid images (файл без индекса images)
+func (r *Reader) indexImages() error {
+	r.imageRows = make(map[string]int64)
+	id := indexOf(r.images.columns, "tile_id")
+	return r.db.scan(r.images.root, id+1, func(rowid int64, values []interface{}) error {
+		r.imageRows[fmt.Sprint(column(values, rowid, id, r.images.rowidColumn))] = rowid
+		return nil
+	})
+}
+
+// tileCoords - координаты тайла из строки таблицы
+func tileCoords(values []interface{}, rowid int64, rowidColumn, z, x, y int) (tileKey, bool) {
+	zoom, okZ := toInt(column(values, rowid, z, rowidColumn))
+	col, okX := toInt(column(values, rowid, x, rowidColumn))
+	row, okY := toInt(column(values, rowid, y, rowidColumn))
+	return tileKey{zoom, col, row}, okZ && okX && okY
+}
+
+// blob - данные тайла из значения столбца
+func blob(v interface{}) []byte {
+	switch data := v.(type) {
+	case []byte:
+		return data
+	case string:
+		return []byte(data)
+	default:
+		return nil
+	}
+}
+
+// column - значение столбца; столбец INTEGER PRIMARY KEY хранится как rowid
+func column(values []interface{}, rowid int64, idx, rowidColumn int) interface{} {
+	if idx == rowidColumn {
+		return rowid
+	}
+	if idx >= len(values) {
+		// Столбец добавлен ALTER TABLE после записи строки
+		return nil
+	}
+	return values[idx]
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func indexOf(columns []string, name string) int {
+	for i, c := range columns {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,283 @@
+package mbtiles
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Файлы testdata созданы testdata/make_fixtures.py
+
+const fixtureMaxZoom = 4
+
+// tileData - содержимое тайла XYZ в файлах testdata (как tile_data в make_fixtures.py)
+func tileData(z, x, y int) []byte {
+	label := []byte(fmt.Sprintf("%d/%d/%d", z, x, y))
+	if (x+y)%11 == 0 {
+		return bytes.Repeat(label, 1500/len(label)+1)[:1500]
+	}
+	return label
+}
+
+func TestReaderTiles(t *testing.T) {
+	tests := []struct {
+		file    string
+		indexed bool // поиск по индексу SQLite, а не по индексу в памяти
+		shared  bool // одинаковые тайлы зума 4 (x >= 12) хранятся одной строкой images
+	}{
+		{file: "tiles.mbtiles", indexed: true},
+		{file: "noindex.mbtiles"},
+		{file: "mapimages.mbtiles", indexed: true, shared: true},
+		{file: "mapimages_rowid.mbtiles", shared: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			r, err := Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer r.Close()
+
+			if indexed := r.coordsIndex != 0; indexed != tt.indexed || indexed == (r.entries != nil) {
+				t.Errorf("индекс SQLite %v (записей в памяти %d), ожидалось %v", indexed, len(r.entries), tt.indexed)
+			}
+			if tt.indexed {
+				if root, err := r.db.btreePage(r.coordsIndex); err != nil || root.kind() != pageInteriorIndex {
+					t.Errorf("корень индекса координат должен быть внутренней страницей: %v", err)
+				}
+			}
+
+			if minZoom, maxZoom := r.ZoomRange(); minZoom != 0 || maxZoom != fixtureMaxZoom {
+				t.Errorf("ZoomRange = %d-%d, ожидалось 0-%d", minZoom, maxZoom, fixtureMaxZoom)
+			}
+			if r.Format() != "png" || r.ContentType() != "image/png" {
+				t.Errorf("Format = %s, ContentType = %s", r.Format(), r.ContentType())
+			}
+			if r.Metadata()["name"] != tt.file {
+				t.Errorf("metadata name = %q", r.Metadata()["name"])
+			}
+
+			for z := 0; z <= fixtureMaxZoom; z++ {
+				for x := 0; x < 1<<z; x++ {
+					for y := 0; y < 1<<z; y++ {
+						want := tileData(z, x, y)
+						if tt.shared && z == fixtureMaxZoom && x >= 12 {
+							want = bytes.Repeat([]byte("sea"), 600)
+						}
+						got, err := r.Tile(z, x, y)
+						if err != nil {
+							t.Fatalf("Tile(%d, %d, %d): %v", z, x, y, err)
+						}
+						if !bytes.Equal(got, want) {
+							t.Fatalf("Tile(%d, %d, %d) = %q (%d байт), ожидалось %d байт",
+								z, x, y, truncate(got), len(got), len(want))
+						}
+					}
+				}
+			}
+
+			for _, c := range [][3]int{{5, 0, 0}, {-1, 0, 0}, {2, 4, 0}, {2, 0, -1}, {31, 0, 0}} {
+				got, err := r.Tile(c[0], c[1], c[2])
+				if err != nil || got != nil {
+					t.Errorf("Tile(%d, %d, %d) = %q, %v; ожидалось нет тайла", c[0], c[1], c[2], truncate(got), err)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenRejectsVectorTiles(t *testing.T) {
+	_, err := Open(filepath.Join("testdata", "vector.mbtiles"))
+	if err == nil || !strings.Contains(err.Error(), "pbf") {
+		t.Fatalf("Open = %v, ожидалась ошибка векторных тайлов", err)
+	}
+}
+
+func TestOpenNotSQLite(t *testing.T) {
+	if _, err := Open(filepath.Join("testdata", "make_fixtures.py")); err == nil {
+		t.Fatal("Open не вернул ошибку для файла не SQLite")
+	}
+}
+
+func TestTableColumns(t *testing.T) {
+	tests := []struct {
+		sql         string
+		columns     []string
+		rowidColumn int
+	}{
+		{
+			sql:         "CREATE TABLE tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob)",
+			columns:     []string{"zoom_level", "tile_column", "tile_row", "tile_data"},
+			rowidColumn: -1,
+		},
+		{
+			sql:         "CREATE TABLE images (tile_id INTEGER PRIMARY KEY, tile_data blob)",
+			columns:     []string{"tile_id", "tile_data"},
+			rowidColumn: 0,
+		},
+		{
+			sql:         `CREATE TABLE "map" ("zoom_level" INTEGER, tile_id TEXT, PRIMARY KEY (zoom_level, tile_id))`,
+			columns:     []string{"zoom_level", "tile_id"},
+			rowidColumn: -1,
+		},
+		{
+			sql:         "CREATE TABLE t (a NUMERIC(10, 2), id integer primary key autoincrement)",
+			columns:     []string{"a", "id"},
+			rowidColumn: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		columns, rowidColumn := tableColumns(tt.sql)
+		if !reflect.DeepEqual(columns, tt.columns) || rowidColumn != tt.rowidColumn {
+			t.Errorf("tableColumns(%q) = %v, %d; ожидалось %v, %d", tt.sql, columns, rowidColumn, tt.columns, tt.rowidColumn)
+		}
+	}
+}
+
+func TestIndexColumns(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"CREATE UNIQUE INDEX tile_index on tiles (zoom_level, tile_column, tile_row)", []string{"zoom_level", "tile_column", "tile_row"}},
+		{`CREATE INDEX i ON images ("tile_id" COLLATE NOCASE DESC)`, []string{"tile_id"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := indexColumns(tt.sql); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("indexColumns(%q) = %v, ожидалось %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestCompareKey(t *testing.T) {
+	tests := []struct {
+		values, key []interface{}
+		want        int
+	}{
+		{[]interface{}{int64(1), int64(2), int64(3), int64(7)}, []interface{}{int64(1), int64(2), int64(3)}, 0},
+		{[]interface{}{int64(1), int64(2)}, []interface{}{int64(1), int64(3)}, -1},
+		{[]interface{}{int64(2)}, []interface{}{1.5}, 1},
+		{[]interface{}{nil}, []interface{}{int64(0)}, -1},
+		{[]interface{}{"a"}, []interface{}{int64(100)}, 1},
+		{[]interface{}{"abc"}, []interface{}{"abd"}, -1},
+		{[]interface{}{[]byte("a")}, []interface{}{"z"}, 1},
+		{[]interface{}{int64(1)}, []interface{}{int64(1), int64(2)}, -1},
+	}
+
+	for _, tt := range tests {
+		if got := compareKey(tt.values, tt.key); got != tt.want {
+			t.Errorf("compareKey(%v, %v) = %d, ожидалось %d", tt.values, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeRecordLimit(t *testing.T) {
+	// Заголовок: размер 4, типы int8 (1), текст длины 2 (17), BLOB длины 3 (18)
+	payload := []byte{4, 1, 17, 18, 0xff, 'h', 'i', 1, 2, 3}
+
+	values, err := decodeRecord(payload, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{int64(-1), "hi", []byte{1, 2, 3}}; !reflect.DeepEqual(values, want) {
+		t.Errorf("decodeRecord = %v, ожидалось %v", values, want)
+	}
+
+	// Первые два значения разбираются без данных BLOB
+	values, err = decodeRecord(payload[:7], 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{int64(-1), "hi"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("decodeRecord(limit 2) = %v, ожидалось %v", values, want)
+	}
+
+	if _, err := decodeRecord(payload[:7], -1); err == nil {
+		t.Error("decodeRecord обрезанной записи не вернул ошибку")
+	}
+}
+
+func truncate(b []byte) []byte {
+	if len(b) > 20 {
+		return b[:20]
+	}
+	return b
+}
+
+func TestOpenRejectsJournal(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "tiles.mbtiles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		journal string // суффикс файла журнала, пусто - без журнала
+		content string
+		wantErr bool
+	}{
+		{name: "без журнала"},
+		{name: "пустой WAL после checkpoint", journal: "-wal"},
+		{name: "WAL с изменениями", journal: "-wal", content: "frames", wantErr: true},
+		{name: "незавершенная транзакция", journal: "-journal", content: "pages", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tiles.mbtiles")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.journal != "" {
+				if err := os.WriteFile(path+tt.journal, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r, err := Open(path)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.journal) {
+					t.Fatalf("Open = %v, ожидалась ошибка журнала %s", err, tt.journal)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Close()
+		})
+	}
+}
+
+func TestHasExtension(t *testing.T) {
+	tests := []struct {
+		format string
+		ext    string
+		want   bool
+	}{
+		{"", "png", true},
+		{"png", "png", true},
+		{"png", "PNG", true},
+		{"png", "jpg", false},
+		{"png", "", false},
+		{"jpg", "jpeg", true},
+		{"jpeg", "jpg", true},
+		{"webp", "webp", true},
+		{"webp", "png", false},
+	}
+
+	for _, tt := range tests {
+		r := &Reader{metadata: map[string]string{"format": tt.format}}
+		if got := r.HasExtension(tt.ext); got != tt.want {
+			t.Errorf("формат %q, расширение %q: %v, ожидалось %v", tt.format, tt.ext, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,715 @@
+package mbtiles
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Минимальное чтение файла SQLite: b-деревья таблиц и индексов (без WITHOUT ROWID
+// и журналов), которых достаточно для чтения MBTiles. Файл с непустым журналом
+// (-wal или -journal) не открывается: изменения из журнала не видны при чтении файла.
+
+const (
+	sqliteMagic = "SQLite format 3\x00"
+
+	pageInteriorIndex = 0x02
+	pageInteriorTable = 0x05
+	pageLeafIndex     = 0x0a
+	pageLeafTable     = 0x0d
+
+	// maxTreeDepth - защита от циклов в поврежденном файле
+	maxTreeDepth = 64
+)
+
+var errCorrupt = errors.New("файл SQLite поврежден")
+
+type sqliteFile struct {
+	f        *os.File
+	pageSize int
+	usable   int // размер страницы без зарезервированной области
+}
+
+// schemaObject - строка sqlite_master
+type schemaObject struct {
+	kind  string // table, view, index, trigger
+	name  string
+	table string // таблица индекса
+	root  uint32
+	sql   string
+}
+
+func openSQLite(path string) (*sqliteFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 100)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("не удалось прочитать заголовок: %w", err)
+	}
+	if string(header[:16]) != sqliteMagic {
+		f.Close()
+		return nil, fmt.Errorf("%s не является базой SQLite", path)
+	}
+	if encoding := binary.BigEndian.Uint32(header[56:60]); encoding > 1 {
+		f.Close()
+		return nil, fmt.Errorf("кодировка UTF-16 не поддерживается")
+	}
+
+	// Журнал не применяется при чтении: тайлы из него были бы молча потеряны
+	for _, suffix := range []string{"-wal", "-journal"} {
+		if info, err := os.Stat(path + suffix); err == nil && info.Size() > 0 {
+			f.Close()
+			return nil, fmt.Errorf("%s%s: изменения журнала не перенесены в файл, выполните sqlite3 %s \"PRAGMA wal_checkpoint(TRUNCATE)\"",
+				path, suffix, path)
+		}
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		f.Close()
+		return nil, errCorrupt
+	}
+
+	return &sqliteFile{
+		f:        f,
+		pageSize: pageSize,
+		usable:   pageSize - int(header[20]),
+	}, nil
+}
+
+func (s *sqliteFile) Close() error {
+	return s.f.Close()
+}
+
+// page читает страницу по номеру (нумерация с 1)
+func (s *sqliteFile) page(n uint32) ([]byte, error) {
+	if n == 0 {
+		return nil, errCorrupt
+	}
+	buf := make([]byte, s.pageSize)
+	read, err := s.f.ReadAt(buf, int64(n-1)*int64(s.pageSize))
+	if err != nil && !(errors.Is(err, io.EOF) && read == len(buf)) {
+		return nil, fmt.Errorf("страница %d: %w", n, err)
+	}
+	return buf, nil
+}
+
+// btreePage - страница b-дерева таблицы или индекса
+type btreePage struct {
+	data []byte
+	hdr  int // смещение заголовка (100 для первой страницы)
+}
+
+func (s *sqliteFile) btreePage(n uint32) (btreePage, error) {
+	data, err := s.page(n)
+	if err != nil {
+		return btreePage{}, err
+	}
+	p := btreePage{data: data}
+	if n == 1 {
+		p.hdr = 100
+	}
+	switch p.kind() {
+	case pageInteriorIndex, pageInteriorTable, pageLeafIndex, pageLeafTable:
+		return p, nil
+	default:
+		return btreePage{}, fmt.Errorf("страница %d: неподдерживаемый тип 0x%02x", n, p.kind())
+	}
+}
+
+func (p btreePage) kind() byte {
+	return p.data[p.hdr]
+}
+
+func (p btreePage) interior() bool {
+	return p.kind() == pageInteriorIndex || p.kind() == pageInteriorTable
+}
+
+func (p btreePage) index() bool {
+	return p.kind() == pageInteriorIndex || p.kind() == pageLeafIndex
+}
+
+func (p btreePage) cellCount() int {
+	return int(binary.BigEndian.Uint16(p.data[p.hdr+3:]))
+}
+
+func (p btreePage) rightmost() uint32 {
+	return binary.BigEndian.Uint32(p.data[p.hdr+8:])
+}
+
+// cell - смещение ячейки i на странице
+func (p btreePage) cell(i int) (int, error) {
+	headerSize := 8
+	if p.interior() {
+		headerSize = 12
+	}
+	ptr := p.hdr + headerSize + 2*i
+	if ptr+2 > len(p.data) {
+		return 0, errCorrupt
+	}
+	off := int(binary.BigEndian.Uint16(p.data[ptr:]))
+	if off >= len(p.data) {
+		return 0, errCorrupt
+	}
+	return off, nil
+}
+
+// childCell - левый потомок и ключ ячейки внутренней страницы таблицы
+func (p btreePage) childCell(i int) (uint32, int64, error) {
+	off, err := p.cell(i)
+	if err != nil {
+		return 0, 0, err
+	}
+	if off+4 > len(p.data) {
+		return 0, 0, errCorrupt
+	}
+	key, n := readVarint(p.data[off+4:])
+	if n == 0 {
+		return 0, 0, errCorrupt
+	}
+	return binary.BigEndian.Uint32(p.data[off:]), int64(key), nil
+}
+
+// cellPayload - содержимое ячейки: часть на странице и начало цепочки переполнения
+type cellPayload struct {
+	rowid    int64  // ключ ячейки листа таблицы
+	child    uint32 // левый потомок ячейки внутренней страницы индекса
+	size     int
+	local    []byte
+	overflow uint32
+}
+
+// payloadCell разбирает ячейку с содержимым (лист таблицы, страницы индекса)
+func (s *sqliteFile) payloadCell(p btreePage, i int) (cellPayload, error) {
+	off, err := p.cell(i)
+	if err != nil {
+		return cellPayload{}, err
+	}
+
+	var c cellPayload
+	if p.kind() == pageInteriorIndex {
+		if off+4 > len(p.data) {
+			return cellPayload{}, errCorrupt
+		}
+		c.child = binary.BigEndian.Uint32(p.data[off:])
+		off += 4
+	}
+
+	size, n := readVarint(p.data[off:])
+	if n == 0 || size > math.MaxInt32 {
+		return cellPayload{}, errCorrupt
+	}
+	off += n
+	c.size = int(size)
+
+	if p.kind() == pageLeafTable {
+		rowid, n := readVarint(p.data[off:])
+		if n == 0 {
+			return cellPayload{}, errCorrupt
+		}
+		off += n
+		c.rowid = int64(rowid)
+	}
+
+	local := s.localPayload(c.size, p.kind() == pageLeafTable)
+	if off+local > len(p.data) {
+		return cellPayload{}, errCorrupt
+	}
+	c.local = p.data[off : off+local]
+	if local < c.size {
+		if off+local+4 > len(p.data) {
+			return cellPayload{}, errCorrupt
+		}
+		c.overflow = binary.BigEndian.Uint32(p.data[off+local:])
+	}
+
+	return c, nil
+}
+
+// payload - полное содержимое ячейки (с переполнением)
+func (s *sqliteFile) payload(c cellPayload) ([]byte, error) {
+	if len(c.local) == c.size {
+		return c.local, nil
+	}
+
+	payload := make([]byte, 0, c.size)
+	payload = append(payload, c.local...)
+	next := c.overflow
+	for len(payload) < c.size {
+		if next == 0 {
+			return nil, errCorrupt
+		}
+		data, err := s.page(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(data)
+		chunk := c.size - len(payload)
+		if chunk > s.usable-4 {
+			chunk = s.usable - 4
+		}
+		payload = append(payload, data[4:4+chunk]...)
+	}
+	return payload, nil
+}
+
+// record разбирает первые limit значений записи ячейки (limit < 0 - все). Страницы переполнения
+// читаются, только если нужные значения не поместились на странице ячейки.
+func (s *sqliteFile) record(c cellPayload, limit int) ([]interface{}, error) {
+	if len(c.local) < c.size {
+		if values, err := decodeRecord(c.local, limit); err == nil {
+			return values, nil
+		}
+	}
+	payload, err := s.payload(c)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecord(payload, limit)
+}
+
+// scan обходит все строки таблицы в порядке rowid, разбирая первые limit столбцов (limit < 0 - все)
+func (s *sqliteFile) scan(root uint32, limit int, fn func(rowid int64, values []interface{}) error) error {
+	return s.scanPage(root, 0, limit, fn)
+}
+
+func (s *sqliteFile) scanPage(n uint32, depth, limit int, fn func(rowid int64, values []interface{}) error) error {
+	if depth > maxTreeDepth {
+		return errCorrupt
+	}
+	p, err := s.btreePage(n)
+	if err != nil {
+		return err
+	}
+	if p.index() {
+		return fmt.Errorf("страница %d: ожидалась страница таблицы", n)
+	}
+
+	if p.interior() {
+		for i := 0; i < p.cellCount(); i++ {
+			child, _, err := p.childCell(i)
+			if err != nil {
+				return err
+			}
+			if err := s.scanPage(child, depth+1, limit, fn); err != nil {
+				return err
+			}
+		}
+		return s.scanPage(p.rightmost(), depth+1, limit, fn)
+	}
+
+	for i := 0; i < p.cellCount(); i++ {
+		c, err := s.payloadCell(p, i)
+		if err != nil {
+			return err
+		}
+		values, err := s.record(c, limit)
+		if err != nil {
+			return err
+		}
+		if err := fn(c.rowid, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup ищет строку таблицы по rowid; nil - строки нет
+func (s *sqliteFile) lookup(root uint32, rowid int64) ([]interface{}, error) {
+	n := root
+	for depth := 0; depth <= maxTreeDepth; depth++ {
+		p, err := s.btreePage(n)
+		if err != nil {
+			return nil, err
+		}
+		if p.index() {
+			return nil, fmt.Errorf("страница %d: ожидалась страница таблицы", n)
+		}
+
+		if p.interior() {
+			// Ключ ячейки - максимальный rowid ее левого поддерева
+			n = p.rightmost()
+			for i := 0; i < p.cellCount(); i++ {
+				child, key, err := p.childCell(i)
+				if err != nil {
+					return nil, err
+				}
+				if rowid <= key {
+					n = child
+					break
+				}
+			}
+			continue
+		}
+
+		for i := 0; i < p.cellCount(); i++ {
+			c, err := s.payloadCell(p, i)
+			if err != nil {
+				return nil, err
+			}
+			if c.rowid == rowid {
+				return s.record(c, -1)
+			}
+		}
+		return nil, nil
+	}
+	return nil, errCorrupt
+}
+
+// seekIndex ищет в индексе запись, первые значения которой равны key; nil - записи нет.
+// Запись индекса - значения столбцов индекса и rowid строки таблицы последним значением.
+func (s *sqliteFile) seekIndex(root uint32, key []interface{}) ([]interface{}, error) {
+	n := root
+	for depth := 0; depth <= maxTreeDepth; depth++ {
+		p, err := s.btreePage(n)
+		if err != nil {
+			return nil, err
+		}
+		if !p.index() {
+			return nil, fmt.Errorf("страница %d: ожидалась страница индекса", n)
+		}
+
+		// Первая ячейка с записью не меньше key
+		var cell cellPayload
+		lo, hi := 0, p.cellCount()
+		for lo < hi {
+			mid := (lo + hi) / 2
+			c, err := s.payloadCell(p, mid)
+			if err != nil {
+				return nil, err
+			}
+			values, err := s.record(c, len(key))
+			if err != nil {
+				return nil, err
+			}
+			if compareKey(values, key) < 0 {
+				lo = mid + 1
+			} else {
+				hi = mid
+				cell = c
+			}
+		}
+
+		if lo < p.cellCount() {
+			values, err := s.record(cell, -1)
+			if err != nil {
+				return nil, err
+			}
+			if compareKey(values, key) == 0 {
+				return values, nil
+			}
+		}
+		if !p.interior() {
+			return nil, nil
+		}
+
+		// Записи меньше ключа ячейки lo - в ее левом поддереве
+		n = p.rightmost()
+		if lo < p.cellCount() {
+			n = cell.child
+		}
+	}
+	return nil, errCorrupt
+}
+
+// edgeIndex - первая (last = false) или последняя запись индекса; nil - индекс пуст
+func (s *sqliteFile) edgeIndex(root uint32, last bool) ([]interface{}, error) {
+	n := root
+	for depth := 0; depth <= maxTreeDepth; depth++ {
+		p, err := s.btreePage(n)
+		if err != nil {
+			return nil, err
+		}
+		if !p.index() {
+			return nil, fmt.Errorf("страница %d: ожидалась страница индекса", n)
+		}
+
+		if p.interior() {
+			if last {
+				n = p.rightmost()
+				continue
+			}
+			c, err := s.payloadCell(p, 0)
+			if err != nil {
+				return nil, err
+			}
+			n = c.child
+			continue
+		}
+
+		if p.cellCount() == 0 {
+			return nil, nil
+		}
+		i := 0
+		if last {
+			i = p.cellCount() - 1
+		}
+		c, err := s.payloadCell(p, i)
+		if err != nil {
+			return nil, err
+		}
+		return s.record(c, -1)
+	}
+	return nil, errCorrupt
+}
+
+// localPayload - часть содержимого ячейки, хранящаяся на самой странице
+func (s *sqliteFile) localPayload(size int, tableLeaf bool) int {
+	maxLocal := (s.usable-12)*64/255 - 23
+	if tableLeaf {
+		maxLocal = s.usable - 35
+	}
+	if size <= maxLocal {
+		return size
+	}
+	minLocal := (s.usable-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(s.usable-4)
+	if local <= maxLocal {
+		return local
+	}
+	return minLocal
+}
+
+// schema читает sqlite_master
+func (s *sqliteFile) schema() (map[string]schemaObject, error) {
+	objects := make(map[string]schemaObject)
+	err := s.scan(1, -1, func(rowid int64, values []interface{}) error {
+		if len(values) < 5 {
+			return errCorrupt
+		}
+		obj := schemaObject{}
+		obj.kind, _ = values[0].(string)
+		obj.name, _ = values[1].(string)
+		obj.table, _ = values[2].(string)
+		obj.table = strings.ToLower(obj.table)
+		obj.sql, _ = values[4].(string)
+		if root, ok := values[3].(int64); ok {
+			obj.root = uint32(root)
+		}
+		objects[strings.ToLower(obj.name)] = obj
+		return nil
+	})
+	return objects, err
+}
+
+// decodeRecord разбирает первые limit значений записи SQLite (limit < 0 - все):
+// nil, int64, float64, string, []byte. Следующие значения не читаются, поэтому
+// payload может быть обрезан после них.
+func decodeRecord(payload []byte, limit int) ([]interface{}, error) {
+	headerSize, n := readVarint(payload)
+	if n == 0 || headerSize > uint64(len(payload)) {
+		return nil, errCorrupt
+	}
+
+	var types []uint64
+	for pos := n; pos < int(headerSize) && (limit < 0 || len(types) < limit); {
+		t, k := readVarint(payload[pos:headerSize])
+		if k == 0 {
+			return nil, errCorrupt
+		}
+		types = append(types, t)
+		pos += k
+	}
+
+	values := make([]interface{}, len(types))
+	pos := int(headerSize)
+	for i, t := range types {
+		switch {
+		case t == 0:
+			values[i] = nil
+		case t <= 6:
+			width := [...]int{0, 1, 2, 3, 4, 6, 8}[t]
+			if pos+width > len(payload) {
+				return nil, errCorrupt
+			}
+			var v int64
+			for _, b := range payload[pos : pos+width] {
+				v = v<<8 | int64(b)
+			}
+			shift := uint(64 - 8*width)
+			values[i] = v << shift >> shift
+			pos += width
+		case t == 7:
+			if pos+8 > len(payload) {
+				return nil, errCorrupt
+			}
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(payload[pos:]))
+			pos += 8
+		case t == 8:
+			values[i] = int64(0)
+		case t == 9:
+			values[i] = int64(1)
+		case t >= 12:
+			size := int((t - 12) / 2)
+			if size < 0 || pos+size > len(payload) {
+				return nil, errCorrupt
+			}
+			if t%2 == 0 {
+				values[i] = payload[pos : pos+size]
+			} else {
+				values[i] = string(payload[pos : pos+size])
+			}
+			pos += size
+		default:
+			return nil, errCorrupt
+		}
+	}
+
+	return values, nil
+}
+
+// readVarint читает varint SQLite; 0 байт - число обрезано
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	if len(b) < 9 {
+		return 0, 0
+	}
+	return v<<8 | uint64(b[8]), 9
+}
+
+// tableColumns - имена столбцов из CREATE TABLE и индекс столбца-псевдонима rowid (-1 - нет)
+func tableColumns(sql string) ([]string, int) {
+	start := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if start < 0 || end <= start {
+		return nil, -1
+	}
+
+	var parts []string
+	depth, last := 0, start+1
+	for i := start + 1; i < end; i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, sql[last:i])
+				last = i + 1
+			}
+		}
+	}
+	parts = append(parts, sql[last:end])
+
+	var columns []string
+	rowidColumn := -1
+	for _, part := range parts {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.Trim(fields[0], "\"`[]'")
+		switch strings.ToUpper(name) {
+		case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT":
+			continue
+		}
+
+		definition := strings.ToUpper(strings.Join(fields[1:], " "))
+		if strings.HasPrefix(definition, "INTEGER") && strings.Contains(definition, "PRIMARY KEY") {
+			rowidColumn = len(columns)
+		}
+		columns = append(columns, strings.ToLower(name))
+	}
+
+	return columns, rowidColumn
+}
+
+// compareKey сравнивает первые len(key) значений записи с key в порядке SQLite:
+// NULL < числа < текст < BLOB
+func compareKey(values, key []interface{}) int {
+	for i, k := range key {
+		if i >= len(values) {
+			return -1
+		}
+		if c := compareValue(values[i], k); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareValue(a, b interface{}) int {
+	if ra, rb := valueRank(a), valueRank(b); ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch a := a.(type) {
+	case int64, float64:
+		fa, fb := toFloat(a), toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case []byte:
+		return strings.Compare(string(a), string(b.([]byte)))
+	}
+	return 0
+}
+
+func valueRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int64, float64:
+		return 1
+	case string:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func toFloat(v interface{}) float64 {
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v.(float64)
+}
+
+// indexColumns - столбцы индекса из CREATE INDEX (без ASC/DESC и COLLATE)
+func indexColumns(sql string) []string {
+	start := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+
+	var columns []string
+	for _, part := range strings.Split(sql[start+1:end], ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			return nil
+		}
+		columns = append(columns, strings.ToLower(strings.Trim(fields[0], "\"`[]'")))
+	}
+	return columns
+}
@@ -0,0 +1,101 @@
+"""Генерирует файлы MBTiles для тестов пакета mbtiles (запускать из этой директории).
+
+Страница 512 байт, чтобы в маленьких файлах были внутренние страницы b-деревьев
+и страницы переполнения. Содержимое тайла - tileData в mbtiles_test.go.
+"""
+import os
+import sqlite3
+
+MAX_ZOOM = 4
+
+
+def tile_data(z, x, y):
+    """Данные тайла по координатам XYZ; каждый 11-й тайл больше страницы (переполнение)."""
+    label = f"{z}/{x}/{y}".encode()
+    if (x + y) % 11 == 0:
+        return (label * (1500 // len(label) + 1))[:1500]
+    return label
+
+
+def shared(z, x):
+    """Тайлы с одинаковым содержимым в схеме map/images (одна строка images)."""
+    return z == MAX_ZOOM and x >= 12
+
+
+SHARED_DATA = b"sea" * 600
+
+
+def tiles():
+    for z in range(MAX_ZOOM + 1):
+        for x in range(1 << z):
+            for y in range(1 << z):
+                # В MBTiles строки тайлов хранятся в схеме TMS
+                yield z, x, (1 << z) - 1 - y, y
+
+
+def create(name, schema, fill, fmt="png"):
+    if os.path.exists(name):
+        os.remove(name)
+    db = sqlite3.connect(name)
+    db.execute("PRAGMA page_size = 512")
+    db.executescript(schema)
+    db.execute("CREATE TABLE metadata (name text, value text)")
+    db.executemany("INSERT INTO metadata VALUES (?, ?)",
+                   [("name", name), ("format", fmt), ("minzoom", "0"), ("maxzoom", str(MAX_ZOOM))])
+    fill(db)
+    db.commit()
+    db.execute("VACUUM")
+    db.close()
+
+
+def fill_tiles(db):
+    db.executemany("INSERT INTO tiles VALUES (?, ?, ?, ?)",
+                   [(z, x, row, tile_data(z, x, y)) for z, x, row, y in tiles()])
+
+
+def fill_map_images(db, integer_ids):
+    images = {}
+    for z, x, row, y in tiles():
+        tile_id = "shared" if shared(z, x) else f"{z}/{x}/{y}"
+        if tile_id not in images:
+            images[tile_id] = len(images) + 1
+            data = SHARED_DATA if tile_id == "shared" else tile_data(z, x, y)
+            key = images[tile_id] if integer_ids else tile_id
+            db.execute("INSERT INTO images (tile_id, tile_data) VALUES (?, ?)", (key, data))
+        key = images[tile_id] if integer_ids else tile_id
+        db.execute("INSERT INTO map VALUES (?, ?, ?, ?)", (z, x, row, key))
+
+
+TILES = """
+CREATE TABLE tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob);
+"""
+
+MAP_IMAGES_VIEW = """
+CREATE VIEW tiles AS SELECT map.zoom_level AS zoom_level, map.tile_column AS tile_column,
+    map.tile_row AS tile_row, images.tile_data AS tile_data
+    FROM map JOIN images ON images.tile_id = map.tile_id;
+"""
+
+# mbutil: таблица tiles с индексом координат
+create("tiles.mbtiles", TILES + "CREATE UNIQUE INDEX tile_index ON tiles (zoom_level, tile_column, tile_row);",
+       fill_tiles)
+
+# Таблица tiles без индекса - индекс координат строится в памяти
+create("noindex.mbtiles", TILES, fill_tiles)
+
+# mbutil со сжатием: map/images с индексами, одинаковые тайлы - одна строка images
+create("mapimages.mbtiles", """
+CREATE TABLE map (zoom_level INTEGER, tile_column INTEGER, tile_row INTEGER, tile_id TEXT);
+CREATE TABLE images (tile_data blob, tile_id text);
+CREATE UNIQUE INDEX map_index ON map (zoom_level, tile_column, tile_row);
+CREATE UNIQUE INDEX images_id ON images (tile_id);
+""" + MAP_IMAGES_VIEW, lambda db: fill_map_images(db, False))
+
+# map/images без индексов, tile_id - INTEGER PRIMARY KEY (псевдоним rowid)
+create("mapimages_rowid.mbtiles", """
+CREATE TABLE map (zoom_level INTEGER, tile_column INTEGER, tile_row INTEGER, tile_id INTEGER);
+CREATE TABLE images (tile_id INTEGER PRIMARY KEY, tile_data blob);
+""" + MAP_IMAGES_VIEW, lambda db: fill_map_images(db, True))
+
+# Векторные тайлы - не поддерживаются
+create("vector.mbtiles", TILES, lambda db: None, fmt="pbf")
@@ -20,10 +20,15 @@ type algorithmService struct {
 type AlgorithmService interface {
 	// Для консольного режима
 	RunAlgorithm()
+	RunBranchDiff(base, target domain.BranchRun) error
 	
 	// Для API режима
 	GetBranchAnalysis() (*responses.Task1Response, error)
 	GetDepotBranches(depoCode string) (*responses.DepotBranches, error)
+	GetBranchDetails(depoCode, branchID string) (*responses.BranchDetails, error)
+	GetLocomotiveBranches(series, number string) (*responses.LocomotiveBranches, error)
+	GetLocomotiveBranchesFrom(locomotives map[string]domain.Locomotive, series, number string) (*responses.LocomotiveBranches, error)
+	CompareBranches(base, target domain.BranchRun) (*responses.BranchDiff, error)
 }
 
 func NewAlgorithmService(dataPath, stationsPath string) AlgorithmService {
@@ -160,11 +165,11 @@ func extractUniqueStations(path []string) []string {
 	return unique
 }
 
-// clusterPaths - группирует похожие пути в кластеры
-func clusterPaths(paths [][]string, depoID string) [][][]string {
-	var clusters [][][]string
+// clusterPathIndices - группирует похожие пути в кластеры, возвращая индексы путей
+func clusterPathIndices(paths [][]string, depoID string) [][]int {
+	var clusters [][]int
 
-	for _, path := range paths {
+	for idx, path := range paths {
 		if len(path) < 2 {
 			continue
 		}
@@ -182,16 +187,16 @@ func clusterPaths(paths [][]string, depoID string) [][][]string {
 			}
 
 			// Проверяем первый путь в кластере
-			clusterCore := extractCorePath(cluster[0], depoID)
+			clusterCore := extractCorePath(paths[cluster[0]], depoID)
 			if isSimilarCore(corePath, clusterCore) {
-				clusters[i] = append(clusters[i], path)
+				clusters[i] = append(clusters[i], idx)
 				found = true
 				break
 			}
 		}
 
 		if !found {
-			clusters = append(clusters, [][]string{path})
+			clusters = append(clusters, []int{idx})
 		}
 	}
 
@@ -270,8 +275,11 @@ func findCoreDirection(cluster [][]string, depoID string) []string {
 		positions = append(positions, stationInfo{s, avg})
 	}
 
-	// Сортируем по средней позиции
+	// Сортируем по средней позиции (при равенстве - по ID, чтобы результат был стабильным)
 	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].avgPos == positions[j].avgPos {
+			return positions[i].station < positions[j].station
+		}
 		return positions[i].avgPos < positions[j].avgPos
 	})
 
@@ -337,17 +345,8 @@ func (a *algorithmService) RunAlgorithm() {
 
 // GetBranchAnalysis - для API режима (полный анализ)
 func (a *algorithmService) GetBranchAnalysis() (*responses.Task1Response, error) {
-	// 1. Загружаем данные
-	locomotives := loadData(a.dataPath)
-
-	// 2. Разбиваем на поездки
-	for key, loc := range locomotives {
-		loc.Trips = splitIntoTrips(loc.Records)
-		locomotives[key] = loc
-	}
-
-	// 3. Анализ веток
-	depotBranches := a.analyzeBranchesImproved(locomotives)
+	// 1-3. Загружаем данные, разбиваем на поездки и анализируем ветки
+	depotBranches := a.computeBranches()
 
 	// 4. Формируем ответ (с названиями)
 	return a.buildTask1Response(depotBranches), nil
@@ -355,17 +354,8 @@ func (a *algorithmService) GetBranchAnalysis() (*responses.Task1Response, error)
 
 // GetDepotBranches - для API режима (конкретное депо)
 func (a *algorithmService) GetDepotBranches(depoCode string) (*responses.DepotBranches, error) {
-	// 1. Загружаем данные
-	locomotives := loadData(a.dataPath)
-
-	// 2. Разбиваем на поездки
-	for key, loc := range locomotives {
-		loc.Trips = splitIntoTrips(loc.Records)
-		locomotives[key] = loc
-	}
-
-	// 3. Анализ веток
-	depotBranches := a.analyzeBranchesImproved(locomotives)
+	// 1-3. Загружаем данные, разбиваем на поездки и анализируем ветки
+	depotBranches := a.computeBranches()
 
 	// 4. Ищем нужное депо
 	branches, exists := depotBranches[depoCode]
@@ -379,10 +369,25 @@ func (a *algorithmService) GetDepotBranches(depoCode string) (*responses.DepotBr
 
 // analyzeBranchesImproved - улучшенный анализ веток с кластеризацией (ID остаются внутри)
 func (a *algorithmService) analyzeBranchesImproved(locomotives map[string]domain.Locomotive) map[string][]domain.ImprovedBranch {
-	// Собираем все пути по депо
+	return buildImprovedBranches(locomotives)
+}
+
+// buildImprovedBranches - кластеризует пути локомотивов в ветки по депо
+func buildImprovedBranches(locomotives map[string]domain.Locomotive) map[string][]domain.ImprovedBranch {
+	// Собираем все пути по депо (и поездки, из которых они получены)
 	allPaths := make(map[string][][]string)
+	allTrips := make(map[string][]domain.BranchTrip)
+
+	// Обходим локомотивы в фиксированном порядке, чтобы кластеры и ID веток
+	// не менялись от запуска к запуску
+	keys := make([]string, 0, len(locomotives))
+	for key := range locomotives {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	for _, loc := range locomotives {
+	for _, key := range keys {
+		loc := locomotives[key]
 		for _, trip := range loc.Trips {
 			if len(trip.Stations) < 2 {
 				continue
@@ -393,6 +398,13 @@ func (a *algorithmService) analyzeBranchesImproved(locomotives map[string]domain
 
 			// Добавляем путь в общий список для депо
 			allPaths[loc.Depo] = append(allPaths[loc.Depo], cleanPath)
+			allTrips[loc.Depo] = append(allTrips[loc.Depo], domain.BranchTrip{
+				LocomotiveKey: key,
+				Series:        loc.Series,
+				Number:        loc.Number,
+				StartTime:     trip.StartTime,
+				EndTime:       trip.EndTime,
+			})
 		}
 	}
 
@@ -403,13 +415,21 @@ func (a *algorithmService) analyzeBranchesImproved(locomotives map[string]domain
 		fmt.Printf("  Анализ депо %s: %d путей\n", depo, len(paths))
 
 		// Группируем похожие пути
-		clusters := clusterPaths(paths, depo)
+		clusters := clusterPathIndices(paths, depo)
+		usedIDs := make(map[string]int)
 
-		for _, cluster := range clusters {
-			if len(cluster) == 0 {
+		for _, indices := range clusters {
+			if len(indices) == 0 {
 				continue
 			}
 
+			cluster := make([][]string, 0, len(indices))
+			trips := make([]domain.BranchTrip, 0, len(indices))
+			for _, idx := range indices {
+				cluster = append(cluster, paths[idx])
+				trips = append(trips, allTrips[depo][idx])
+			}
+
 			// Определяем основное направление
 			coreDirection := findCoreDirection(cluster, depo)
 
@@ -427,17 +447,28 @@ func (a *algorithmService) analyzeBranchesImproved(locomotives map[string]domain
 				}
 			}
 
+			// ID ветки должен быть уникальным в пределах депо
+			branchID := generateBranchID(coreDirection)
+			usedIDs[branchID]++
+			if usedIDs[branchID] > 1 {
+				branchID = fmt.Sprintf("%s_%d", branchID, usedIDs[branchID])
+			}
+
 			branch := domain.ImprovedBranch{
 				Depo:         depo,
 				CoreStations: coreDirection,
-				BranchID:     generateBranchID(coreDirection),
+				BranchID:     branchID,
 				AllPaths:     cluster,
+				Trips:        trips,
 				Terminals:    terminals,
 				Length:       len(coreDirection),
 			}
 
 			depotBranches[depo] = append(depotBranches[depo], branch)
 		}
+
+		// Оцениваем качество кластеризации
+		evaluateBranchQuality(depotBranches[depo], depo)
 	}
 
 	return depotBranches
@@ -512,9 +543,10 @@ func (a *algorithmService) buildDepotBranchesResponse(depoCode string, branches
 	})
 
 	depotResponse := &responses.DepotBranches{
-		DepoCode:    depoCode,
-		BranchCount: len(branches),
-		Branches:    make([]responses.BranchInfo, 0, len(branches)),
+		DepoCode:     depoCode,
+		BranchCount:  len(branches),
+		QualityScore: depotQualityScore(branches),
+		Branches:     make([]responses.BranchInfo, 0, len(branches)),
 	}
 
 	for _, branch := range branches {
@@ -564,6 +596,11 @@ func (a *algorithmService) buildDepotBranchesResponse(depoCode string, branches
 			StationCount:  len(branch.CoreStations),
 			Terminals:     terminals,
 			ExamplePath:   examplePath,
+			Quality: responses.BranchQualityInfo{
+				Cohesion:   branch.Quality.Cohesion,
+				Separation: branch.Quality.Separation,
+				Coverage:   branch.Quality.Coverage,
+			},
 		}
 
 		depotResponse.Branches = append(depotResponse.Branches, branchInfo)
@@ -598,6 +635,7 @@ func (a *algorithmService) printImprovedResults(depotBranches map[string][]domai
 
 		fmt.Printf("Депо %s:\n", a.getStationName(depo))
 		fmt.Printf("  Реальных веток: %d\n", len(branches))
+		fmt.Printf("  Оценка качества кластеризации: %.2f\n", depotQualityScore(branches))
 		fmt.Println()
 
 		for i, branch := range branches {
@@ -617,6 +655,12 @@ func (a *algorithmService) printImprovedResults(depotBranches map[string][]domai
 			fmt.Printf("  Ветка %d (ID: %s):\n", i+1, branch.BranchID)
 			fmt.Printf("    Основной маршрут (%d станций): %v\n",
 				len(branch.CoreStations), a.convertStationsToNames(branch.CoreStations))
+			separation := "—"
+			if branch.Quality.Separation != nil {
+				separation = fmt.Sprintf("%.2f", *branch.Quality.Separation)
+			}
+			fmt.Printf("    Качество: связность %.2f, отделимость %s, покрытие %.0f%%\n",
+				branch.Quality.Cohesion, separation, branch.Quality.Coverage*100)
 
 			if len(terminals) > 0 {
 				fmt.Printf("    Конечные станции (с частотой):\n")
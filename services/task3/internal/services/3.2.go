@@ -14,6 +14,10 @@ import (
     "github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
 )
 
+// defaultMatchThreshold - минимальная схожесть маршрутов (0..1), при которой
+// поездка считается поездкой по направлению, если порог не задан явно
+const defaultMatchThreshold = 0.5
+
 type mostPopularTripService struct {
     dataPath     string
     stationsPath string
@@ -21,9 +25,13 @@ type mostPopularTripService struct {
 }
 
 type MostPopularTripService interface {
-    RunMostPopularTrip()
-    GetPopularDirections() (*responses.Task2Response, error)
-    GetLocomotivePopularDirection(series, number string) (*responses.LocomotiveStats, error)
+    RunMostPopularTrip(opts domain.DirectionOptions)
+    GetPopularDirections(opts domain.DirectionOptions, filter domain.PopularDirectionFilter) (*responses.Task2Response, error)
+    GetLocomotivePopularDirection(series, number string, opts domain.DirectionOptions) (*responses.LocomotiveStats, error)
+    GetLocomotivePopularDirectionFrom(locomotives map[string]domain.Locomotive, series, number string, opts domain.DirectionOptions) (*responses.LocomotiveStats, error)
+    GetLocomotiveDirectionHistory(series, number, bucket string, opts domain.DirectionOptions) (*responses.LocomotiveDirectionHistory, error)
+    GetLocomotiveNextDirection(series, number string, order int, opts domain.DirectionOptions) (*responses.NextDirectionPrediction, error)
+    GetLocomotiveClusters(clusterOpts domain.ClusterOptions, opts domain.DirectionOptions) (*responses.LocomotiveClusters, error)
 }
 
 func NewMostPopularTripService(dataPath, stationsPath string) MostPopularTripService {
@@ -246,12 +254,21 @@ func (m *mostPopularTripService) cleanStops(stations []string) []string {
     return result
 }
 
-// identifyDirectionsFromTrips - определяет направления на основе маршрутов поездок
-func (m *mostPopularTripService) identifyDirectionsFromTrips(locomotives map[string]domain.Locomotive) map[string][]domain.Direction {
-    // Собираем все уникальные маршруты для каждого депо
-    depotRoutes := make(map[string]map[string]*domain.Direction)
+// identifyDirectionsFromTrips - определяет направления на основе маршрутов поездок.
+// Направление - все поездки из депо до одной конечной станции; различные пути
+// до нее сохраняются как варианты маршрута. При splitVariants каждый вариант
+// становится отдельным направлением со ссылкой на общее (ParentID); общие
+// направления в результат не входят (см. withParentDirections).
+func (m *mostPopularTripService) identifyDirectionsFromTrips(
+    locomotives map[string]domain.Locomotive,
+    splitVariants bool) map[string][]domain.Direction {
+
+    // Собираем варианты маршрутов: депо -> конечная станция -> маршрут
+    depotRoutes := make(map[string]map[string]map[string]*domain.RouteVariant)
     
     for _, loc := range locomotives {
+        locKey := loc.Series + "_" + loc.Number
+
         for _, trip := range loc.Trips {
             if len(trip.Route) < 2 {
                 continue // пропускаем поездки без маршрута
@@ -266,65 +283,47 @@ func (m *mostPopularTripService) identifyDirectionsFromTrips(locomotives map[str
                 continue
             }
             
-            routeKey := fmt.Sprintf("%s->%s", start, end)
-            
             if _, exists := depotRoutes[loc.Depo]; !exists {
-                depotRoutes[loc.Depo] = make(map[string]*domain.Direction)
+                depotRoutes[loc.Depo] = make(map[string]map[string]*domain.RouteVariant)
+            }
+            if _, exists := depotRoutes[loc.Depo][end]; !exists {
+                depotRoutes[loc.Depo][end] = make(map[string]*domain.RouteVariant)
             }
             
-            if dir, exists := depotRoutes[loc.Depo][routeKey]; exists {
-                // Увеличиваем частоту использования
-                dir.Frequency++
-                if dir.Locomotives == nil {
-                    dir.Locomotives = make(map[string]bool)
-                }
-                dir.Locomotives[loc.Series+"_"+loc.Number] = true
-            } else {
-                // Создаем новое направление
-                routeNames := make([]string, len(trip.Route))
-                for i, station := range trip.Route {
-                    routeNames[i] = m.getStationName(station)
-                }
-                
-                terminalName := m.getStationName(end)
-                
-                // Формируем название направления
-                directionName := fmt.Sprintf("Маршрут на %s", terminalName)
-                if len(routeNames) > 1 {
-                    // Если есть промежуточные станции, добавляем их
-                    intermediate := routeNames[1 : len(routeNames)-1]
-                    if len(intermediate) > 0 {
-                        directionName = fmt.Sprintf("Через %s на %s", 
-                            strings.Join(intermediate, " → "), 
-                            terminalName)
-                    }
-                }
-                
-                depotRoutes[loc.Depo][routeKey] = &domain.Direction{
-                    ID:          fmt.Sprintf("dir_%s_%s", loc.Depo, end),
-                    Name:        directionName,
-                    Depo:        loc.Depo,
-                    Terminal:    end,
-                    TerminalName: terminalName,
+            routeKey := strings.Join(trip.Route, ",")
+            variant, exists := depotRoutes[loc.Depo][end][routeKey]
+            if !exists {
+                variant = &domain.RouteVariant{
                     Route:       trip.Route,
-                    RouteNames:  routeNames,
-                    Frequency:   1,
-                    Locomotives: map[string]bool{loc.Series + "_" + loc.Number: true},
+                    Locomotives: make(map[string]bool),
                 }
+                depotRoutes[loc.Depo][end][routeKey] = variant
             }
+            
+            // Увеличиваем частоту использования
+            variant.Frequency++
+            variant.Locomotives[locKey] = true
         }
     }
     
     // Преобразуем в нужный формат и сортируем по популярности
     result := make(map[string][]domain.Direction)
-    for depo, routes := range depotRoutes {
-        directions := make([]domain.Direction, 0, len(routes))
-        for _, dir := range routes {
-            directions = append(directions, *dir)
+    for depo, terminals := range depotRoutes {
+        directions := make([]domain.Direction, 0, len(terminals))
+        for end, variants := range terminals {
+            dir := m.buildDirection(depo, end, variants)
+            if splitVariants {
+                directions = append(directions, m.splitDirectionVariants(dir)...)
+            } else {
+                directions = append(directions, dir)
+            }
         }
         
         // Сортируем по частоте использования
         sort.Slice(directions, func(i, j int) bool {
+            if directions[i].Frequency == directions[j].Frequency {
+                return directions[i].ID < directions[j].ID
+            }
             return directions[i].Frequency > directions[j].Frequency
         })
         
@@ -334,12 +333,137 @@ func (m *mostPopularTripService) identifyDirectionsFromTrips(locomotives map[str
     return result
 }
 
+// buildDirection - собирает направление из вариантов маршрута до конечной станции.
+// Типичным маршрутом направления считается самый частый вариант.
+func (m *mostPopularTripService) buildDirection(
+    depo, end string,
+    variants map[string]*domain.RouteVariant) domain.Direction {
+
+    keys := make([]string, 0, len(variants))
+    for key := range variants {
+        keys = append(keys, key)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        if variants[keys[i]].Frequency == variants[keys[j]].Frequency {
+            return keys[i] < keys[j]
+        }
+        return variants[keys[i]].Frequency > variants[keys[j]].Frequency
+    })
+
+    dir := domain.Direction{
+        ID:           fmt.Sprintf("dir_%s_%s", depo, end),
+        Depo:         depo,
+        Terminal:     end,
+        TerminalName: m.getStationName(end),
+        Locomotives:  make(map[string]bool),
+        Variants:     make([]domain.RouteVariant, 0, len(keys)),
+    }
+
+    for i, key := range keys {
+        variant := *variants[key]
+        variant.ID = fmt.Sprintf("%s_v%d", dir.ID, i+1)
+        variant.RouteNames = make([]string, len(variant.Route))
+        for j, station := range variant.Route {
+            variant.RouteNames[j] = m.getStationName(station)
+        }
+
+        dir.Frequency += variant.Frequency
+        for locKey := range variant.Locomotives {
+            dir.Locomotives[locKey] = true
+        }
+        dir.Variants = append(dir.Variants, variant)
+    }
+
+    dir.Route = dir.Variants[0].Route
+    dir.RouteNames = dir.Variants[0].RouteNames
+    dir.Name = m.directionName(dir.RouteNames, dir.TerminalName)
+
+    return dir
+}
+
+// splitDirectionVariants - превращает каждый вариант маршрута в отдельное направление
+func (m *mostPopularTripService) splitDirectionVariants(dir domain.Direction) []domain.Direction {
+    directions := make([]domain.Direction, 0, len(dir.Variants))
+    for _, variant := range dir.Variants {
+        directions = append(directions, domain.Direction{
+            ID:           variant.ID,
+            Name:         m.directionName(variant.RouteNames, dir.TerminalName),
+            Depo:         dir.Depo,
+            Terminal:     dir.Terminal,
+            TerminalName: dir.TerminalName,
+            Route:        variant.Route,
+            RouteNames:   variant.RouteNames,
+            Frequency:    variant.Frequency,
+            Locomotives:  variant.Locomotives,
+            ParentID:     dir.ID,
+            Variants:     []domain.RouteVariant{variant},
+        })
+    }
+    return directions
+}
+
+// withParentDirections - при разделенных вариантах добавляет перед вариантами
+// общее направление до конечной станции. Общие направления нужны только для
+// ответа и вывода: поездки сопоставляются с вариантами.
+func (m *mostPopularTripService) withParentDirections(directions []domain.Direction) []domain.Direction {
+    children := make(map[string][]domain.Direction)
+    for _, dir := range directions {
+        if dir.ParentID != "" {
+            children[dir.ParentID] = append(children[dir.ParentID], dir)
+        }
+    }
+    if len(children) == 0 {
+        return directions
+    }
+
+    parents := make([]domain.Direction, 0, len(children))
+    for _, variantDirs := range children {
+        variants := make(map[string]*domain.RouteVariant, len(variantDirs))
+        for _, dir := range variantDirs {
+            variant := dir.Variants[0]
+            variants[strings.Join(variant.Route, ",")] = &variant
+        }
+        parents = append(parents, m.buildDirection(variantDirs[0].Depo, variantDirs[0].Terminal, variants))
+    }
+    sort.Slice(parents, func(i, j int) bool {
+        if parents[i].Frequency == parents[j].Frequency {
+            return parents[i].ID < parents[j].ID
+        }
+        return parents[i].Frequency > parents[j].Frequency
+    })
+
+    result := make([]domain.Direction, 0, len(directions)+len(parents))
+    for _, parent := range parents {
+        result = append(result, parent)
+        result = append(result, children[parent.ID]...)
+    }
+    return result
+}
+
+// directionName - формирует название направления по маршруту
+func (m *mostPopularTripService) directionName(routeNames []string, terminalName string) string {
+    name := fmt.Sprintf("Маршрут на %s", terminalName)
+    if len(routeNames) > 1 {
+        // Если есть промежуточные станции, добавляем их
+        intermediate := routeNames[1 : len(routeNames)-1]
+        if len(intermediate) > 0 {
+            name = fmt.Sprintf("Через %s на %s", 
+                strings.Join(intermediate, " → "), 
+                terminalName)
+        }
+    }
+    return name
+}
+
 // analyzeFavoriteDirections - анализ популярных направлений для каждого локомотива
 func (m *mostPopularTripService) analyzeFavoriteDirections(
     locomotives map[string]domain.Locomotive,
-    depotDirections map[string][]domain.Direction) map[string]domain.LocomotiveDirectionStats {
+    depotDirections map[string][]domain.Direction,
+    minSimilarity float64) map[string]domain.LocomotiveDirectionStats {
 
     stats := make(map[string]domain.LocomotiveDirectionStats)
+    // Индексы направлений для сопоставления поездок, по депо
+    matchers := make(map[string]*directionMatcher)
 
     for key, loc := range locomotives {
         if len(loc.Trips) == 0 {
@@ -358,6 +482,12 @@ func (m *mostPopularTripService) analyzeFavoriteDirections(
             dirMap[dir.ID] = dir
         }
 
+        matcher, exists := matchers[loc.Depo]
+        if !exists {
+            matcher = m.newDirectionMatcher(directions)
+            matchers[loc.Depo] = matcher
+        }
+
         locStats := domain.LocomotiveDirectionStats{
             LocomotiveKey:   key,
             Model:           loc.Series,
@@ -367,8 +497,12 @@ func (m *mostPopularTripService) analyzeFavoriteDirections(
             TotalTrips:      len(loc.Trips),
             DirectionVisits: make(map[string]int),
             Directions:      make([]domain.DirectionInfo, 0),
+            Trips:           make([]domain.Trip, 0, len(loc.Trips)),
         }
 
+        // Сумма уверенности сопоставления по направлениям
+        confidenceSum := make(map[string]float64)
+
         // Анализируем каждую поездку
         for _, trip := range loc.Trips {
             if len(trip.Route) < 2 {
@@ -376,17 +510,25 @@ func (m *mostPopularTripService) analyzeFavoriteDirections(
             }
 
             // Определяем, какому направлению соответствует эта поездка
-            matchedDir := m.matchTripToDirection(trip, directions)
+            matchedDir, matchedVariant, confidence := m.matchTripToDirection(trip, matcher, minSimilarity)
             if matchedDir != "" {
                 locStats.DirectionVisits[matchedDir]++
+                confidenceSum[matchedDir] += confidence
+            } else {
+                locStats.UnmatchedTrips++
             }
+
+            trip.DirectionID = matchedDir
+            trip.VariantID = matchedVariant
+            trip.Confidence = confidence
+            locStats.Trips = append(locStats.Trips, trip)
         }
 
-        // Находим самое популярное направление
+        // Находим самое популярное направление (при равенстве - с меньшим ID)
         maxVisits := 0
         mostPopular := ""
         for dirID, visits := range locStats.DirectionVisits {
-            if visits > maxVisits {
+            if visits > maxVisits || (visits == maxVisits && dirID < mostPopular) {
                 maxVisits = visits
                 mostPopular = dirID
             }
@@ -411,6 +553,7 @@ func (m *mostPopularTripService) analyzeFavoriteDirections(
                     TerminalName: dir.TerminalName,
                     Visits:      visits,
                     Percentage:  float64(visits) / float64(locStats.TotalTrips) * 100,
+                    AvgConfidence: confidenceSum[dirID] / float64(visits),
                 }
                 locStats.Directions = append(locStats.Directions, info)
             }
@@ -418,6 +561,9 @@ func (m *mostPopularTripService) analyzeFavoriteDirections(
 
         // Сортируем направления по популярности
         sort.Slice(locStats.Directions, func(i, j int) bool {
+            if locStats.Directions[i].Visits == locStats.Directions[j].Visits {
+                return locStats.Directions[i].ID < locStats.Directions[j].ID
+            }
             return locStats.Directions[i].Visits > locStats.Directions[j].Visits
         })
 
@@ -427,69 +573,169 @@ func (m *mostPopularTripService) analyzeFavoriteDirections(
     return stats
 }
 
-// matchTripToDirection - определяет, какому направлению соответствует поездка
-func (m *mostPopularTripService) matchTripToDirection(trip domain.Trip, directions []domain.Direction) string {
-    if len(trip.Route) < 2 {
-        return ""
-    }
+// directionMatcher - индекс вариантов маршрута направлений депо для сопоставления поездок
+type directionMatcher struct {
+    variants   []matchVariant
+    exact      map[string]int   // маршрут без стоянок -> первый вариант с таким маршрутом
+    byTerminal map[string][]int // конечная станция направления -> варианты
+    byStation  map[string][]int // станция -> варианты, маршрут которых через нее проходит
+}
 
-    // Конечная станция поездки
-    tripEnd := trip.Route[len(trip.Route)-1]
-    
-    // Ищем направление с такой же конечной станцией
-    for _, dir := range directions {
-        if dir.Terminal == tripEnd {
-            return dir.ID
-        }
+// matchVariant - вариант маршрута направления, подготовленный для сравнения
+type matchVariant struct {
+    dirID     string
+    variantID string
+    terminal  string
+    route     []string // маршрут без повторяющихся стоянок
+}
+
+// newDirectionMatcher - строит индекс направлений депо (варианты в порядке направлений)
+func (m *mostPopularTripService) newDirectionMatcher(directions []domain.Direction) *directionMatcher {
+    matcher := &directionMatcher{
+        exact:      make(map[string]int),
+        byTerminal: make(map[string][]int),
+        byStation:  make(map[string][]int),
     }
-    
-    // Если не нашли точного совпадения, ищем частичное совпадение маршрута
-    bestMatch := ""
-    bestScore := 0
-    
+
     for _, dir := range directions {
-        score := m.calculateRouteSimilarity(trip.Route, dir.Route)
-        if score > bestScore && score > 50 { // минимум 50% совпадения
-            bestScore = score
-            bestMatch = dir.ID
+        for _, variant := range dir.Variants {
+            idx := len(matcher.variants)
+            route := m.cleanStops(variant.Route)
+            matcher.variants = append(matcher.variants, matchVariant{
+                dirID:     dir.ID,
+                variantID: variant.ID,
+                terminal:  dir.Terminal,
+                route:     route,
+            })
+
+            key := strings.Join(route, ",")
+            if _, exists := matcher.exact[key]; !exists {
+                matcher.exact[key] = idx
+            }
+            matcher.byTerminal[dir.Terminal] = append(matcher.byTerminal[dir.Terminal], idx)
+
+            seen := make(map[string]bool, len(route))
+            for _, station := range route {
+                if !seen[station] {
+                    seen[station] = true
+                    matcher.byStation[station] = append(matcher.byStation[station], idx)
+                }
+            }
         }
     }
-    
-    return bestMatch
+
+    return matcher
 }
 
-// calculateRouteSimilarity - вычисляет процент совпадения маршрутов
-func (m *mostPopularTripService) calculateRouteSimilarity(route1, route2 []string) int {
-    if len(route1) == 0 || len(route2) == 0 {
-        return 0
+// matchTripToDirection - определяет, какому направлению соответствует поездка.
+// Возвращает ID направления, ID ближайшего варианта маршрута
+// и уверенность сопоставления (0..1).
+//
+// Сначала ищется вариант с точно таким же маршрутом, затем - самый похожий
+// вариант направлений с той же конечной станцией. Только если таких нет,
+// схожесть (LCS) считается по направлениям, у которых общая с поездкой
+// конечная станция: конечная направления на маршруте поездки или конечная
+// поездки на маршруте варианта.
+func (m *mostPopularTripService) matchTripToDirection(
+    trip domain.Trip,
+    matcher *directionMatcher,
+    minSimilarity float64) (string, string, float64) {
+
+    route := m.cleanStops(trip.Route)
+    if len(route) < 2 {
+        return "", "", 0
     }
-    
-    // Создаем множества станций
-    set1 := make(map[string]bool)
-    for _, s := range route1 {
-        set1[s] = true
+
+    if idx, ok := matcher.exact[strings.Join(route, ",")]; ok {
+        variant := matcher.variants[idx]
+        return variant.dirID, variant.variantID, 1
     }
-    
-    set2 := make(map[string]bool)
-    for _, s := range route2 {
-        set2[s] = true
+
+    threshold := matchThreshold(minSimilarity)
+
+    // Конечная станция поездки
+    tripEnd := route[len(route)-1]
+
+    if idx, score := matcher.bestVariant(route, matcher.byTerminal[tripEnd], threshold); idx >= 0 {
+        variant := matcher.variants[idx]
+        return variant.dirID, variant.variantID, score
     }
-    
-    // Считаем пересечение
-    intersection := 0
-    for s := range set1 {
-        if set2[s] {
-            intersection++
+
+    seen := make(map[int]bool)
+    candidates := make([]int, 0)
+    for _, idx := range matcher.byStation[tripEnd] {
+        if !seen[idx] {
+            seen[idx] = true
+            candidates = append(candidates, idx)
         }
     }
-    
-    // Считаем объединение
-    union := len(set1) + len(set2) - intersection
-    if union == 0 {
+    for _, station := range route {
+        for _, idx := range matcher.byTerminal[station] {
+            if !seen[idx] {
+                seen[idx] = true
+                candidates = append(candidates, idx)
+            }
+        }
+    }
+    // Порядок направлений: при равной схожести выигрывает более частое
+    sort.Ints(candidates)
+
+    if idx, score := matcher.bestVariant(route, candidates, threshold); idx >= 0 {
+        variant := matcher.variants[idx]
+        return variant.dirID, variant.variantID, score
+    }
+
+    return "", "", 0
+}
+
+// bestVariant - самый похожий на маршрут вариант из candidates (не ниже порога), -1 если нет
+func (d *directionMatcher) bestVariant(route []string, candidates []int, threshold float64) (int, float64) {
+    best := -1
+    bestScore := 0.0
+    for _, idx := range candidates {
+        score := calculateRouteSimilarity(route, d.variants[idx].route)
+        if score >= threshold && score > bestScore {
+            best = idx
+            bestScore = score
+        }
+    }
+    return best, bestScore
+}
+
+// matchThreshold - порог схожести маршрутов (значение по умолчанию, если не задан)
+func matchThreshold(minSimilarity float64) float64 {
+    if minSimilarity <= 0 {
+        return defaultMatchThreshold
+    }
+    return minSimilarity
+}
+
+// calculateRouteSimilarity - вычисляет схожесть маршрутов с учетом порядка станций (0..1).
+// Основана на длине наибольшей общей подпоследовательности (LCS):
+// 2 * LCS / (len1 + len2). Маршруты передаются без повторяющихся стоянок.
+func calculateRouteSimilarity(a, b []string) float64 {
+    if len(a) == 0 || len(b) == 0 {
         return 0
     }
-    
-    return intersection * 100 / union
+
+    // Динамика по двум строкам таблицы LCS
+    prev := make([]int, len(b)+1)
+    curr := make([]int, len(b)+1)
+    for i := 1; i <= len(a); i++ {
+        for j := 1; j <= len(b); j++ {
+            if a[i-1] == b[j-1] {
+                curr[j] = prev[j-1] + 1
+            } else if prev[j] >= curr[j-1] {
+                curr[j] = prev[j]
+            } else {
+                curr[j] = curr[j-1]
+            }
+        }
+        prev, curr = curr, prev
+    }
+
+    lcs := prev[len(b)]
+    return float64(2*lcs) / float64(len(a)+len(b))
 }
 
 // printDirectionStats - выводит статистику
@@ -522,16 +768,26 @@ func (m *mostPopularTripService) printDirectionStats(
         
         // Показываем популярные направления из этого депо
         if dirs, exists := depotDirections[depo]; exists && len(dirs) > 0 {
+            dirs = m.withParentDirections(dirs)
             fmt.Printf("\n🚂 ПОПУЛЯРНЫЕ НАПРАВЛЕНИЯ ИЗ ДЕПО:\n")
             for i, dir := range dirs {
                 if i >= 5 {
                     fmt.Printf("  • ... и еще %d направлений\n", len(dirs)-5)
                     break
                 }
-                fmt.Printf("  %d. %s\n", i+1, dir.Name)
+                if dir.ParentID != "" {
+                    fmt.Printf("  %d. ↳ вариант %s\n", i+1, dir.Name)
+                } else {
+                    fmt.Printf("  %d. %s\n", i+1, dir.Name)
+                }
                 fmt.Printf("     Маршрут: %s\n", strings.Join(dir.RouteNames, " → "))
                 fmt.Printf("     Используют: %d локомотивов, %d поездок\n", 
                     len(dir.Locomotives), dir.Frequency)
+                if len(dir.Variants) > 1 {
+                    fmt.Printf("     Вариантов маршрута: %d, основной: %.1f%% поездок\n",
+                        len(dir.Variants),
+                        float64(dir.Variants[0].Frequency)/float64(dir.Frequency)*100)
+                }
             }
         }
         
@@ -565,7 +821,8 @@ func (m *mostPopularTripService) printDirectionStats(
                     }
                     fmt.Printf("       %d. %s\n", j+1, dir.Name)
                     fmt.Printf("          Конечная: %s\n", dir.TerminalName)
-                    fmt.Printf("          Поездок: %d (%.1f%%)\n", dir.Visits, dir.Percentage)
+                    fmt.Printf("          Поездок: %d (%.1f%%), уверенность: %.2f\n",
+                        dir.Visits, dir.Percentage, dir.AvgConfidence)
                 }
             }
             
@@ -576,6 +833,14 @@ func (m *mostPopularTripService) printDirectionStats(
                     stat.MaxVisits, stat.TotalTrips,
                     float64(stat.MaxVisits)/float64(stat.TotalTrips)*100)
             }
+
+            fmt.Printf("     Значимость: %s (χ² = %.2f, df = %d, p = %.4f, энтропия = %.2f бит)\n",
+                favoriteLabelName(stat.FavoriteLabel), stat.ChiSquare, stat.DegreesOfFreedom,
+                stat.PValue, stat.Entropy)
+
+            if stat.UnmatchedTrips > 0 {
+                fmt.Printf("     Поездок без направления: %d\n", stat.UnmatchedTrips)
+            }
         }
 
         if len(locStats) > displayCount {
@@ -596,6 +861,7 @@ func (m *mostPopularTripService) printOverallStats(stats map[string]domain.Locom
     locWithFavorite := 0
     locWithSingleDirection := 0
     totalTrips := 0
+    labels := make(map[string]int)
 
     for _, stat := range stats {
         totalTrips += stat.TotalTrips
@@ -605,6 +871,7 @@ func (m *mostPopularTripService) printOverallStats(stats map[string]domain.Locom
         if len(stat.Directions) == 1 {
             locWithSingleDirection++
         }
+        labels[stat.FavoriteLabel]++
     }
 
     fmt.Printf("\n📊 ОБЩИЕ ПОКАЗАТЕЛИ:\n")
@@ -619,10 +886,28 @@ func (m *mostPopularTripService) printOverallStats(stats map[string]domain.Locom
     fmt.Printf("  • Локомотивов, работающих на одном направлении: %d (%.1f%%)\n",
         locWithSingleDirection, 
         float64(locWithSingleDirection)/float64(totalLocomotives)*100)
+
+    fmt.Printf("\n🧪 ЗНАЧИМОСТЬ ЛЮБИМОГО НАПРАВЛЕНИЯ:\n")
+    for _, label := range []string{favoriteStrong, favoriteWeak, favoriteNone} {
+        fmt.Printf("  • %s: %d (%.1f%%)\n", favoriteLabelName(label),
+            labels[label], float64(labels[label])/float64(totalLocomotives)*100)
+    }
+}
+
+// favoriteLabelName - название метки любимого направления для консоли
+func favoriteLabelName(label string) string {
+    switch label {
+    case favoriteStrong:
+        return "сильное любимое направление"
+    case favoriteWeak:
+        return "слабое любимое направление"
+    default:
+        return "нет любимого направления"
+    }
 }
 
 // RunMostPopularTrip - основной метод для консольного режима
-func (m *mostPopularTripService) RunMostPopularTrip() {
+func (m *mostPopularTripService) RunMostPopularTrip(opts domain.DirectionOptions) {
     fmt.Println("\n" + strings.Repeat("=", 80))
     fmt.Println("ЗАГРУЗКА ДАННЫХ")
     fmt.Println(strings.Repeat("=", 80))
@@ -646,62 +931,113 @@ func (m *mostPopularTripService) RunMostPopularTrip() {
     fmt.Println("ОПРЕДЕЛЕНИЕ НАПРАВЛЕНИЙ")
     fmt.Println(strings.Repeat("=", 80))
     
-    depotDirections := m.identifyDirectionsFromTrips(locomotives)
+    depotDirections := m.identifyDirectionsFromTrips(locomotives, opts.SplitVariants)
     
     totalDirections := 0
     for _, dirs := range depotDirections {
         totalDirections += len(dirs)
     }
     fmt.Printf("✓ Определено направлений: %d\n", totalDirections)
+    if opts.SplitVariants {
+        fmt.Println("  (варианты маршрута считаются отдельными направлениями)")
+    }
 
     fmt.Println("\n" + strings.Repeat("=", 80))
     fmt.Println("АНАЛИЗ ПОПУЛЯРНЫХ НАПРАВЛЕНИЙ")
     fmt.Println(strings.Repeat("=", 80))
     
-    locomotiveStats := m.analyzeFavoriteDirections(locomotives, depotDirections)
+    fmt.Printf("Порог схожести маршрутов: %.2f\n", matchThreshold(opts.MinSimilarity))
+    locomotiveStats := m.analyzeFavoriteDirections(locomotives, depotDirections, opts.MinSimilarity)
+    m.evaluateFavoriteSignificance(locomotiveStats, depotDirections, opts.Baseline)
     
     m.printDirectionStats(locomotiveStats, depotDirections)
 }
 
-// GetPopularDirections - для API режима
-func (m *mostPopularTripService) GetPopularDirections() (*responses.Task2Response, error) {
-    locomotives := m.loadData()
+// computeDirectionStats - загружает данные, определяет направления и анализирует локомотивы
+func (m *mostPopularTripService) computeDirectionStats(
+    opts domain.DirectionOptions) (map[string]domain.LocomotiveDirectionStats, map[string][]domain.Direction) {
 
-    for key, loc := range locomotives {
+    return m.computeDirectionStatsFrom(m.loadData(), opts)
+}
+
+// computeDirectionStatsFrom - определяет направления и анализирует уже загруженные локомотивы.
+// Переданная карта не изменяется; ключи статистики - Серия_Номер.
+func (m *mostPopularTripService) computeDirectionStatsFrom(
+    loaded map[string]domain.Locomotive,
+    opts domain.DirectionOptions) (map[string]domain.LocomotiveDirectionStats, map[string][]domain.Direction) {
+
+    locomotives := make(map[string]domain.Locomotive, len(loaded))
+    for _, loc := range loaded {
         loc.Trips = m.splitIntoTrips(loc.Records)
-        locomotives[key] = loc
+        locomotives[loc.Series+"_"+loc.Number] = loc
     }
 
-    depotDirections := m.identifyDirectionsFromTrips(locomotives)
-    locomotiveStats := m.analyzeFavoriteDirections(locomotives, depotDirections)
+    depotDirections := m.identifyDirectionsFromTrips(locomotives, opts.SplitVariants)
+    locomotiveStats := m.analyzeFavoriteDirections(locomotives, depotDirections, opts.MinSimilarity)
+    m.evaluateFavoriteSignificance(locomotiveStats, depotDirections, opts.Baseline)
 
-    return m.buildTask2Response(locomotiveStats, depotDirections), nil
+    return locomotiveStats, depotDirections
 }
 
-// GetLocomotivePopularDirection - для API режима
-func (m *mostPopularTripService) GetLocomotivePopularDirection(series, number string) (*responses.LocomotiveStats, error) {
-    locomotives := m.loadData()
+// GetPopularDirections - для API режима
+func (m *mostPopularTripService) GetPopularDirections(
+    opts domain.DirectionOptions,
+    filter domain.PopularDirectionFilter) (*responses.Task2Response, error) {
 
-    for key, loc := range locomotives {
-        loc.Trips = m.splitIntoTrips(loc.Records)
-        locomotives[key] = loc
+    locomotiveStats, depotDirections := m.computeDirectionStats(opts)
+
+    // Все депо - для фильтров на клиенте
+    depoSet := make(map[string]bool)
+    for _, stat := range locomotiveStats {
+        depoSet[stat.Depo] = true
+    }
+    availableDepots := make([]string, 0, len(depoSet))
+    for depo := range depoSet {
+        availableDepots = append(availableDepots, depo)
     }
+    sort.Strings(availableDepots)
+
+    response := m.buildTask2Response(filterLocomotiveStats(locomotiveStats, filter), locomotiveStats, depotDirections)
+    response.MatchThreshold = matchThreshold(opts.MinSimilarity)
+    response.SplitVariants = opts.SplitVariants
+    response.AvailableDepots = availableDepots
+
+    paginateTask2Response(response, filter)
+
+    return response, nil
+}
+
+// GetLocomotivePopularDirection - для API режима
+func (m *mostPopularTripService) GetLocomotivePopularDirection(series, number string, opts domain.DirectionOptions) (*responses.LocomotiveStats, error) {
+    return m.GetLocomotivePopularDirectionFrom(m.loadData(), series, number, opts)
+}
+
+// GetLocomotivePopularDirectionFrom - то же по уже загруженным данным (профиль локомотива).
+// Для локомотива без поездок из депо возвращает nil.
+func (m *mostPopularTripService) GetLocomotivePopularDirectionFrom(
+    locomotives map[string]domain.Locomotive,
+    series, number string,
+    opts domain.DirectionOptions) (*responses.LocomotiveStats, error) {
 
-    depotDirections := m.identifyDirectionsFromTrips(locomotives)
-    locomotiveStats := m.analyzeFavoriteDirections(locomotives, depotDirections)
+    locomotiveStats, depotDirections := m.computeDirectionStatsFrom(locomotives, opts)
 
     key := series + "_" + number
     stats, exists := locomotiveStats[key]
     if !exists {
-        return nil, fmt.Errorf("локомотив %s-%s не найден", series, number)
+        return nil, nil
     }
 
-    return m.buildLocomotiveStatsResponse(stats, depotDirections[stats.Depo]), nil
+    response := m.buildLocomotiveStatsResponse(stats, depotDirections[stats.Depo])
+    response.Trips = m.buildTripMatches(stats.Trips, depotDirections[stats.Depo])
+
+    return response, nil
 }
 
-// buildTask2Response - формирует ответ для API
+// buildTask2Response - формирует ответ для API по локомотивам stats (после фильтров);
+// показатели депо (locomotive_count, matched_trips и avg_confidence направлений) - по всем локомотивам all
 func (m *mostPopularTripService) buildTask2Response(
     stats map[string]domain.LocomotiveDirectionStats,
+    all map[string]domain.LocomotiveDirectionStats,
     depotDirections map[string][]domain.Direction) *responses.Task2Response {
 
     response := &responses.Task2Response{
@@ -714,6 +1050,10 @@ func (m *mostPopularTripService) buildTask2Response(
     for _, stat := range stats {
         byDepot[stat.Depo] = append(byDepot[stat.Depo], stat)
     }
+    allByDepot := make(map[string][]domain.LocomotiveDirectionStats)
+    for _, stat := range all {
+        allByDepot[stat.Depo] = append(allByDepot[stat.Depo], stat)
+    }
 
     depots := make([]string, 0, len(byDepot))
     for d := range byDepot {
@@ -727,22 +1067,48 @@ func (m *mostPopularTripService) buildTask2Response(
         depotResponse := responses.DepotResponse{
             DepoCode:        depo,
             DepoName:        m.getStationName(depo),
-            LocomotiveCount: len(locStats),
+            LocomotiveCount: len(allByDepot[depo]),
             Directions:      make([]responses.DirectionInfo, 0),
             Locomotives:     make([]responses.LocomotiveStats, 0),
         }
 
+        // Сопоставленные поездки и уверенность по направлениям депо
+        matchedTrips := make(map[string]int)
+        confidenceSum := make(map[string]float64)
+        for _, stat := range allByDepot[depo] {
+            for _, dir := range stat.Directions {
+                matchedTrips[dir.ID] += dir.Visits
+                confidenceSum[dir.ID] += dir.AvgConfidence * float64(dir.Visits)
+            }
+        }
+
+        // Общее направление вариантов - сумма по его вариантам
+        for _, d := range depotDirections[depo] {
+            if d.ParentID != "" {
+                matchedTrips[d.ParentID] += matchedTrips[d.ID]
+                confidenceSum[d.ParentID] += confidenceSum[d.ID]
+            }
+        }
+
         // Добавляем направления
         if dirs, exists := depotDirections[depo]; exists {
-            for _, d := range dirs {
-                depotResponse.Directions = append(depotResponse.Directions, responses.DirectionInfo{
+            for _, d := range m.withParentDirections(dirs) {
+                info := responses.DirectionInfo{
                     ID:          d.ID,
                     Name:        d.Name,
                     Terminal:    d.Terminal,
                     TerminalName: d.TerminalName,
                     Frequency:   d.Frequency,
                     LocomotiveCount: len(d.Locomotives),
-                })
+                    MatchedTrips: matchedTrips[d.ID],
+                    ParentID:    d.ParentID,
+                    VariantCount: len(d.Variants),
+                    Variants:    m.buildRouteVariants(d),
+                }
+                if matchedTrips[d.ID] > 0 {
+                    info.AvgConfidence = confidenceSum[d.ID] / float64(matchedTrips[d.ID])
+                }
+                depotResponse.Directions = append(depotResponse.Directions, info)
             }
         }
 
@@ -760,6 +1126,8 @@ func (m *mostPopularTripService) buildTask2Response(
     locWithFavorite := 0
     locWithSingleDirection := 0
     totalTrips := 0
+    labels := make(map[string]int)
+    entropySum := 0.0
 
     for _, stat := range stats {
         totalTrips += stat.TotalTrips
@@ -769,16 +1137,28 @@ func (m *mostPopularTripService) buildTask2Response(
         if len(stat.Directions) == 1 {
             locWithSingleDirection++
         }
+        labels[stat.FavoriteLabel]++
+        entropySum += stat.Entropy
     }
 
-    response.OverallStats = responses.OverallStats{
-        TotalLocomotives:      totalLocomotives,
-        TotalTrips:            totalTrips,
-        AvgTripsPerLocomotive: float64(totalTrips) / float64(totalLocomotives),
-        LocomotivesWithFavorite: locWithFavorite,
-        LocomotivesWithFavoritePercent: float64(locWithFavorite) / float64(totalLocomotives) * 100,
-        LocomotivesSingleDirection: locWithSingleDirection,
-        LocomotivesSingleDirectionPercent: float64(locWithSingleDirection) / float64(totalLocomotives) * 100,
+    // Без локомотивов (например, после фильтрации) оставляем нулевую статистику
+    if totalLocomotives > 0 {
+        response.OverallStats = responses.OverallStats{
+            TotalLocomotives:      totalLocomotives,
+            TotalTrips:            totalTrips,
+            AvgTripsPerLocomotive: float64(totalTrips) / float64(totalLocomotives),
+            LocomotivesWithFavorite: locWithFavorite,
+            LocomotivesWithFavoritePercent: float64(locWithFavorite) / float64(totalLocomotives) * 100,
+            LocomotivesSingleDirection: locWithSingleDirection,
+            LocomotivesSingleDirectionPercent: float64(locWithSingleDirection) / float64(totalLocomotives) * 100,
+            StrongFavorite: labels[favoriteStrong],
+            StrongFavoritePercent: float64(labels[favoriteStrong]) / float64(totalLocomotives) * 100,
+            WeakFavorite: labels[favoriteWeak],
+            WeakFavoritePercent: float64(labels[favoriteWeak]) / float64(totalLocomotives) * 100,
+            NoFavorite: labels[favoriteNone],
+            NoFavoritePercent: float64(labels[favoriteNone]) / float64(totalLocomotives) * 100,
+            AvgEntropy: entropySum / float64(totalLocomotives),
+        }
     }
 
     return response
@@ -795,7 +1175,17 @@ func (m *mostPopularTripService) buildLocomotiveStatsResponse(
         Depo:         stat.Depo,
         DepoName:     stat.DepoName,
         TotalTrips:   stat.TotalTrips,
+        UnmatchedTrips: stat.UnmatchedTrips,
         Directions:   make([]responses.LocomotiveDirection, 0),
+        FavoriteLabel: stat.FavoriteLabel,
+        Significance: responses.FavoriteSignificance{
+            Baseline:          stat.Baseline,
+            ChiSquare:         stat.ChiSquare,
+            DegreesOfFreedom:  stat.DegreesOfFreedom,
+            PValue:            stat.PValue,
+            Entropy:           stat.Entropy,
+            NormalizedEntropy: stat.NormalizedEntropy,
+        },
     }
 
     // Добавляем информацию о посещенных направлениях
@@ -807,6 +1197,7 @@ func (m *mostPopularTripService) buildLocomotiveStatsResponse(
             TerminalName: dir.TerminalName,
             Visits:      dir.Visits,
             Percentage:  dir.Percentage,
+            AvgConfidence: dir.AvgConfidence,
         })
     }
 
@@ -818,7 +1209,56 @@ func (m *mostPopularTripService) buildLocomotiveStatsResponse(
             Visits:        stat.MaxVisits,
             Percentage:    float64(stat.MaxVisits) / float64(stat.TotalTrips) * 100,
         }
+        for _, dir := range stat.Directions {
+            if dir.ID == stat.MostPopularDirection {
+                locStatsResp.MostPopular.AvgConfidence = dir.AvgConfidence
+                break
+            }
+        }
     }
 
     return locStatsResp
+}
+
+// buildRouteVariants - формирует список вариантов маршрута направления
+func (m *mostPopularTripService) buildRouteVariants(dir domain.Direction) []responses.RouteVariantInfo {
+    result := make([]responses.RouteVariantInfo, 0, len(dir.Variants))
+    for _, variant := range dir.Variants {
+        result = append(result, responses.RouteVariantInfo{
+            ID:              variant.ID,
+            Route:           variant.RouteNames,
+            Stations:        len(variant.Route),
+            Frequency:       variant.Frequency,
+            Share:           float64(variant.Frequency) / float64(dir.Frequency) * 100,
+            LocomotiveCount: len(variant.Locomotives),
+        })
+    }
+    return result
+}
+
+// buildTripMatches - формирует список поездок локомотива с сопоставленными направлениями
+func (m *mostPopularTripService) buildTripMatches(
+    trips []domain.Trip,
+    directions []domain.Direction) []responses.TripMatch {
+
+    dirNames := make(map[string]string)
+    for _, dir := range directions {
+        dirNames[dir.ID] = dir.Name
+    }
+
+    result := make([]responses.TripMatch, 0, len(trips))
+    for _, trip := range trips {
+        result = append(result, responses.TripMatch{
+            StartTime:     trip.StartTime.Format(time.RFC3339),
+            EndTime:       trip.EndTime.Format(time.RFC3339),
+            Stations:      len(trip.Route),
+            Terminal:      m.getStationName(trip.Route[len(trip.Route)-1]),
+            DirectionID:   trip.DirectionID,
+            DirectionName: dirNames[trip.DirectionID],
+            VariantID:     trip.VariantID,
+            Confidence:    trip.Confidence,
+        })
+    }
+
+    return result
 }
\ No newline at end of file
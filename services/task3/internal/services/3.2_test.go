@@ -0,0 +1,139 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+func TestCalculateRouteSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want float64
+	}{
+		{name: "одинаковые маршруты", a: []string{"D", "A", "B"}, b: []string{"D", "A", "B"}, want: 1},
+		{name: "подпоследовательность", a: []string{"A", "C"}, b: []string{"A", "B", "C"}, want: 0.8},
+		{name: "важен порядок станций", a: []string{"A", "B", "C"}, b: []string{"C", "B", "A"}, want: 1.0 / 3},
+		{name: "нет общих станций", a: []string{"A", "B"}, b: []string{"C", "D"}, want: 0},
+		{name: "общий путь с расхождением", a: []string{"D", "A", "X", "B"}, b: []string{"D", "A", "Y", "B"}, want: 0.75},
+		{name: "пустой маршрут", a: nil, b: []string{"A"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateRouteSimilarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("calculateRouteSimilarity(%v, %v) = %v, ожидалось %v", tt.a, tt.b, got, tt.want)
+			}
+			if back := calculateRouteSimilarity(tt.b, tt.a); back != got {
+				t.Errorf("схожесть несимметрична: %v и %v", got, back)
+			}
+		})
+	}
+}
+
+// testDirection - направление до конечной станции с вариантами маршрута по убыванию частоты
+func testDirection(id, terminal string, routes ...[]string) domain.Direction {
+	dir := domain.Direction{ID: id, Terminal: terminal}
+	for i, route := range routes {
+		dir.Variants = append(dir.Variants, domain.RouteVariant{
+			ID:        id + "_v" + string(rune('1'+i)),
+			Route:     route,
+			Frequency: len(routes) - i,
+		})
+	}
+	return dir
+}
+
+func TestMatchTripToDirection(t *testing.T) {
+	m := &mostPopularTripService{}
+	matcher := m.newDirectionMatcher([]domain.Direction{
+		testDirection("A", "A", []string{"D", "X", "A"}, []string{"D", "Y", "Z", "A"}),
+		testDirection("B", "B", []string{"D", "X", "B"}),
+	})
+
+	tests := []struct {
+		name          string
+		route         []string
+		minSimilarity float64
+		direction     string
+		variant       string
+		confidence    float64
+	}{
+		{name: "точный маршрут варианта", route: []string{"D", "Y", "Z", "A"}, direction: "A", variant: "A_v2", confidence: 1},
+		{name: "точный маршрут со стоянками", route: []string{"D", "D", "X", "X", "B"}, direction: "B", variant: "B_v1", confidence: 1},
+		{name: "ближайший вариант с той же конечной", route: []string{"D", "Y", "A"}, direction: "A", variant: "A_v2", confidence: 6.0 / 7},
+		{name: "поездка закончилась на маршруте варианта", route: []string{"D", "Y", "Z"}, direction: "A", variant: "A_v2", confidence: 6.0 / 7},
+		{name: "поездка прошла конечную направления", route: []string{"D", "X", "B", "C"}, direction: "B", variant: "B_v1", confidence: 6.0 / 7},
+		{name: "нет общей конечной - не сопоставляется", route: []string{"D", "X", "Q"}},
+		{name: "ниже порога схожести", route: []string{"D", "Y", "Z"}, minSimilarity: 0.9},
+		{name: "поездка без маршрута", route: []string{"D", "D"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			direction, variant, confidence := m.matchTripToDirection(domain.Trip{Route: tt.route}, matcher, tt.minSimilarity)
+			if direction != tt.direction || variant != tt.variant || math.Abs(confidence-tt.confidence) > 1e-9 {
+				t.Errorf("matchTripToDirection(%v) = %q, %q, %v; ожидалось %q, %q, %v",
+					tt.route, direction, variant, confidence, tt.direction, tt.variant, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestWithParentDirections(t *testing.T) {
+	m := &mostPopularTripService{}
+	trips := func(routes ...[]string) []domain.Trip {
+		result := make([]domain.Trip, 0, len(routes))
+		for _, route := range routes {
+			result = append(result, domain.Trip{Route: route})
+		}
+		return result
+	}
+	locomotives := map[string]domain.Locomotive{
+		"ТЭ_1": {Series: "ТЭ", Number: "1", Depo: "D", Trips: trips(
+			[]string{"D", "X", "A"}, []string{"D", "X", "A"}, []string{"D", "Y", "A"}, []string{"D", "B"},
+		)},
+		"ТЭ_2": {Series: "ТЭ", Number: "2", Depo: "D", Trips: trips(
+			[]string{"D", "Y", "A"}, []string{"D", "Z", "A"},
+		)},
+	}
+
+	whole := m.identifyDirectionsFromTrips(locomotives, false)["D"]
+	split := m.identifyDirectionsFromTrips(locomotives, true)["D"]
+	if len(whole) != 2 || len(split) != 4 {
+		t.Fatalf("направлений %d и %d с вариантами, ожидалось 2 и 4", len(whole), len(split))
+	}
+
+	got := m.withParentDirections(split)
+	wantIDs := []string{"dir_D_A", "dir_D_A_v1", "dir_D_A_v2", "dir_D_A_v3", "dir_D_B", "dir_D_B_v1"}
+	if len(got) != len(wantIDs) {
+		t.Fatalf("направлений %d, ожидалось %d", len(got), len(wantIDs))
+	}
+	for i, dir := range got {
+		if dir.ID != wantIDs[i] {
+			t.Errorf("направление %d = %s, ожидалось %s", i, dir.ID, wantIDs[i])
+		}
+	}
+
+	// Общее направление совпадает с направлением без разделения вариантов
+	for i, parent := range []domain.Direction{got[0], got[4]} {
+		want := whole[i]
+		if parent.ID != want.ID || parent.ParentID != "" || parent.Frequency != want.Frequency ||
+			len(parent.Locomotives) != len(want.Locomotives) || len(parent.Variants) != len(want.Variants) ||
+			parent.Name != want.Name {
+			t.Errorf("общее направление %+v, ожидалось %+v", parent, want)
+		}
+		for j := range parent.Variants {
+			if parent.Variants[j].ID != want.Variants[j].ID || parent.Variants[j].Frequency != want.Variants[j].Frequency {
+				t.Errorf("вариант %d направления %s = %+v, ожидалось %+v", j, parent.ID, parent.Variants[j], want.Variants[j])
+			}
+		}
+	}
+
+	if same := m.withParentDirections(whole); len(same) != len(whole) {
+		t.Errorf("без разделения вариантов направлений %d, ожидалось %d", len(same), len(whole))
+	}
+}
@@ -1,36 +1,53 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"math"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/staticmap"
 	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
 )
 
 type visualizationService struct {
-	dataPath string
-	mapsDir  string // директория для карт (./maps)
+	dataPath  string
+	mapsDir   string           // директория для карт (./maps)
+	tileLayer domain.TileLayer // тайлы подложки карт API режима
+	jobTTL    time.Duration    // время жизни заданий генерации карт (0 - без удаления)
+
+	jobsMu   sync.Mutex
+	jobs     map[string]*mapJobRun // задания, запущенные с момента старта сервера
+	jobSlots chan struct{}         // занятые слоты одновременной генерации карт
 }
 
 type VisualizationService interface {
 	// Существующие методы для консольного режима
-	GenerateMap(depoID string, maxLocomotives int) error
-	GenerateHeatmap(depoID string) error
-	GenerateLocomotiveMap(locomotiveKey string) error
-	GenerateAllMaps(depoID string, maxLocomotives int) error
+	GenerateMap(depoID string, maxLocomotives int, opts domain.MapOptions) error
+	GenerateHeatmap(depoID string, opts domain.MapOptions) error
+	GenerateLocomotiveMap(locomotiveKey string, opts domain.MapOptions) error
+	GenerateAllMaps(depoID string, maxLocomotives int, opts domain.MapOptions) error
 
 	// Методы для API режима
-	GenerateMapsAPI(depoID string, maxLocomotives int) (*responses.GenerateMapsResponse, error)
+	StartMapsJob(depoID string, maxLocomotives int, opts domain.MapOptions) (string, error)
+	WatchMapJob(ctx context.Context, jobID string) (<-chan responses.MapJobEvent, bool)
 	GetAvailableDepots() ([]string, error)
 	GetDepotInfo(depoID string) (*responses.DepotInfo, error)
+	GetStationsGeoJSON(depoID string) (*responses.FeatureCollection, error)
+	GetRoutesGeoJSON(depoID string) (*responses.FeatureCollection, error)
+	GetHeatGeoJSON(depoID string) (*responses.FeatureCollection, error)
+	GetLocomotiveTrackGeoJSON(locomotiveKey string) (*responses.FeatureCollection, error)
+	RenderStaticMap(depoID string, opts domain.StaticMapOptions) (*staticmap.Map, error)
+	GetMapJob(jobID string) (*responses.MapJobResponse, error)
 	GetMapsDir() string
+	SetTileLayer(layer domain.TileLayer)
+	StartJanitor(ttl time.Duration)
 	Cleanup()
 }
 
@@ -61,8 +78,11 @@ func NewVisualizationService(dataPath string) VisualizationService {
 	}
 
 	return &visualizationService{
-		dataPath: dataPath,
-		mapsDir:  mapsDir,
+		dataPath:  dataPath,
+		mapsDir:   mapsDir,
+		tileLayer: osmTileLayer,
+		jobs:      make(map[string]*mapJobRun),
+		jobSlots:  make(chan struct{}, maxMapJobs),
 	}
 }
 
@@ -71,14 +91,9 @@ func (v *visualizationService) GetMapsDir() string {
 	return v.mapsDir
 }
 
-// Cleanup очищает директорию с картами
-func (v *visualizationService) Cleanup() {
-	fmt.Printf("🧹 Очистка директории %s\n", v.mapsDir)
-	if err := os.RemoveAll(v.mapsDir); err != nil {
-		fmt.Printf("⚠️ Ошибка при удалении %s: %v\n", v.mapsDir, err)
-	} else {
-		fmt.Printf("✅ Директория %s удалена\n", v.mapsDir)
-	}
+// SetTileLayer задает источник тайлов для карт API режима (например, локальный MBTiles)
+func (v *visualizationService) SetTileLayer(layer domain.TileLayer) {
+	v.tileLayer = layer
 }
 
 // GetAvailableDepots возвращает список всех депо
@@ -122,32 +137,14 @@ func (v *visualizationService) GetDepotInfo(depoID string) (*responses.DepotInfo
 	}, nil
 }
 
-// cleanupOldMaps удаляет старые файлы карт для конкретного депо
-func (v *visualizationService) cleanupOldMaps(depoID string) {
-	pattern := fmt.Sprintf("depot_%s_*.html", depoID)
-	files, err := filepath.Glob(filepath.Join(v.mapsDir, pattern))
-	if err != nil {
-		return
-	}
-	
-	// Также удаляем старые карты локомотивов этого депо
-	// (мы не знаем точные имена, поэтому удалим все .html файлы)
-	// Но чтобы не удалять чужие, можно удалить только те, которые содержат локомотивы из этого депо
-	// Для простоты удалим все .html файлы в директории
-	allHTML, _ := filepath.Glob(filepath.Join(v.mapsDir, "*.html"))
-	files = append(files, allHTML...)
-	
-	for _, f := range files {
-		if err := os.Remove(f); err != nil {
-			fmt.Printf("⚠️ Не удалось удалить %s: %v\n", f, err)
-		} else {
-			fmt.Printf("   🗑️ Удален старый файл: %s\n", filepath.Base(f))
-		}
-	}
-}
+// generateMaps - генерация карт задания API режима в ./maps/{job_id} (выполняется в фоне, см. StartMapsJob)
+func (v *visualizationService) generateMaps(
+	jobID string,
+	depoID string,
+	maxLocomotives int,
+	opts domain.MapOptions,
+	report mapProgress) (*responses.GenerateMapsResponse, error) {
 
-// GenerateMapsAPI - для API режима (генерирует карты в ./maps)
-func (v *visualizationService) GenerateMapsAPI(depoID string, maxLocomotives int) (*responses.GenerateMapsResponse, error) {
 	fmt.Printf("\n%s\n", strings.Repeat("=", 80))
 	fmt.Printf("🚀 ЗАПУСК ГЕНЕРАЦИИ КАРТ ДЛЯ ДЕПО %s\n", depoID)
 	fmt.Printf("%s\n", strings.Repeat("=", 80))
@@ -159,69 +156,99 @@ func (v *visualizationService) GenerateMapsAPI(depoID string, maxLocomotives int
 		return nil, fmt.Errorf("❌ не удалось создать директорию %s: %w", v.mapsDir, err)
 	}
 	
-	// Удаляем старые карты перед генерацией новых
-	fmt.Println("🧹 Очистка старых файлов...")
-	v.cleanupOldMaps(depoID)
-	fmt.Printf("✅ Директория очищена\n")
-
 	// 1. Загружаем данные
-	fmt.Println("1️⃣ Загрузка данных...")
-	locomotives := loadData(v.dataPath)
+	report(mapStageLoad, "Загрузка данных...")
+	locomotives, err := readData(v.dataPath)
+	if err != nil {
+		return nil, fmt.Errorf("❌ не удалось загрузить данные: %w", err)
+	}
 	fmt.Printf("   Загружено локомотивов: %d\n", len(locomotives))
 
 	// 2. Разбиваем на поездки
-	fmt.Println("2️⃣ Разбиение на поездки...")
+	report(mapStageTrips, "Разбиение на поездки...")
 	for key, loc := range locomotives {
 		loc.Trips = splitIntoTrips(loc.Records)
 		locomotives[key] = loc
 	}
 
 	// 3. Фильтруем локомотивы выбранного депо
-	fmt.Printf("3️⃣ Фильтрация локомотивов депо %s...\n", depoID)
+	report(mapStageFilter, fmt.Sprintf("Фильтрация локомотивов депо %s...", depoID))
 	depoLocomotives := filterLocomotivesByDepo(locomotives, depoID)
 	if len(depoLocomotives) == 0 {
 		return nil, fmt.Errorf("❌ депо %s не найдено или нет локомотивов", depoID)
 	}
 	fmt.Printf("   Найдено локомотивов в депо: %d\n", len(depoLocomotives))
 
+	// Каждое задание пишет в свою директорию, параллельные генерации не мешают друг другу
+	jobDir := filepath.Join(v.mapsDir, jobID)
+	fmt.Printf("🆔 Задание: %s\n", jobID)
+
 	// 4. Получаем координаты станций
-	fmt.Println("4️⃣ Получение координат станций...")
-	stations := v.getStationCoordinates(depoID)
+	report(mapStageCoordinates, "Получение координат станций...")
+	stations, geo, err := v.loadMapGeography(depoID, depoLocomotives, opts)
+	if err != nil {
+		return nil, fmt.Errorf("❌ %w", err)
+	}
 	fmt.Printf("   Загружено станций с координатами: %d\n", len(stations))
 
 	// 5. Собираем статистику посещений
-	fmt.Println("5️⃣ Сбор статистики посещений...")
+	report(mapStageStats, "Сбор статистики посещений...")
 	stationStats := v.collectStationStats(depoLocomotives, stations)
 
 	// 6. Строим маршруты для локомотивов
-	fmt.Println("6️⃣ Построение маршрутов...")
+	report(mapStageRoutes, "Построение маршрутов...")
 	routes := v.buildLocomotiveRoutes(depoLocomotives, stations)
 
 	// 7. Сортируем локомотивы по активности
-	fmt.Println("7️⃣ Сортировка локомотивов...")
+	report(mapStageSort, "Сортировка локомотивов...")
 	topLocomotives := getTopLocomotives(depoLocomotives, maxLocomotives)
 	fmt.Printf("   Топ-%d локомотивов:\n", len(topLocomotives))
 	for i, loc := range topLocomotives {
 		fmt.Printf("      %d. %s\n", i+1, loc)
 	}
 
-	// 8. Генерируем HTML карты
-	fmt.Println("8️⃣ Генерация общей карты...")
-	overviewURL, err := v.generateHTMLMapAPI(depoID, stationStats, routes, topLocomotives, stations)
+	// 8-11. Генерируем HTML карты (ресурсы Leaflet раздает сервер)
+	page := v.newMapPage(locomotives, stations, opts, false)
+	page.job = jobID
+	page.Warning = geo.Warning()
+
+	report(mapStageOverview, "Генерация общей карты...")
+	overviewURL, err := v.generateHTMLMapAPI(depoID, stationStats, routes, topLocomotives, stations, page)
 	if err != nil {
 		return nil, fmt.Errorf("❌ ошибка генерации общей карты: %w", err)
 	}
 	fmt.Printf("   ✅ Общая карта: %s\n", overviewURL)
 
-	fmt.Println("9️⃣ Генерация тепловой карты...")
-	heatmapURL, err := v.generateHeatmapHTMLAPI(depoID, stationStats)
+	report(mapStageHeatmap, "Генерация тепловой карты...")
+	heatStats := v.collectHeatStats(depoLocomotives, stations, opts)
+	heatmapURL, err := v.generateHeatmapHTMLAPI(depoID, heatStats, opts, page)
 	if err != nil {
 		return nil, fmt.Errorf("❌ ошибка генерации тепловой карты: %w", err)
 	}
 	fmt.Printf("   ✅ Тепловая карта: %s\n", heatmapURL)
 
-	// 9. Генерируем карты для топ локомотивов
-	fmt.Println("🔟 Генерация карт локомотивов...")
+	report(mapStageBranches, "Генерация карты веток...")
+	branches := buildImprovedBranches(depoLocomotives)[depoID]
+	branchesURL, err := v.generateBranchesHTMLAPI(depoID, branches, stations, page)
+	if err != nil {
+		return nil, fmt.Errorf("❌ ошибка генерации карты веток: %w", err)
+	}
+	fmt.Printf("   ✅ Карта веток: %s\n", branchesURL)
+
+	report(mapStageFlow, "Генерация карты потоков по перегонам...")
+	segments := buildSegmentFlows(depoLocomotives, stations)
+	flowURL, err := v.generateFlowHTMLAPI(depoID, segments, opts, page)
+	if err != nil {
+		return nil, fmt.Errorf("❌ ошибка генерации карты потоков: %w", err)
+	}
+	segmentsURL, err := v.generateSegmentsCSVAPI(depoID, segments, opts, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("❌ ошибка сохранения таблицы перегонов: %w", err)
+	}
+	fmt.Printf("   ✅ Карта потоков: %s\n", flowURL)
+
+	// 12. Генерируем карты для топ локомотивов
+	report(mapStageLocomotives, "Генерация карт локомотивов...")
 	var locoMaps []responses.LocomotiveMap
 	for i, locKey := range topLocomotives {
 		if i >= maxLocomotives {
@@ -230,25 +257,31 @@ func (v *visualizationService) GenerateMapsAPI(depoID string, maxLocomotives int
 		
 		fmt.Printf("   Генерация для %s... ", locKey)
 		loc := depoLocomotives[locKey]
-		locoURL, err := v.generateLocomotiveHTMLAPI(locKey, loc, stations)
+		locoURL, err := v.generateLocomotiveHTMLAPI(locKey, loc, stations, page)
 		if err != nil {
 			fmt.Printf("❌ ошибка: %v\n", err)
 			continue
 		}
 		fmt.Printf("✅ %s\n", locoURL)
+
+		playbackURL, err := v.generateLocomotivePlaybackHTMLAPI(locKey, loc, stations, page)
+		if err != nil {
+			fmt.Printf("      ⚠️ воспроизведение: %v\n", err)
+		}
 		
 		locoMaps = append(locoMaps, responses.LocomotiveMap{
-			Key:       locKey,
-			Model:     loc.Series,
-			Number:    loc.Number,
-			URL:       locoURL,
-			TripCount: len(loc.Trips),
+			Key:         locKey,
+			Model:       loc.Series,
+			Number:      loc.Number,
+			URL:         locoURL,
+			PlaybackURL: playbackURL,
+			TripCount:   len(loc.Trips),
 		})
 	}
 
 	// Проверяем созданные файлы
 	fmt.Println("\n📄 Проверка созданных файлов:")
-	files, err := os.ReadDir(v.mapsDir)
+	files, err := os.ReadDir(jobDir)
 	if err != nil {
 		fmt.Printf("❌ Ошибка чтения директории: %v\n", err)
 	} else {
@@ -259,20 +292,36 @@ func (v *visualizationService) GenerateMapsAPI(depoID string, maxLocomotives int
 		}
 	}
 
-	// 10. Формируем ответ
+	// Формируем ответ
+	generatedAt := time.Now()
 	response := &responses.GenerateMapsResponse{
+		JobID:       jobID,
 		DepotID:     depoID,
-		GeneratedAt: time.Now().Format(time.RFC3339),
+		GeneratedAt: generatedAt.Format(time.RFC3339),
+		HeatMetric:  heatMetric(opts),
+
+		CoordinatesGenerated: geo.Generated,
+		UnmappedStations:     geo.Unmapped,
+		UnmappedRecords:      geo.UnmappedRecords,
 		Maps: responses.MapsList{
 			Overview:    overviewURL,
 			Heatmap:     heatmapURL,
+			Branches:    branchesURL,
+			Flow:        flowURL,
+			Segments:    segmentsURL,
 			Locomotives: locoMaps,
 		},
 	}
+	if v.jobTTL > 0 {
+		response.ExpiresAt = generatedAt.Add(v.jobTTL).Format(time.RFC3339)
+	}
+	if err := v.saveMapJob(response); err != nil {
+		return nil, fmt.Errorf("❌ ошибка сохранения задания: %w", err)
+	}
 
 	fmt.Printf("\n%s\n", strings.Repeat("=", 80))
 	fmt.Printf("✅ ГЕНЕРАЦИЯ ЗАВЕРШЕНА УСПЕШНО\n")
-	fmt.Printf("📁 Файлы сохранены в: %s\n", v.mapsDir)
+	fmt.Printf("📁 Файлы сохранены в: %s\n", jobDir)
 	fmt.Printf("%s\n", strings.Repeat("=", 80))
 
 	return response, nil
@@ -281,7 +330,7 @@ func (v *visualizationService) GenerateMapsAPI(depoID string, maxLocomotives int
 // ==================== Существующие методы (с изменением пути сохранения) ====================
 
 // GenerateMap создает карту для депо (консольный режим, сохраняет в ./maps)
-func (v *visualizationService) GenerateMap(depoID string, maxLocomotives int) error {
+func (v *visualizationService) GenerateMap(depoID string, maxLocomotives int, opts domain.MapOptions) error {
 	fmt.Printf("\n%s\n", strings.Repeat("=", 80))
 	fmt.Printf("ПУНКТ 3: ВИЗУАЛИЗАЦИЯ ДЕПО %s\n", depoID)
 	fmt.Printf("%s\n\n", strings.Repeat("=", 80))
@@ -303,7 +352,10 @@ func (v *visualizationService) GenerateMap(depoID string, maxLocomotives int) er
 	fmt.Printf("Найдено локомотивов в депо: %d\n", len(depoLocomotives))
 
 	// 4. Получаем координаты станций
-	stations := v.getStationCoordinates(depoID)
+	stations, geo, err := v.loadMapGeography(depoID, depoLocomotives, opts)
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Загружено станций с координатами: %d\n", len(stations))
 
 	// 5. Собираем статистику посещений
@@ -315,8 +367,10 @@ func (v *visualizationService) GenerateMap(depoID string, maxLocomotives int) er
 	// 7. Сортируем локомотивы по активности
 	topLocomotives := getTopLocomotives(depoLocomotives, maxLocomotives)
 
-	// 8. Генерируем HTML карту (используем старый метод, но он должен сохранять в v.mapsDir)
-	err := v.generateHTMLMap(depoID, stationStats, routes, topLocomotives, stations)
+	// 8. Генерируем HTML карту (ресурсы Leaflet встраиваются в файл)
+	page := v.newMapPage(locomotives, stations, opts, true)
+	page.Warning = geo.Warning()
+	err = v.generateHTMLMap(depoID, stationStats, routes, topLocomotives, stations, page)
 	if err != nil {
 		return fmt.Errorf("ошибка генерации карты: %w", err)
 	}
@@ -326,7 +380,7 @@ func (v *visualizationService) GenerateMap(depoID string, maxLocomotives int) er
 }
 
 // GenerateHeatmap создает тепловую карту (консольный режим)
-func (v *visualizationService) GenerateHeatmap(depoID string) error {
+func (v *visualizationService) GenerateHeatmap(depoID string, opts domain.MapOptions) error {
 	locomotives := loadData(v.dataPath)
 
 	for key, loc := range locomotives {
@@ -335,14 +389,20 @@ func (v *visualizationService) GenerateHeatmap(depoID string) error {
 	}
 
 	depoLocomotives := filterLocomotivesByDepo(locomotives, depoID)
-	stations := v.getStationCoordinates(depoID)
-	stationStats := v.collectStationStats(depoLocomotives, stations)
+	stations, geo, err := v.loadMapGeography(depoID, depoLocomotives, opts)
+	if err != nil {
+		return err
+	}
+	stationStats := v.collectHeatStats(depoLocomotives, stations, opts)
+
+	page := v.newMapPage(locomotives, stations, opts, true)
+	page.Warning = geo.Warning()
 
-	return v.generateHeatmapHTML(depoID, stationStats)
+	return v.generateHeatmapHTML(depoID, stationStats, opts, page)
 }
 
 // GenerateLocomotiveMap создает карту для конкретного локомотива (консольный режим)
-func (v *visualizationService) GenerateLocomotiveMap(locomotiveKey string) error {
+func (v *visualizationService) GenerateLocomotiveMap(locomotiveKey string, opts domain.MapOptions) error {
 	locomotives := loadData(v.dataPath)
 
 	loc, exists := locomotives[locomotiveKey]
@@ -351,20 +411,36 @@ func (v *visualizationService) GenerateLocomotiveMap(locomotiveKey string) error
 	}
 
 	loc.Trips = splitIntoTrips(loc.Records)
-	stations := v.getStationCoordinates(loc.Depo)
+	stations, geo, err := v.loadMapGeography(loc.Depo, map[string]domain.Locomotive{locomotiveKey: loc}, opts)
+	if err != nil {
+		return err
+	}
+
+	page := v.newMapPage(locomotives, stations, opts, true)
+	page.Warning = geo.Warning()
 
-	return v.generateLocomotiveHTML(locomotiveKey, loc, stations)
+	return v.generateLocomotiveHTML(locomotiveKey, loc, stations, page)
 }
 
 // GenerateAllMaps генерирует все карты для депо (консольный режим)
-func (v *visualizationService) GenerateAllMaps(depoID string, maxLocomotives int) error {
+func (v *visualizationService) GenerateAllMaps(depoID string, maxLocomotives int, opts domain.MapOptions) error {
 	// Общая карта с топ-10 локомотивами
-	if err := v.GenerateMap(depoID, maxLocomotives); err != nil {
+	if err := v.GenerateMap(depoID, maxLocomotives, opts); err != nil {
 		return err
 	}
 
 	// Тепловая карта
-	if err := v.GenerateHeatmap(depoID); err != nil {
+	if err := v.GenerateHeatmap(depoID, opts); err != nil {
+		return err
+	}
+
+	// Карта веток депо
+	if err := v.generateBranchesMap(depoID, opts); err != nil {
+		return err
+	}
+
+	// Карта потоков по перегонам
+	if err := v.generateFlowMap(depoID, opts); err != nil {
 		return err
 	}
 
@@ -390,7 +466,7 @@ func (v *visualizationService) GenerateAllMaps(depoID string, maxLocomotives int
 		if i >= maxLocomotives {
 			break
 		}
-		if err := v.GenerateLocomotiveMap(act.key); err != nil {
+		if err := v.GenerateLocomotiveMap(act.key, opts); err != nil {
 			fmt.Printf("Ошибка для %s: %v\n", act.key, err)
 		}
 	}
@@ -399,11 +475,60 @@ func (v *visualizationService) GenerateAllMaps(depoID string, maxLocomotives int
 	return nil
 }
 
+// generateBranchesMap создает карту веток депо (консольный режим)
+func (v *visualizationService) generateBranchesMap(depoID string, opts domain.MapOptions) error {
+	locomotives := loadData(v.dataPath)
+
+	for key, loc := range locomotives {
+		loc.Trips = splitIntoTrips(loc.Records)
+		locomotives[key] = loc
+	}
+
+	depoLocomotives := filterLocomotivesByDepo(locomotives, depoID)
+	branches := buildImprovedBranches(depoLocomotives)[depoID]
+	stations, geo, err := v.loadMapGeography(depoID, depoLocomotives, opts)
+	if err != nil {
+		return err
+	}
+
+	page := v.newMapPage(locomotives, stations, opts, true)
+	page.Warning = geo.Warning()
+
+	_, err = v.generateBranchesHTMLAPI(depoID, branches, stations, page)
+	return err
+}
+
+// generateFlowMap создает карту потоков по перегонам и таблицу самых загруженных перегонов (консольный режим)
+func (v *visualizationService) generateFlowMap(depoID string, opts domain.MapOptions) error {
+	locomotives := loadData(v.dataPath)
+
+	for key, loc := range locomotives {
+		loc.Trips = splitIntoTrips(loc.Records)
+		locomotives[key] = loc
+	}
+
+	depoLocomotives := filterLocomotivesByDepo(locomotives, depoID)
+	stations, geo, err := v.loadMapGeography(depoID, depoLocomotives, opts)
+	if err != nil {
+		return err
+	}
+	segments := buildSegmentFlows(depoLocomotives, stations)
+
+	page := v.newMapPage(locomotives, stations, opts, true)
+	page.Warning = geo.Warning()
+
+	if _, err := v.generateFlowHTMLAPI(depoID, segments, opts, page); err != nil {
+		return err
+	}
+	_, err = v.generateSegmentsCSVAPI(depoID, segments, opts, page.job)
+	return err
+}
+
 // ==================== Вспомогательные методы ====================
 
-// getStationCoordinates получает координаты станций
-func (v *visualizationService) getStationCoordinates(depoID string) map[string]domain.Station {
-	stations := make(map[string]domain.Station)
+// getStationCoordinates получает координаты станций. Если station_info.csv не загружается,
+// в строгом режиме возвращается ошибка, иначе - тестовые координаты (generated = true).
+func (v *visualizationService) getStationCoordinates(depoID string, strict bool) (map[string]domain.Station, bool, error) {
 
 	// Используем station_info.csv
 	coordsFile := "./data/station_info.csv"
@@ -427,18 +552,21 @@ func (v *visualizationService) getStationCoordinates(depoID string) map[string]d
 
 	fmt.Printf("Загрузка станций из: %s\n", coordsFile)
 
-	if loadedStations, err := loadStationCoordinates(coordsFile); err == nil {
-		stations = loadedStations
-	} else {
-		fmt.Printf("⚠️ Не удалось загрузить station_info.csv: %v\n", err)
-		fmt.Println("Используются тестовые координаты")
-		stations = v.generateTestCoordinates(depoID)
+	stations, err := loadStationCoordinates(coordsFile)
+	if err == nil {
+		return stations, false, nil
 	}
 
-	return stations
+	if strict {
+		return nil, false, fmt.Errorf("не удалось загрузить координаты станций (%s): %w", coordsFile, err)
+	}
+	fmt.Printf("⚠️ Не удалось загрузить station_info.csv: %v\n", err)
+	fmt.Println("⚠️ Используются ВЫМЫШЛЕННЫЕ тестовые координаты (для реальной географии включите строгий режим)")
+	return v.generateTestCoordinates(depoID), true, nil
 }
 
-// generateTestCoordinates создает тестовые координаты
+// generateTestCoordinates создает тестовые координаты вокруг условного центра.
+// Станции и их положение выдуманы, используется только вне строгого режима.
 func (v *visualizationService) generateTestCoordinates(depoID string) map[string]domain.Station {
 	stations := make(map[string]domain.Station)
 
@@ -619,29 +747,23 @@ func (v *visualizationService) buildLocomotiveRoutes(
 	return routes
 }
 
-// ==================== Методы генерации HTML для API (сохраняют в ./maps) ====================
+// ==================== Методы генерации HTML для API (сохраняют в /maps/{job_id}/) ====================
 
-// generateHTMLMapAPI создает HTML файл с картой в ./maps
+// generateHTMLMapAPI создает HTML файл с картой в директории задания (/maps/{job_id}/)
 func (v *visualizationService) generateHTMLMapAPI(
 	depoID string,
 	stationStats map[string]*domain.StationStats,
 	routes map[string][]domain.LocomotiveRoute,
 	topLocomotives []string,
-	stations map[string]domain.Station) (string, error) {
+	stations map[string]domain.Station,
+	page mapPage) (string, error) {
 
 	fmt.Printf("   📍 Генерация HTML карты для депо %s...\n", depoID)
-	
-	// Проверяем директорию
-	if err := os.MkdirAll(v.mapsDir, 0755); err != nil {
-		return "", fmt.Errorf("не удалось создать директорию: %w", err)
-	}
-
-	// Определяем регион депо
-	depoRegion := getRegionByDepo(depoID)
 
 	// Подготавливаем данные для JavaScript
-	var jsRoutes []JSRoute
-	var jsStations []JSStation
+	jsRoutes := []JSRoute{}
+	jsStations := []JSStation{}
+	legend := []mapLegendItem{}
 
 	colors := []string{"#FF6B6B", "#4ECDC4", "#45B7D1", "#96CEB4", "#FFEAA7", "#C7B198", "#DFC2C2", "#B2B2B2"}
 
@@ -649,8 +771,9 @@ func (v *visualizationService) generateHTMLMapAPI(
 	activeStations := make(map[string]bool)
 
 	for i, locKey := range topLocomotives {
+		color := colors[i%len(colors)]
+		legend = append(legend, mapLegendItem{Color: color, Label: locKey})
 		if routeList, exists := routes[locKey]; exists {
-			color := colors[i%len(colors)]
 			for _, route := range routeList {
 				var points [][]float64
 				for _, p := range route.Points {
@@ -672,6 +795,7 @@ func (v *visualizationService) generateHTMLMapAPI(
 	// Добавляем только активные станции
 	for _, stat := range stationStats {
 		if activeStations[stat.StationID] && stat.Latitude != 0 && stat.Longitude != 0 {
+			// Размер от 5 до 30 пикселей, цвет от синего к красному
 			size := 5 + stat.Popularity*25
 			color := fmt.Sprintf("hsl(%d, 70%%, 50%%)", int(240*(1-stat.Popularity)))
 
@@ -693,52 +817,66 @@ func (v *visualizationService) generateHTMLMapAPI(
 	latPadding := (maxLat - minLat) * 0.2
 	lonPadding := (maxLon - minLon) * 0.2
 
-	stationsJSON, _ := json.Marshal(jsStations)
-	routesJSON, _ := json.Marshal(jsRoutes)
-
-	// HTML шаблон
-	html := v.generateMapHTMLTemplate(depoID, depoRegion, len(jsStations), len(jsRoutes),
-		len(topLocomotives), topLocomotives, colors,
-		minLat-latPadding, minLon-lonPadding, maxLat+latPadding, maxLon+lonPadding,
-		stationsJSON, routesJSON)
+	data := overviewMapData{
+		mapPage: page,
+		DepoID:  depoID,
+		Region:  getRegionByDepo(depoID),
+		Legend:  legend,
+		Bounds: [][]float64{
+			{minLat - latPadding, minLon - lonPadding},
+			{maxLat + latPadding, maxLon + lonPadding},
+		},
+		Stations: jsStations,
+		Routes:   jsRoutes,
+	}
 
-	// Сохраняем в директорию
 	filename := fmt.Sprintf("depot_%s_map.html", depoID)
-	fullPath := filepath.Join(v.mapsDir, filename)
-
-	fmt.Printf("      Сохранение в %s... ", fullPath)
-	if err := os.WriteFile(fullPath, []byte(html), 0644); err != nil {
+	fmt.Printf("      Сохранение в %s... ", filepath.Join(v.mapsDir, page.job, filename))
+	url, err := v.renderMap(page.job, "overview.html", filename, data)
+	if err != nil {
 		fmt.Printf("❌ %v\n", err)
 		return "", err
 	}
-	fmt.Printf("✅ (%d bytes)\n", len(html))
+	fmt.Printf("✅\n")
 
-	// Проверяем что файл создан
-	if _, err := os.Stat(fullPath); err != nil {
-		return "", fmt.Errorf("файл не создан: %w", err)
-	}
-
-	return "/maps/" + filename, nil
+	return url, nil
 }
 
-// generateHeatmapHTMLAPI создает тепловую карту в ./maps
+// generateHeatmapHTMLAPI создает тепловую карту в директории задания (/maps/{job_id}/)
 func (v *visualizationService) generateHeatmapHTMLAPI(
 	depoID string,
-	stationStats map[string]*domain.StationStats) (string, error) {
+	stationStats map[string]*domain.StationStats,
+	opts domain.MapOptions,
+	page mapPage) (string, error) {
 
-	// Собираем данные для тепловой карты
-	var heatData [][]float64
+	// Собираем данные для тепловой карты (интенсивность - доля от максимума метрики)
+	heatData := [][]float64{}
+	maxWeight := 0.0
 	for _, stat := range stationStats {
-		if stat.VisitCount > 0 {
+		if stat.Weight > 0 {
 			heatData = append(heatData, []float64{
-				stat.Longitude,
 				stat.Latitude,
-				float64(stat.VisitCount),
+				stat.Longitude,
+				stat.Popularity,
 			})
+			maxWeight = math.Max(maxWeight, stat.Weight)
 		}
 	}
 
-	heatDataJSON, _ := json.Marshal(heatData)
+	// Легенда в абсолютных значениях метрики
+	metric := heatMetric(opts)
+	legend := heatmapLegend{
+		Title:   heatMetricTitle(metric),
+		Filters: heatFilterDescription(opts),
+	}
+	for i := len(heatGradient) - 1; i >= 0; i-- {
+		stop := heatGradient[i]
+		legend.Stops = append(legend.Stops, heatmapLegendStop{
+			Color: stop.color,
+			Value: formatHeatValue(metric, stop.stop*maxWeight),
+		})
+	}
+
 	centerLat, centerLon := 55.75, 37.62 // Москва по умолчанию
 
 	// Находим центр по первой станции с координатами
@@ -749,58 +887,26 @@ func (v *visualizationService) generateHeatmapHTMLAPI(
 		}
 	}
 
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head>
-    <title>Депо %s - Тепловая карта</title>
-    <meta charset="utf-8" />
-    <link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css" />
-    <script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
-    <script src="https://unpkg.com/leaflet.heat/dist/leaflet-heat.js"></script>
-    <style>
-        body { margin: 0; padding: 0; }
-        #map { height: 100vh; width: 100vw; }
-        /* Скрываем атрибуцию Leaflet */
-        .leaflet-control-attribution {
-            display: none !important;
-        }
-    </style>
-</head>
-<body>
-    <div id="map"></div>
-    <script>
-        var map = L.map('map').setView([%f, %f], 11);
-        L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
-            attribution: ''
-        }).addTo(map);
-        var heat = L.heatLayer(%s, {
-            radius: 30,
-            blur: 20,
-            maxZoom: 12,
-            gradient: {0.2: 'blue', 0.4: 'cyan', 0.6: 'lime', 0.8: 'yellow', 1.0: 'red'}
-        }).addTo(map);
-    </script>
-</body>
-</html>`, depoID, centerLat, centerLon, heatDataJSON)
-
-	filename := fmt.Sprintf("depot_%s_heatmap.html", depoID)
-	fullPath := filepath.Join(v.mapsDir, filename)
-
-	if err := os.WriteFile(fullPath, []byte(html), 0644); err != nil {
-		return "", err
+	data := heatmapData{
+		mapPage:  page,
+		DepoID:   depoID,
+		Center:   []float64{centerLat, centerLon},
+		HeatData: heatData,
+		Legend:   legend,
 	}
 
-	return "/maps/" + filename, nil
+	return v.renderMap(page.job, "heatmap.html", fmt.Sprintf("depot_%s_heatmap.html", depoID), data)
 }
 
-// generateLocomotiveHTMLAPI создает карту для конкретного локомотива в ./maps
+// generateLocomotiveHTMLAPI создает карту для конкретного локомотива в директории задания (/maps/{job_id}/)
 func (v *visualizationService) generateLocomotiveHTMLAPI(
 	locomotiveKey string,
 	loc domain.Locomotive,
-	stations map[string]domain.Station) (string, error) {
+	stations map[string]domain.Station,
+	page mapPage) (string, error) {
 
 	// Собираем все поездки
-	var allPoints [][][]float64
+	allPoints := [][][]float64{}
 	for _, trip := range loc.Trips {
 		var points [][]float64
 		cleanTrip := removeDuplicates(trip.Stations)
@@ -822,7 +928,7 @@ func (v *visualizationService) generateLocomotiveHTMLAPI(
 		}
 	}
 
-	var stationList []map[string]interface{}
+	stationList := []map[string]interface{}{}
 	for stationID := range uniqueStations {
 		if station, exists := stations[stationID]; exists {
 			stationList = append(stationList, map[string]interface{}{
@@ -834,89 +940,26 @@ func (v *visualizationService) generateLocomotiveHTMLAPI(
 		}
 	}
 
-	routesJSON, _ := json.Marshal(allPoints)
-	stationsJSON, _ := json.Marshal(stationList)
-
 	centerLat, centerLon := 55.75, 37.62
 	if depoStation, exists := stations[loc.Depo]; exists {
 		centerLat, centerLon = depoStation.Latitude, depoStation.Longitude
 	}
 
-	safeKey := strings.ReplaceAll(locomotiveKey, "-", "_")
-	filename := fmt.Sprintf("locomotive_%s.html", safeKey)
-	fullPath := filepath.Join(v.mapsDir, filename)
-
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head>
-    <title>Локомотив %s</title>
-    <meta charset="utf-8" />
-    <link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css" />
-    <script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
-    <style>
-        body { margin: 0; padding: 0; }
-        #map { height: 100vh; width: 100vw; }
-        .info {
-            position: absolute;
-            top: 10px;
-            left: 10px;
-            background: white;
-            padding: 10px;
-            border-radius: 8px;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.2);
-            z-index: 1000;
-        }
-        /* Скрываем атрибуцию Leaflet */
-        .leaflet-control-attribution {
-            display: none !important;
-        }
-    </style>
-</head>
-<body>
-    <div class="info">
-        <h3>Локомотив %s</h3>
-        <p>Модель: %s<br>
-           Номер: %s<br>
-           Депо: %s<br>
-           Поездок: %d<br>
-           Станций: %d</p>
-    </div>
-    <div id="map"></div>
-    <script>
-        var map = L.map('map').setView([%f, %f], 11);
-        L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
-            attribution: ''
-        }).addTo(map);
-
-        var stations = %s;
-        stations.forEach(function(s) {
-            L.circleMarker([s.lat, s.lon], {
-                radius: 6,
-                color: '#3388ff',
-                fillColor: '#3388ff',
-                fillOpacity: 0.8
-            }).bindPopup(s.id + '<br>' + s.name).addTo(map);
-        });
-
-        var colors = ['#FF6B6B', '#4ECDC4', '#45B7D1', '#96CEB4', '#FFEAA7', '#C7B198'];
-        var routes = %s;
-        routes.forEach(function(r, i) {
-            L.polyline(r, {
-                color: colors[i %% colors.length],
-                weight: 3,
-                opacity: 0.6
-            }).addTo(map);
-        });
-    </script>
-</body>
-</html>`, locomotiveKey, locomotiveKey, loc.Series, loc.Number, loc.Depo,
-		len(loc.Trips), len(uniqueStations), centerLat, centerLon, stationsJSON, routesJSON)
-
-	if err := os.WriteFile(fullPath, []byte(html), 0644); err != nil {
-		return "", err
+	data := locomotiveMapData{
+		mapPage:      page,
+		Key:          locomotiveKey,
+		Series:       loc.Series,
+		Number:       loc.Number,
+		Depo:         loc.Depo,
+		TripCount:    len(loc.Trips),
+		StationCount: len(uniqueStations),
+		Center:       []float64{centerLat, centerLon},
+		Stations:     stationList,
+		Routes:       allPoints,
 	}
 
-	return "/maps/" + filename, nil
+	safeKey := strings.ReplaceAll(locomotiveKey, "-", "_")
+	return v.renderMap(page.job, "locomotive.html", fmt.Sprintf("locomotive_%s.html", safeKey), data)
 }
 
 // ==================== Методы генерации HTML для консольного режима ====================
@@ -927,318 +970,41 @@ func (v *visualizationService) generateHTMLMap(
 	stationStats map[string]*domain.StationStats,
 	routes map[string][]domain.LocomotiveRoute,
 	topLocomotives []string,
-	stations map[string]domain.Station) error {
-
-	// Создаем директорию для карт
-	if err := os.MkdirAll(v.mapsDir, 0755); err != nil {
-		return err
-	}
+	stations map[string]domain.Station,
+	page mapPage) error {
 
 	// Определяем регион депо по его ID
 	depoRegion := getRegionByDepo(depoID)
 	fmt.Printf("📍 Депо %s находится в регионе: %s\n", depoID, depoRegion)
 
-	// Подготавливаем данные для JavaScript
-	var jsRoutes []JSRoute
-	var jsStations []JSStation
-
-	colors := []string{"#FF6B6B", "#4ECDC4", "#45B7D1", "#96CEB4", "#FFEAA7", "#C7B198", "#DFC2C2", "#B2B2B2"}
-
-	// Сначала собираем все станции, которые реально посещаются в маршрутах
-	activeStations := make(map[string]bool)
-
-	for i, locKey := range topLocomotives {
-		if routeList, exists := routes[locKey]; exists {
-			color := colors[i%len(colors)]
-			for _, route := range routeList {
-				var points [][]float64
-				for _, p := range route.Points {
-					points = append(points, []float64{p.Lon, p.Lat})
-					activeStations[p.StationID] = true
-				}
-				if len(points) > 1 {
-					jsRoutes = append(jsRoutes, JSRoute{
-						Points:     points,
-						Color:      color,
-						Locomotive: locKey,
-					})
-				}
-			}
-		}
-	}
-
-	// Добавляем только активные станции (которые есть в маршрутах)
-	for _, stat := range stationStats {
-		if activeStations[stat.StationID] && stat.Latitude != 0 && stat.Longitude != 0 {
-			// Размер от 5 до 30 пикселей
-			size := 5 + stat.Popularity*25
-			// Цвет от синего к красному
-			color := fmt.Sprintf("hsl(%d, 70%%, 50%%)", int(240*(1-stat.Popularity)))
-
-			jsStations = append(jsStations, JSStation{
-				ID:     stat.StationID,
-				Name:   stat.StationName,
-				Coords: []float64{stat.Longitude, stat.Latitude},
-				Size:   size,
-				Visits: stat.VisitCount,
-				Color:  color,
-			})
-		}
-	}
-
-	// Определяем границы карты по активным станциям
-	minLat, maxLat := 90.0, -90.0
-	minLon, maxLon := 180.0, -180.0
-
-	if len(jsStations) == 0 {
-		// Если нет активных станций, используем координаты депо
-		if depo, exists := stations[depoID]; exists {
-			minLat, maxLat = depo.Latitude-0.5, depo.Latitude+0.5
-			minLon, maxLon = depo.Longitude-0.5, depo.Longitude+0.5
-		} else {
-			// Запасной вариант
-			minLat, maxLat = 55.0, 56.0
-			minLon, maxLon = 37.0, 38.0
-		}
-	} else {
-		for _, stat := range jsStations {
-			if stat.Coords[1] < minLat {
-				minLat = stat.Coords[1]
-			}
-			if stat.Coords[1] > maxLat {
-				maxLat = stat.Coords[1]
-			}
-			if stat.Coords[0] < minLon {
-				minLon = stat.Coords[0]
-			}
-			if stat.Coords[0] > maxLon {
-				maxLon = stat.Coords[0]
-			}
-		}
-	}
-
-	// Добавляем отступы 20% для лучшего обзора
-	latPadding := (maxLat - minLat) * 0.2
-	lonPadding := (maxLon - minLon) * 0.2
-
-	// Конвертируем в JSON
-	stationsJSON, _ := json.Marshal(jsStations)
-	routesJSON, _ := json.Marshal(jsRoutes)
-
-	// HTML шаблон
-	html := v.generateMapHTMLTemplate(depoID, depoRegion, len(jsStations), len(jsRoutes),
-		len(topLocomotives), topLocomotives, colors,
-		minLat-latPadding, minLon-lonPadding, maxLat+latPadding, maxLon+lonPadding,
-		stationsJSON, routesJSON)
-
-	// Сохраняем файл
-	filename := filepath.Join(v.mapsDir, fmt.Sprintf("depot_%s_map.html", depoID))
-	return os.WriteFile(filename, []byte(html), 0644)
+	_, err := v.generateHTMLMapAPI(depoID, stationStats, routes, topLocomotives, stations, page)
+	return err
 }
 
 // generateHeatmapHTML создает тепловую карту (консольный режим)
 func (v *visualizationService) generateHeatmapHTML(
 	depoID string,
-	stationStats map[string]*domain.StationStats) error {
-
-	// Создаем директорию для карт
-	if err := os.MkdirAll(v.mapsDir, 0755); err != nil {
-		return err
-	}
-
-	filename := filepath.Join(v.mapsDir, fmt.Sprintf("depot_%s_heatmap.html", depoID))
-
-	// Собираем данные для тепловой карты
-	var heatData [][]float64
-	for _, stat := range stationStats {
-		if stat.VisitCount > 0 {
-			heatData = append(heatData, []float64{
-				stat.Longitude,
-				stat.Latitude,
-				float64(stat.VisitCount),
-			})
-		}
-	}
-
-	heatDataJSON, _ := json.Marshal(heatData)
-	centerLat, centerLon := 55.75, 37.62
-
-	// Находим центр (берем первую станцию с координатами)
-	for _, stat := range stationStats {
-		if stat.Latitude != 0 && stat.Longitude != 0 {
-			centerLat, centerLon = stat.Latitude, stat.Longitude
-			break
-		}
-	}
+	stationStats map[string]*domain.StationStats,
+	opts domain.MapOptions,
+	page mapPage) error {
 
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head>
-    <title>Депо %s - Тепловая карта</title>
-    <meta charset="utf-8" />
-    <link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css" />
-    <script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
-    <script src="https://unpkg.com/leaflet.heat/dist/leaflet-heat.js"></script>
-    <style>
-        body { margin: 0; padding: 0; }
-        #map { height: 100vh; width: 100vw; }
-        /* Скрываем атрибуцию Leaflet */
-        .leaflet-control-attribution {
-            display: none !important;
-        }
-    </style>
-</head>
-<body>
-    <div id="map"></div>
-    <script>
-        var map = L.map('map').setView([%f, %f], 11);
-        
-        L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
-            attribution: ''
-        }).addTo(map);
-
-        var heat = L.heatLayer(%s, {
-            radius: 30,
-            blur: 20,
-            maxZoom: 12,
-            gradient: {0.2: 'blue', 0.4: 'cyan', 0.6: 'lime', 0.8: 'yellow', 1.0: 'red'}
-        }).addTo(map);
-    </script>
-</body>
-</html>`, depoID, centerLat, centerLon, heatDataJSON)
-
-	return os.WriteFile(filename, []byte(html), 0644)
+	_, err := v.generateHeatmapHTMLAPI(depoID, stationStats, opts, page)
+	return err
 }
 
 // generateLocomotiveHTML создает карту для конкретного локомотива (консольный режим)
 func (v *visualizationService) generateLocomotiveHTML(
 	locomotiveKey string,
 	loc domain.Locomotive,
-	stations map[string]domain.Station) error {
+	stations map[string]domain.Station,
+	page mapPage) error {
 
-	// Создаем директорию для карт
-	if err := os.MkdirAll(v.mapsDir, 0755); err != nil {
+	if _, err := v.generateLocomotiveHTMLAPI(locomotiveKey, loc, stations, page); err != nil {
 		return err
 	}
 
-	filename := filepath.Join(v.mapsDir, fmt.Sprintf("locomotive_%s.html", strings.ReplaceAll(locomotiveKey, "-", "_")))
-
-	// Собираем все поездки
-	var allPoints [][][]float64
-	for _, trip := range loc.Trips {
-		var points [][]float64
-		// Очищаем от стоянок
-		cleanTrip := removeDuplicates(trip.Stations)
-		for _, stationID := range cleanTrip {
-			if station, exists := stations[stationID]; exists {
-				points = append(points, []float64{station.Longitude, station.Latitude})
-			}
-		}
-		if len(points) > 1 {
-			allPoints = append(allPoints, points)
-		}
-	}
-
-	// Уникальные станции
-	uniqueStations := make(map[string]bool)
-	for _, trip := range loc.Trips {
-		for _, stationID := range trip.Stations {
-			uniqueStations[stationID] = true
-		}
-	}
-
-	var stationList []map[string]interface{}
-	for stationID := range uniqueStations {
-		if station, exists := stations[stationID]; exists {
-			stationList = append(stationList, map[string]interface{}{
-				"id":   stationID,
-				"lat":  station.Latitude,
-				"lon":  station.Longitude,
-				"name": station.Name,
-			})
-		}
-	}
-
-	// Конвертируем в JSON
-	routesJSON, _ := json.Marshal(allPoints)
-	stationsJSON, _ := json.Marshal(stationList)
-
-	// Центр карты
-	centerLat, centerLon := 55.75, 37.62
-	if depoStation, exists := stations[loc.Depo]; exists {
-		centerLat, centerLon = depoStation.Latitude, depoStation.Longitude
-	}
-
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head>
-    <title>Локомотив %s</title>
-    <meta charset="utf-8" />
-    <link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css" />
-    <script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
-    <style>
-        body { margin: 0; padding: 0; }
-        #map { height: 100vh; width: 100vw; }
-        .info {
-            position: absolute;
-            top: 10px;
-            left: 10px;
-            background: white;
-            padding: 10px;
-            border-radius: 8px;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.2);
-            z-index: 1000;
-        }
-        /* Скрываем атрибуцию Leaflet */
-        .leaflet-control-attribution {
-            display: none !important;
-        }
-    </style>
-</head>
-<body>
-    <div class="info">
-        <h3>Локомотив %s</h3>
-        <p>Модель: %s<br>
-           Номер: %s<br>
-           Депо: %s<br>
-           Поездок: %d<br>
-           Станций: %d</p>
-    </div>
-    <div id="map"></div>
-    <script>
-        var map = L.map('map').setView([%f, %f], 11);
-        
-        L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
-            attribution: ''
-        }).addTo(map);
-
-        // Станции
-        var stations = %s;
-        stations.forEach(function(s) {
-            L.circleMarker([s.lat, s.lon], {
-                radius: 6,
-                color: '#3388ff',
-                fillColor: '#3388ff',
-                fillOpacity: 0.8
-            }).bindPopup(s.id + '<br>' + s.name).addTo(map);
-        });
-
-        // Маршруты (разные цвета для разных поездок)
-        var colors = ['#FF6B6B', '#4ECDC4', '#45B7D1', '#96CEB4', '#FFEAA7', '#C7B198'];
-        var routes = %s;
-        routes.forEach(function(r, i) {
-            L.polyline(r, {
-                color: colors[i %% colors.length],
-                weight: 3,
-                opacity: 0.6
-            }).addTo(map);
-        });
-    </script>
-</body>
-</html>`, locomotiveKey, locomotiveKey, loc.Series, loc.Number, loc.Depo, len(loc.Trips), len(uniqueStations),
-		centerLat, centerLon, stationsJSON, routesJSON)
-
-	return os.WriteFile(filename, []byte(html), 0644)
+	_, err := v.generateLocomotivePlaybackHTMLAPI(locomotiveKey, loc, stations, page)
+	return err
 }
 
 // ==================== Общие вспомогательные функции ====================
@@ -1326,189 +1092,3 @@ func (v *visualizationService) calculateBounds(
 
 	return minLat, maxLat, minLon, maxLon
 }
-
-// generateMapHTMLTemplate создает HTML шаблон карты
-func (v *visualizationService) generateMapHTMLTemplate(
-	depoID, depoRegion string,
-	stationsCount, routesCount, topCount int,
-	topLocomotives, colors []string,
-	minLat, minLon, maxLat, maxLon float64,
-	stationsJSON, routesJSON []byte) string {
-
-	// HTML шаблон
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head>
-    <title>Депо %s - Карта маршрутов</title>
-    <meta charset="utf-8" />
-    <link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css" />
-    <script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
-    <script src="https://unpkg.com/leaflet.heat/dist/leaflet-heat.js"></script>
-    <style>
-        body { margin: 0; padding: 0; font-family: Arial; }
-        #map { height: 100vh; width: 100vw; }
-        /* Скрываем атрибуцию Leaflet */
-        .leaflet-control-attribution {
-            display: none !important;
-        }
-        .info-panel {
-            position: absolute;
-            top: 10px;
-            right: 10px;
-            background: white;
-            padding: 15px;
-            border-radius: 8px;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.2);
-            z-index: 1000;
-            max-height: 80vh;
-            overflow-y: auto;
-            width: 300px;
-        }
-        .legend { margin-top: 10px; padding: 10px 0; border-top: 1px solid #eee; }
-        .legend-item { display: flex; align-items: center; margin: 5px 0; }
-        .color-box { width: 20px; height: 20px; margin-right: 8px; border-radius: 4px; }
-        .station-info {
-            position: absolute; bottom: 30px; left: 10px; background: white;
-            padding: 10px; border-radius: 4px; box-shadow: 0 2px 5px rgba(0,0,0,0.2);
-            z-index: 1000; font-size: 12px; max-width: 300px; display: none;
-        }
-        .controls {
-            position: absolute; top: 10px; left: 10px; background: white;
-            padding: 10px; border-radius: 8px; box-shadow: 0 2px 10px rgba(0,0,0,0.2);
-            z-index: 1000;
-        }
-        button {
-            margin: 2px; padding: 5px 10px; cursor: pointer;
-            background: #f0f0f0; border: 1px solid #ccc; border-radius: 4px;
-        }
-        button:hover { background: #e0e0e0; }
-        .stats { font-size: 12px; color: #666; margin-top: 10px; }
-    </style>
-</head>
-<body>
-    <div id="map"></div>
-    
-    <div class="info-panel">
-        <h3>Депо %s</h3>
-        <p>Регион: %s</p>
-        <p>Станций на карте: %d<br>
-           Маршрутов: %d<br>
-           Топ-%d локомотивов</p>
-        
-        <div class="legend">
-            <h4>Цвета маршрутов:</h4>`, depoID, depoID, depoRegion, stationsCount, routesCount, topCount)
-
-	// Добавляем легенду для каждого локомотива
-	for i, locKey := range topLocomotives {
-		html += fmt.Sprintf(`
-            <div class="legend-item">
-                <div class="color-box" style="background: %s"></div>
-                <span>%s</span>
-            </div>`, colors[i%len(colors)], locKey)
-	}
-
-	html += fmt.Sprintf(`
-        </div>
-        
-        <div class="legend">
-            <h4>Популярность станций:</h4>
-            <div class="legend-item"><div class="color-box" style="background: #ff0000"></div> Высокая</div>
-            <div class="legend-item"><div class="color-box" style="background: #ffaa00"></div> Средняя</div>
-            <div class="legend-item"><div class="color-box" style="background: #0000ff"></div> Низкая</div>
-        </div>
-        
-        <div class="stats">
-            <p>💡 Показаны только станции, которые посещают локомотивы депо.</p>
-        </div>
-    </div>
-
-    <div class="controls">
-        <button onclick="toggleHeatmap()">🔥 Тепловая карта</button>
-        <button onclick="toggleRoutes()">🛤️ Маршруты</button>
-        <button onclick="toggleStations()">📍 Станции</button>
-        <button onclick="resetView()">🗺️ Сброс вида</button>
-    </div>
-
-    <div id="stationInfo" class="station-info"></div>
-
-    <script>
-        var map = L.map('map').fitBounds([[%f, %f], [%f, %f]]);
-        L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
-            attribution: ''
-        }).addTo(map);
-
-        var stationLayer = L.layerGroup();
-        var routeLayer = L.layerGroup();
-        var heatLayer = null;
-
-        var stations = %s;
-        stations.forEach(function(s) {
-            var marker = L.circleMarker([s.coords[1], s.coords[0]], {
-                radius: s.size,
-                color: s.color,
-                fillColor: s.color,
-                fillOpacity: 0.8,
-                weight: 1
-            }).bindPopup('<b>' + s.id + '</b><br>' + s.name + '<br>Посещений: ' + s.visits);
-            
-            marker.on('mouseover', function(e) {
-                document.getElementById('stationInfo').style.display = 'block';
-                document.getElementById('stationInfo').innerHTML = '<b>' + s.id + '</b><br>' + s.name + '<br>Посещений: ' + s.visits;
-                document.getElementById('stationInfo').style.left = (e.originalEvent.pageX + 10) + 'px';
-                document.getElementById('stationInfo').style.top = (e.originalEvent.pageY - 40) + 'px';
-            });
-            
-            marker.on('mouseout', function() {
-                document.getElementById('stationInfo').style.display = 'none';
-            });
-            
-            stationLayer.addLayer(marker);
-        });
-        stationLayer.addTo(map);
-
-        var routes = %s;
-        routes.forEach(function(r) {
-            var points = r.points.map(function(p) { return [p[1], p[0]]; });
-            var polyline = L.polyline(points, {
-                color: r.color,
-                weight: 3,
-                opacity: 0.7
-            }).bindPopup('Локомотив: ' + r.locomotive);
-            routeLayer.addLayer(polyline);
-        });
-        routeLayer.addTo(map);
-
-        var heatData = stations.map(function(s) {
-            return [s.coords[1], s.coords[0], s.visits];
-        });
-        heatLayer = L.heatLayer(heatData, {
-            radius: 20, blur: 15, maxZoom: 12,
-            gradient: {0.2: 'blue', 0.4: 'cyan', 0.6: 'lime', 0.8: 'yellow', 1.0: 'red'}
-        });
-
-        function toggleHeatmap() {
-            if (map.hasLayer(heatLayer)) map.removeLayer(heatLayer);
-            else heatLayer.addTo(map);
-        }
-
-        function toggleRoutes() {
-            if (map.hasLayer(routeLayer)) map.removeLayer(routeLayer);
-            else routeLayer.addTo(map);
-        }
-
-        function toggleStations() {
-            if (map.hasLayer(stationLayer)) map.removeLayer(stationLayer);
-            else stationLayer.addTo(map);
-        }
-
-        function resetView() {
-            map.fitBounds([[%f, %f], [%f, %f]]);
-        }
-
-        L.control.scale().addTo(map);
-    </script>
-</body>
-</html>`, minLat, minLon, maxLat, maxLon, stationsJSON, routesJSON, minLat, minLon, maxLat, maxLon)
-
-	return html
-}
\ No newline at end of file
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// GetBranchDetails - для API режима (детализация одной ветки депо)
+func (a *algorithmService) GetBranchDetails(depoCode, branchID string) (*responses.BranchDetails, error) {
+	depotBranches := a.computeBranches()
+
+	branches, exists := depotBranches[depoCode]
+	if !exists {
+		return nil, nil
+	}
+
+	for _, branch := range branches {
+		if branch.BranchID == branchID {
+			return a.buildBranchDetailsResponse(branch), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetLocomotiveBranches - для API режима (ветки депо, которые обслуживает локомотив)
+func (a *algorithmService) GetLocomotiveBranches(series, number string) (*responses.LocomotiveBranches, error) {
+	return a.GetLocomotiveBranchesFrom(loadData(a.dataPath), series, number)
+}
+
+// GetLocomotiveBranchesFrom - то же по уже загруженным данным (ключи Серия-Номер, как у loadData).
+// Переданная карта не изменяется.
+func (a *algorithmService) GetLocomotiveBranchesFrom(
+	loaded map[string]domain.Locomotive,
+	series, number string) (*responses.LocomotiveBranches, error) {
+
+	locomotives := make(map[string]domain.Locomotive, len(loaded))
+	for key, loc := range loaded {
+		loc.Trips = splitIntoTrips(loc.Records)
+		locomotives[key] = loc
+	}
+
+	key := series + "-" + number
+	loc, exists := locomotives[key]
+	if !exists {
+		return nil, nil
+	}
+
+	depotBranches := a.analyzeBranchesImproved(locomotives)
+
+	return a.buildLocomotiveBranchesResponse(key, loc, depotBranches[loc.Depo]), nil
+}
+
+// computeBranches - загружает данные, разбивает на поездки и кластеризует ветки
+func (a *algorithmService) computeBranches() map[string][]domain.ImprovedBranch {
+	return a.analyzeBranchesImproved(a.loadLocomotivesWithTrips())
+}
+
+// loadLocomotivesWithTrips - загружает локомотивы и разбивает их записи на поездки
+func (a *algorithmService) loadLocomotivesWithTrips() map[string]domain.Locomotive {
+	locomotives := loadData(a.dataPath)
+
+	for key, loc := range locomotives {
+		loc.Trips = splitIntoTrips(loc.Records)
+		locomotives[key] = loc
+	}
+
+	return locomotives
+}
+
+// buildLocomotiveBranchesResponse - формирует список веток локомотива (с названиями)
+func (a *algorithmService) buildLocomotiveBranchesResponse(
+	locKey string,
+	loc domain.Locomotive,
+	branches []domain.ImprovedBranch) *responses.LocomotiveBranches {
+
+	response := &responses.LocomotiveBranches{
+		Series:     loc.Series,
+		Number:     loc.Number,
+		DepoCode:   loc.Depo,
+		DepoName:   a.getStationName(loc.Depo),
+		TotalTrips: len(loc.Trips),
+		Branches:   make([]responses.LocomotiveBranchUsage, 0),
+	}
+
+	branchTrips := 0
+	for _, branch := range branches {
+		trips := 0
+		var first, last time.Time
+		for _, trip := range branch.Trips {
+			if trip.LocomotiveKey != locKey {
+				continue
+			}
+			trips++
+			if first.IsZero() || trip.StartTime.Before(first) {
+				first = trip.StartTime
+			}
+			if trip.StartTime.After(last) {
+				last = trip.StartTime
+			}
+		}
+
+		if trips == 0 {
+			continue
+		}
+		branchTrips += trips
+
+		response.Branches = append(response.Branches, responses.LocomotiveBranchUsage{
+			BranchID:     branch.BranchID,
+			CoreStations: a.convertStationsToNames(branch.CoreStations),
+			StationCount: len(branch.CoreStations),
+			Trips:        trips,
+			FirstTrip:    first.Format(time.RFC3339),
+			LastTrip:     last.Format(time.RFC3339),
+		})
+	}
+
+	for i := range response.Branches {
+		response.Branches[i].Share = float64(response.Branches[i].Trips) / float64(branchTrips) * 100
+	}
+
+	// Сортируем по количеству поездок
+	sort.Slice(response.Branches, func(i, j int) bool {
+		if response.Branches[i].Trips == response.Branches[j].Trips {
+			return response.Branches[i].BranchID < response.Branches[j].BranchID
+		}
+		return response.Branches[i].Trips > response.Branches[j].Trips
+	})
+
+	response.BranchTrips = branchTrips
+	response.BranchCount = len(response.Branches)
+
+	return response
+}
+
+// buildBranchDetailsResponse - формирует детализацию ветки (с названиями)
+func (a *algorithmService) buildBranchDetailsResponse(branch domain.ImprovedBranch) *responses.BranchDetails {
+	details := &responses.BranchDetails{
+		DepoCode:     branch.Depo,
+		BranchID:     branch.BranchID,
+		CoreStations: a.convertStationsToNames(branch.CoreStations),
+		StationCount: len(branch.CoreStations),
+		TotalTrips:   len(branch.AllPaths),
+		Locomotives:  a.branchLocomotives(branch),
+		WeeklyUsage:  a.branchWeeklyUsage(branch),
+		PathVariants: a.branchPathVariants(branch),
+	}
+
+	return details
+}
+
+// branchLocomotives - локомотивы, ездившие по ветке, с количеством поездок
+func (a *algorithmService) branchLocomotives(branch domain.ImprovedBranch) []responses.BranchLocomotive {
+	type locUsage struct {
+		series, number string
+		trips          int
+		first, last    time.Time
+	}
+
+	usage := make(map[string]*locUsage)
+	for _, trip := range branch.Trips {
+		u, exists := usage[trip.LocomotiveKey]
+		if !exists {
+			u = &locUsage{
+				series: trip.Series,
+				number: trip.Number,
+				first:  trip.StartTime,
+				last:   trip.StartTime,
+			}
+			usage[trip.LocomotiveKey] = u
+		}
+		u.trips++
+		if trip.StartTime.Before(u.first) {
+			u.first = trip.StartTime
+		}
+		if trip.StartTime.After(u.last) {
+			u.last = trip.StartTime
+		}
+	}
+
+	result := make([]responses.BranchLocomotive, 0, len(usage))
+	for _, u := range usage {
+		result = append(result, responses.BranchLocomotive{
+			Series:    u.series,
+			Number:    u.number,
+			TripCount: u.trips,
+			FirstTrip: u.first.Format(time.RFC3339),
+			LastTrip:  u.last.Format(time.RFC3339),
+		})
+	}
+
+	// Сортируем по количеству поездок
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TripCount == result[j].TripCount {
+			return result[i].Series+result[i].Number < result[j].Series+result[j].Number
+		}
+		return result[i].TripCount > result[j].TripCount
+	})
+
+	return result
+}
+
+// branchWeeklyUsage - понедельный ряд использования ветки и распределение конечных станций
+func (a *algorithmService) branchWeeklyUsage(branch domain.ImprovedBranch) []responses.BranchWeeklyUsage {
+	type weekUsage struct {
+		trips       int
+		locomotives map[string]bool
+		terminals   map[string]int
+	}
+
+	weeks := make(map[time.Time]*weekUsage)
+	for i, trip := range branch.Trips {
+		week := weekStart(trip.StartTime)
+		w, exists := weeks[week]
+		if !exists {
+			w = &weekUsage{
+				locomotives: make(map[string]bool),
+				terminals:   make(map[string]int),
+			}
+			weeks[week] = w
+		}
+		w.trips++
+		w.locomotives[trip.LocomotiveKey] = true
+
+		if path := branch.AllPaths[i]; len(path) > 1 {
+			w.terminals[path[len(path)-1]]++
+		}
+	}
+
+	weekList := make([]time.Time, 0, len(weeks))
+	for week := range weeks {
+		weekList = append(weekList, week)
+	}
+	sort.Slice(weekList, func(i, j int) bool {
+		return weekList[i].Before(weekList[j])
+	})
+
+	result := make([]responses.BranchWeeklyUsage, 0, len(weekList))
+	for _, week := range weekList {
+		w := weeks[week]
+		result = append(result, responses.BranchWeeklyUsage{
+			WeekStart:   week.Format("2006-01-02"),
+			Trips:       w.trips,
+			Locomotives: len(w.locomotives),
+			Terminals:   a.buildTerminalInfos(w.terminals, 0),
+		})
+	}
+
+	return result
+}
+
+// branchPathVariants - различные варианты проезда по ветке с частотой
+func (a *algorithmService) branchPathVariants(branch domain.ImprovedBranch) []responses.PathVariant {
+	counts := make(map[string]int)
+	paths := make(map[string][]string)
+	for _, path := range branch.AllPaths {
+		key := strings.Join(path, ",")
+		counts[key]++
+		paths[key] = path
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] == counts[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return counts[keys[i]] > counts[keys[j]]
+	})
+
+	total := len(branch.AllPaths)
+	result := make([]responses.PathVariant, 0, len(keys))
+	for _, key := range keys {
+		path := paths[key]
+		result = append(result, responses.PathVariant{
+			Path:      a.convertPathToNames(path),
+			Stations:  len(path),
+			Trips:     counts[key],
+			Frequency: float64(counts[key]) / float64(total) * 100,
+		})
+	}
+
+	return result
+}
+
+// buildTerminalInfos - преобразует частоты конечных станций в список (limit <= 0 - без ограничения)
+func (a *algorithmService) buildTerminalInfos(terminals map[string]int, limit int) []responses.TerminalInfo {
+	total := 0
+	list := make([]string, 0, len(terminals))
+	for t, count := range terminals {
+		total += count
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if terminals[list[i]] == terminals[list[j]] {
+			return list[i] < list[j]
+		}
+		return terminals[list[i]] > terminals[list[j]]
+	})
+
+	result := make([]responses.TerminalInfo, 0, len(list))
+	for i, t := range list {
+		if limit > 0 && i >= limit {
+			break
+		}
+		result = append(result, responses.TerminalInfo{
+			Station:   a.getStationName(t),
+			Visits:    terminals[t],
+			Frequency: float64(terminals[t]) / float64(total) * 100,
+		})
+	}
+
+	return result
+}
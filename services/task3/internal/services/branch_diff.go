@@ -0,0 +1,346 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// branchMatchThreshold - минимальная схожесть CoreStations, при которой
+// ветки двух прогонов считаются одной и той же веткой
+const branchMatchThreshold = 0.5
+
+// ErrUnknownDataset - набор данных не найден среди CSV в директории данных сервиса
+var ErrUnknownDataset = errors.New("неизвестный набор данных")
+
+// CompareBranches - для API режима (сравнение веток двух прогонов)
+func (a *algorithmService) CompareBranches(base, target domain.BranchRun) (*responses.BranchDiff, error) {
+	// Наборы данных проверяем до кластеризации, чтобы не считать базовый прогон зря
+	for _, run := range []*domain.BranchRun{&base, &target} {
+		if run.Dataset == "" {
+			continue
+		}
+		path, err := a.resolveDataset(run.Dataset)
+		if err != nil {
+			return nil, err
+		}
+		run.DataPath = path
+	}
+
+	baseBranches, err := a.computeBranchesForRun(base)
+	if err != nil {
+		return nil, fmt.Errorf("базовый прогон: %w", err)
+	}
+
+	targetBranches, err := a.computeBranchesForRun(target)
+	if err != nil {
+		return nil, fmt.Errorf("сравниваемый прогон: %w", err)
+	}
+
+	diff := a.diffBranches(baseBranches, targetBranches)
+	diff.Base = a.buildBranchRunInfo(base, baseBranches)
+	diff.Target = a.buildBranchRunInfo(target, targetBranches)
+
+	return diff, nil
+}
+
+// RunBranchDiff - для консольного режима (сравнение веток двух прогонов)
+func (a *algorithmService) RunBranchDiff(base, target domain.BranchRun) error {
+	fmt.Println("Сравнение веток двух прогонов...")
+
+	diff, err := a.CompareBranches(base, target)
+	if err != nil {
+		return err
+	}
+
+	a.printBranchDiff(diff)
+	return nil
+}
+
+// resolveDataset - путь к набору данных по имени файла. Допускаются только
+// CSV из директории данных сервиса (без подкаталогов), кроме справочника станций
+func (a *algorithmService) resolveDataset(name string) (string, error) {
+	if name != filepath.Base(name) || !strings.EqualFold(filepath.Ext(name), ".csv") {
+		return "", fmt.Errorf("%w: %s", ErrUnknownDataset, name)
+	}
+
+	path := filepath.Join(filepath.Dir(a.dataPath), name)
+	if path == filepath.Clean(a.stationsPath) {
+		return "", fmt.Errorf("%w: %s", ErrUnknownDataset, name)
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: %s", ErrUnknownDataset, name)
+	}
+
+	return path, nil
+}
+
+// computeBranchesForRun - кластеризует ветки для набора данных и временного окна
+func (a *algorithmService) computeBranchesForRun(run domain.BranchRun) (map[string][]domain.ImprovedBranch, error) {
+	dataPath := run.DataPath
+	if dataPath == "" {
+		dataPath = a.dataPath
+	}
+
+	locomotives, err := readData(dataPath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать %s: %w", dataPath, err)
+	}
+
+	for key, loc := range locomotives {
+		// Поездки относим к окну по времени начала
+		var trips []domain.Trip
+		for _, trip := range splitIntoTrips(loc.Records) {
+			if run.Contains(trip.StartTime) {
+				trips = append(trips, trip)
+			}
+		}
+		loc.Trips = trips
+		locomotives[key] = loc
+	}
+
+	return a.analyzeBranchesImproved(locomotives), nil
+}
+
+// diffBranches - сопоставляет ветки двух прогонов по пересечению станций
+func (a *algorithmService) diffBranches(base, target map[string][]domain.ImprovedBranch) *responses.BranchDiff {
+	diff := &responses.BranchDiff{
+		Depots: make([]responses.DepotBranchDiff, 0),
+	}
+
+	depoSet := make(map[string]bool)
+	for depo := range base {
+		depoSet[depo] = true
+	}
+	for depo := range target {
+		depoSet[depo] = true
+	}
+
+	depots := make([]string, 0, len(depoSet))
+	for depo := range depoSet {
+		depots = append(depots, depo)
+	}
+	sort.Strings(depots)
+
+	for _, depo := range depots {
+		depotDiff := a.diffDepotBranches(depo, base[depo], target[depo])
+
+		diff.Summary.Added += len(depotDiff.Added)
+		diff.Summary.Removed += len(depotDiff.Removed)
+		diff.Summary.Modified += len(depotDiff.Modified)
+		diff.Summary.Unchanged += depotDiff.Unchanged
+
+		if len(depotDiff.Added) == 0 && len(depotDiff.Removed) == 0 && len(depotDiff.Modified) == 0 {
+			continue
+		}
+		diff.Depots = append(diff.Depots, depotDiff)
+	}
+
+	return diff
+}
+
+// diffDepotBranches - жадно сопоставляет ветки депо по убыванию схожести
+func (a *algorithmService) diffDepotBranches(depo string, base, target []domain.ImprovedBranch) responses.DepotBranchDiff {
+	depotDiff := responses.DepotBranchDiff{
+		DepoCode: depo,
+		DepoName: a.getStationName(depo),
+		Added:    make([]responses.BranchSummary, 0),
+		Removed:  make([]responses.BranchSummary, 0),
+		Modified: make([]responses.BranchChange, 0),
+	}
+
+	type candidate struct {
+		baseIdx, targetIdx int
+		overlap            float64
+	}
+
+	var candidates []candidate
+	for i := range base {
+		for j := range target {
+			overlap := stationSetSimilarity(base[i].CoreStations, target[j].CoreStations)
+			if overlap >= branchMatchThreshold {
+				candidates = append(candidates, candidate{i, j, overlap})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].overlap == candidates[j].overlap {
+			if candidates[i].baseIdx == candidates[j].baseIdx {
+				return candidates[i].targetIdx < candidates[j].targetIdx
+			}
+			return candidates[i].baseIdx < candidates[j].baseIdx
+		}
+		return candidates[i].overlap > candidates[j].overlap
+	})
+
+	baseMatched := make(map[int]bool)
+	targetMatched := make(map[int]bool)
+
+	for _, c := range candidates {
+		if baseMatched[c.baseIdx] || targetMatched[c.targetIdx] {
+			continue
+		}
+		baseMatched[c.baseIdx] = true
+		targetMatched[c.targetIdx] = true
+
+		change := a.buildBranchChange(base[c.baseIdx], target[c.targetIdx], c.overlap)
+		if len(change.AddedStations) == 0 && len(change.RemovedStations) == 0 &&
+			len(change.AddedTerminals) == 0 && len(change.RemovedTerminals) == 0 {
+			depotDiff.Unchanged++
+			continue
+		}
+		depotDiff.Modified = append(depotDiff.Modified, change)
+	}
+
+	for i, branch := range base {
+		if !baseMatched[i] {
+			depotDiff.Removed = append(depotDiff.Removed, a.buildBranchSummary(branch))
+		}
+	}
+	for j, branch := range target {
+		if !targetMatched[j] {
+			depotDiff.Added = append(depotDiff.Added, a.buildBranchSummary(branch))
+		}
+	}
+
+	return depotDiff
+}
+
+// buildBranchChange - описывает изменения между сопоставленными ветками
+func (a *algorithmService) buildBranchChange(base, target domain.ImprovedBranch, overlap float64) responses.BranchChange {
+	baseTerminals := make([]string, 0, len(base.Terminals))
+	for t := range base.Terminals {
+		baseTerminals = append(baseTerminals, t)
+	}
+	targetTerminals := make([]string, 0, len(target.Terminals))
+	for t := range target.Terminals {
+		targetTerminals = append(targetTerminals, t)
+	}
+
+	return responses.BranchChange{
+		BaseBranchID:     base.BranchID,
+		TargetBranchID:   target.BranchID,
+		Overlap:          overlap,
+		AddedStations:    a.convertStationsToNames(stationsDifference(target.CoreStations, base.CoreStations)),
+		RemovedStations:  a.convertStationsToNames(stationsDifference(base.CoreStations, target.CoreStations)),
+		AddedTerminals:   a.convertStationsToNames(stationsDifference(targetTerminals, baseTerminals)),
+		RemovedTerminals: a.convertStationsToNames(stationsDifference(baseTerminals, targetTerminals)),
+		BaseTrips:        len(base.AllPaths),
+		TargetTrips:      len(target.AllPaths),
+	}
+}
+
+// buildBranchSummary - краткое описание ветки (с названиями)
+func (a *algorithmService) buildBranchSummary(branch domain.ImprovedBranch) responses.BranchSummary {
+	terminals := make([]string, 0, len(branch.Terminals))
+	for _, t := range a.buildTerminalInfos(branch.Terminals, 0) {
+		terminals = append(terminals, t.Station)
+	}
+
+	return responses.BranchSummary{
+		BranchID:     branch.BranchID,
+		CoreStations: a.convertStationsToNames(branch.CoreStations),
+		Terminals:    terminals,
+		Trips:        len(branch.AllPaths),
+	}
+}
+
+// buildBranchRunInfo - описание прогона для ответа
+func (a *algorithmService) buildBranchRunInfo(run domain.BranchRun, branches map[string][]domain.ImprovedBranch) responses.BranchRunInfo {
+	info := responses.BranchRunInfo{
+		Source: run.DataPath,
+	}
+	if run.Dataset != "" {
+		info.Source = run.Dataset
+	} else if info.Source == "" {
+		info.Source = a.dataPath
+	}
+	if !run.From.IsZero() {
+		info.From = run.From.Format(time.RFC3339)
+	}
+	if !run.To.IsZero() {
+		info.To = run.To.Format(time.RFC3339)
+	}
+	for _, b := range branches {
+		info.TotalBranches += len(b)
+	}
+	return info
+}
+
+// stationsDifference - станции из a, которых нет в b (по алфавиту)
+func stationsDifference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+
+	result := make([]string, 0)
+	for _, s := range a {
+		if !inB[s] {
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// printBranchDiff - выводит результат сравнения веток
+func (a *algorithmService) printBranchDiff(diff *responses.BranchDiff) {
+	fmt.Println("\n" + strings.Repeat("=", 80))
+	fmt.Println("СРАВНЕНИЕ ВЕТОК")
+	fmt.Println(strings.Repeat("=", 80))
+
+	printRun := func(label string, info responses.BranchRunInfo) {
+		fmt.Printf("%s: %s", label, info.Source)
+		if info.From != "" || info.To != "" {
+			fmt.Printf(" [%s .. %s)", info.From, info.To)
+		}
+		fmt.Printf(", веток: %d\n", info.TotalBranches)
+	}
+	printRun("Базовый прогон", diff.Base)
+	printRun("Сравниваемый прогон", diff.Target)
+
+	for _, depot := range diff.Depots {
+		fmt.Printf("\nДепо %s (%s):\n", depot.DepoName, depot.DepoCode)
+
+		for _, b := range depot.Added {
+			fmt.Printf("  + Новая ветка %s (%d поездок): %s\n",
+				b.BranchID, b.Trips, strings.Join(b.CoreStations, " → "))
+		}
+		for _, b := range depot.Removed {
+			fmt.Printf("  - Исчезла ветка %s (%d поездок): %s\n",
+				b.BranchID, b.Trips, strings.Join(b.CoreStations, " → "))
+		}
+		for _, c := range depot.Modified {
+			fmt.Printf("  ~ Изменена ветка %s → %s (схожесть %.2f, поездок %d → %d)\n",
+				c.BaseBranchID, c.TargetBranchID, c.Overlap, c.BaseTrips, c.TargetTrips)
+			if len(c.AddedStations) > 0 {
+				fmt.Printf("      добавлены станции: %s\n", strings.Join(c.AddedStations, ", "))
+			}
+			if len(c.RemovedStations) > 0 {
+				fmt.Printf("      убраны станции: %s\n", strings.Join(c.RemovedStations, ", "))
+			}
+			if len(c.AddedTerminals) > 0 {
+				fmt.Printf("      новые конечные: %s\n", strings.Join(c.AddedTerminals, ", "))
+			}
+			if len(c.RemovedTerminals) > 0 {
+				fmt.Printf("      пропавшие конечные: %s\n", strings.Join(c.RemovedTerminals, ", "))
+			}
+		}
+		if depot.Unchanged > 0 {
+			fmt.Printf("  = Без изменений: %d\n", depot.Unchanged)
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 80))
+	fmt.Printf("Добавлено: %d, удалено: %d, изменено: %d, без изменений: %d\n",
+		diff.Summary.Added, diff.Summary.Removed, diff.Summary.Modified, diff.Summary.Unchanged)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+// testBranch - ветка с основными станциями и конечной (по одной поездке)
+func testBranch(id, terminal string, stations ...string) domain.ImprovedBranch {
+	return domain.ImprovedBranch{
+		BranchID:     id,
+		CoreStations: stations,
+		AllPaths:     [][]string{stations},
+		Terminals:    map[string]int{terminal: 1},
+	}
+}
+
+func TestDiffDepotBranches(t *testing.T) {
+	type change struct {
+		base, target     string
+		added, removed   []string
+		addedTerminals   []string
+		removedTerminals []string
+	}
+
+	tests := []struct {
+		name      string
+		base      []domain.ImprovedBranch
+		target    []domain.ImprovedBranch
+		added     []string
+		removed   []string
+		modified  []change
+		unchanged int
+	}{
+		{
+			name:      "одинаковые ветки",
+			base:      []domain.ImprovedBranch{testBranch("b1", "C", "A", "B", "C")},
+			target:    []domain.ImprovedBranch{testBranch("t1", "C", "A", "B", "C")},
+			unchanged: 1,
+		},
+		{
+			name:   "станции ветки изменились",
+			base:   []domain.ImprovedBranch{testBranch("b1", "C", "A", "B", "C", "D")},
+			target: []domain.ImprovedBranch{testBranch("t1", "C", "E", "A", "B", "C")},
+			modified: []change{{
+				base: "b1", target: "t1", added: []string{"E"}, removed: []string{"D"},
+			}},
+		},
+		{
+			name:   "сменилась конечная станция",
+			base:   []domain.ImprovedBranch{testBranch("b1", "C", "A", "B", "C")},
+			target: []domain.ImprovedBranch{testBranch("t1", "B", "A", "B", "C")},
+			modified: []change{{
+				base: "b1", target: "t1", addedTerminals: []string{"B"}, removedTerminals: []string{"C"},
+			}},
+		},
+		{
+			name:    "ветки без общих станций - исчезла и появилась",
+			base:    []domain.ImprovedBranch{testBranch("b1", "B", "A", "B")},
+			target:  []domain.ImprovedBranch{testBranch("t1", "D", "C", "D")},
+			added:   []string{"t1"},
+			removed: []string{"b1"},
+		},
+		{
+			name: "сопоставляется самая похожая пара",
+			base: []domain.ImprovedBranch{
+				testBranch("b1", "C", "A", "B", "C"),
+				testBranch("b2", "D", "A", "B", "C", "D"),
+			},
+			target:    []domain.ImprovedBranch{testBranch("t1", "D", "A", "B", "C", "D")},
+			removed:   []string{"b1"},
+			unchanged: 1,
+		},
+		{
+			name:   "депо есть только в новом прогоне",
+			target: []domain.ImprovedBranch{testBranch("t1", "B", "A", "B"), testBranch("t2", "C", "A", "C")},
+			added:  []string{"t1", "t2"},
+		},
+	}
+
+	a := &algorithmService{}
+	ids := func(branches []string) []string {
+		if branches == nil {
+			return []string{}
+		}
+		return branches
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := a.diffDepotBranches("D1", tt.base, tt.target)
+
+			if diff.DepoCode != "D1" || diff.Unchanged != tt.unchanged {
+				t.Errorf("депо %s, без изменений %d; ожидалось D1, %d", diff.DepoCode, diff.Unchanged, tt.unchanged)
+			}
+
+			added := make([]string, 0)
+			for _, b := range diff.Added {
+				added = append(added, b.BranchID)
+			}
+			removed := make([]string, 0)
+			for _, b := range diff.Removed {
+				removed = append(removed, b.BranchID)
+			}
+			if !reflect.DeepEqual(added, ids(tt.added)) || !reflect.DeepEqual(removed, ids(tt.removed)) {
+				t.Errorf("добавлены %v, удалены %v; ожидалось %v, %v", added, removed, tt.added, tt.removed)
+			}
+
+			if len(diff.Modified) != len(tt.modified) {
+				t.Fatalf("изменено %d веток, ожидалось %d", len(diff.Modified), len(tt.modified))
+			}
+			for i, want := range tt.modified {
+				got := diff.Modified[i]
+				if got.BaseBranchID != want.base || got.TargetBranchID != want.target ||
+					!reflect.DeepEqual(got.AddedStations, ids(want.added)) ||
+					!reflect.DeepEqual(got.RemovedStations, ids(want.removed)) ||
+					!reflect.DeepEqual(got.AddedTerminals, ids(want.addedTerminals)) ||
+					!reflect.DeepEqual(got.RemovedTerminals, ids(want.removedTerminals)) {
+					t.Errorf("изменение %d = %+v, ожидалось %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStationsDifference(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{name: "результат по алфавиту", a: []string{"D", "A", "C", "B"}, b: []string{"C"}, want: []string{"A", "B", "D"}},
+		{name: "все станции есть во втором списке", a: []string{"A", "B"}, b: []string{"B", "A", "C"}, want: []string{}},
+		{name: "пустой второй список", a: []string{"B", "A"}, want: []string{"A", "B"}},
+		{name: "пустой первый список", b: []string{"A"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stationsDifference(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stationsDifference(%v, %v) = %v, ожидалось %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveDataset(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.csv", "old.csv", "station_info.csv", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dir.csv"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &algorithmService{
+		dataPath:     filepath.Join(dir, "main.csv"),
+		stationsPath: filepath.Join(dir, "station_info.csv"),
+	}
+
+	tests := []struct {
+		name    string
+		dataset string
+		want    string
+	}{
+		{name: "CSV в директории данных", dataset: "old.csv", want: filepath.Join(dir, "old.csv")},
+		{name: "основной набор данных", dataset: "main.csv", want: filepath.Join(dir, "main.csv")},
+		{name: "справочник станций", dataset: "station_info.csv"},
+		{name: "не CSV", dataset: "notes.txt"},
+		{name: "файла нет", dataset: "missing.csv"},
+		{name: "директория", dataset: "dir.csv"},
+		{name: "путь с подкаталогом", dataset: "../main.csv"},
+		{name: "абсолютный путь", dataset: filepath.Join(dir, "old.csv")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.resolveDataset(tt.dataset)
+			if tt.want == "" {
+				if !errors.Is(err, ErrUnknownDataset) {
+					t.Errorf("resolveDataset(%q) = %q, %v; ожидалась ErrUnknownDataset", tt.dataset, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveDataset(%q) = %q, %v; ожидалось %q", tt.dataset, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+// JSBranch структура для передачи ветки депо в JavaScript
+type JSBranch struct {
+	ID        string       `json:"id"`
+	Color     string       `json:"color"`
+	Trips     int          `json:"trips"`
+	Points    [][]float64  `json:"points"`
+	Stations  []string     `json:"stations"`
+	Terminals []JSTerminal `json:"terminals"`
+}
+
+// JSTerminal структура для передачи конечной станции ветки в JavaScript
+type JSTerminal struct {
+	ID     string    `json:"id"`
+	Name   string    `json:"name"`
+	Coords []float64 `json:"coords"`
+	Visits int       `json:"visits"`
+	Size   float64   `json:"size"`
+}
+
+// generateBranchesHTMLAPI создает карту веток депо (задача 1) в директории задания (/maps/{job_id}/)
+func (v *visualizationService) generateBranchesHTMLAPI(
+	depoID string,
+	branches []domain.ImprovedBranch,
+	stations map[string]domain.Station,
+	page mapPage) (string, error) {
+
+	// Ветки рисуем от самой используемой к наименее используемой
+	sort.Slice(branches, func(i, j int) bool {
+		if len(branches[i].AllPaths) == len(branches[j].AllPaths) {
+			return branches[i].BranchID < branches[j].BranchID
+		}
+		return len(branches[i].AllPaths) > len(branches[j].AllPaths)
+	})
+
+	colors := []string{"#e6194b", "#3cb44b", "#4363d8", "#f58231", "#911eb4", "#46f0f0",
+		"#f032e6", "#bcf60c", "#008080", "#9a6324", "#800000", "#000075"}
+
+	// Максимальное число заездов на конечную для масштабирования маркеров
+	maxVisits := 0
+	for _, b := range branches {
+		for _, visits := range b.Terminals {
+			if visits > maxVisits {
+				maxVisits = visits
+			}
+		}
+	}
+
+	jsBranches := []JSBranch{}
+	var bounds []JSStation
+	for i, b := range branches {
+		branch := JSBranch{
+			ID:        b.BranchID,
+			Color:     colors[i%len(colors)],
+			Trips:     len(b.AllPaths),
+			Points:    [][]float64{},
+			Stations:  []string{},
+			Terminals: []JSTerminal{},
+		}
+
+		// Ветка начинается в депо
+		path := append([]string{depoID}, b.CoreStations...)
+		for _, stationID := range path {
+			station, exists := stations[stationID]
+			if !exists {
+				continue
+			}
+			branch.Points = append(branch.Points, []float64{station.Longitude, station.Latitude})
+			branch.Stations = append(branch.Stations, station.Name)
+			bounds = append(bounds, JSStation{Coords: []float64{station.Longitude, station.Latitude}})
+		}
+
+		for terminalID, visits := range b.Terminals {
+			station, exists := stations[terminalID]
+			if !exists {
+				continue
+			}
+			size := 6.0
+			if maxVisits > 0 {
+				size += 18 * math.Sqrt(float64(visits)/float64(maxVisits))
+			}
+			branch.Terminals = append(branch.Terminals, JSTerminal{
+				ID:     terminalID,
+				Name:   station.Name,
+				Coords: []float64{station.Longitude, station.Latitude},
+				Visits: visits,
+				Size:   size,
+			})
+		}
+		sort.Slice(branch.Terminals, func(i, j int) bool {
+			return branch.Terminals[i].Visits > branch.Terminals[j].Visits
+		})
+
+		jsBranches = append(jsBranches, branch)
+	}
+
+	minLat, maxLat, minLon, maxLon := v.calculateBounds(bounds, stations, depoID)
+	latPadding := (maxLat - minLat) * 0.2
+	lonPadding := (maxLon - minLon) * 0.2
+
+	data := branchesMapData{
+		mapPage: page,
+		DepoID:  depoID,
+		Region:  getRegionByDepo(depoID),
+		Bounds: [][]float64{
+			{minLat - latPadding, minLon - lonPadding},
+			{maxLat + latPadding, maxLon + lonPadding},
+		},
+		Branches: jsBranches,
+	}
+
+	return v.renderMap(page.job, "branches.html", fmt.Sprintf("depot_%s_branches.html", depoID), data)
+}
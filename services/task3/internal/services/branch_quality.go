@@ -0,0 +1,193 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+// maxCohesionVariants ограничивает число уникальных вариантов пути,
+// которые сравниваются попарно при расчете связности ветки (берутся самые частые)
+const maxCohesionVariants = 200
+
+// stationSetSimilarity - коэффициент Жаккара для двух наборов станций (0..1)
+func stationSetSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	setA := make(map[string]bool, len(a))
+	for _, s := range a {
+		setA[s] = true
+	}
+
+	setB := make(map[string]bool, len(b))
+	for _, s := range b {
+		setB[s] = true
+	}
+
+	intersection := 0
+	for s := range setA {
+		if setB[s] {
+			intersection++
+		}
+	}
+
+	union := len(setA) + len(setB) - intersection
+	if union == 0 {
+		return 0
+	}
+
+	return float64(intersection) / float64(union)
+}
+
+// evaluateBranchQuality - рассчитывает метрики качества для всех веток депо
+func evaluateBranchQuality(branches []domain.ImprovedBranch, depoID string) {
+	for i := range branches {
+		branches[i].Quality = domain.BranchQuality{
+			Cohesion:   branchCohesion(branches[i], depoID),
+			Separation: branchSeparation(branches, i),
+			Coverage:   branchCoverage(branches[i], depoID),
+		}
+	}
+}
+
+// branchCohesion - средняя попарная схожесть путей внутри ветки.
+// Одинаковые пути схлопываются в варианты с весами, чтобы не сравнивать
+// тысячи повторяющихся поездок между собой. Если вариантов больше
+// maxCohesionVariants, учитываются самые частые (при равенстве - по пути),
+// так что результат не зависит от порядка путей.
+func branchCohesion(branch domain.ImprovedBranch, depoID string) float64 {
+	type variant struct {
+		key   string
+		core  []string
+		count int
+	}
+
+	var variants []variant
+	index := make(map[string]int)
+
+	for _, path := range branch.AllPaths {
+		core := extractCorePath(path, depoID)
+		key := strings.Join(core, ",")
+		if idx, exists := index[key]; exists {
+			variants[idx].count++
+			continue
+		}
+		index[key] = len(variants)
+		variants = append(variants, variant{key: key, core: core, count: 1})
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		if variants[i].count == variants[j].count {
+			return variants[i].key < variants[j].key
+		}
+		return variants[i].count > variants[j].count
+	})
+	if len(variants) > maxCohesionVariants {
+		variants = variants[:maxCohesionVariants]
+	}
+
+	total := 0
+	for _, v := range variants {
+		total += v.count
+	}
+	if total < 2 {
+		return 1
+	}
+
+	var sum, pairs float64
+	for i := range variants {
+		// Пары внутри одного варианта полностью совпадают
+		same := float64(variants[i].count) * float64(variants[i].count-1) / 2
+		sum += same
+		pairs += same
+
+		for j := i + 1; j < len(variants); j++ {
+			weight := float64(variants[i].count) * float64(variants[j].count)
+			sum += weight * stationSetSimilarity(variants[i].core, variants[j].core)
+			pairs += weight
+		}
+	}
+
+	if pairs == 0 {
+		return 1
+	}
+	return sum / pairs
+}
+
+// branchSeparation - насколько ветка отличается от ближайшей другой ветки депо.
+// Если других веток нет, отделимость не определена (nil).
+func branchSeparation(branches []domain.ImprovedBranch, idx int) *float64 {
+	if len(branches) < 2 {
+		return nil
+	}
+
+	maxSimilarity := 0.0
+	for j := range branches {
+		if j == idx {
+			continue
+		}
+		similarity := stationSetSimilarity(branches[idx].CoreStations, branches[j].CoreStations)
+		if similarity > maxSimilarity {
+			maxSimilarity = similarity
+		}
+	}
+	separation := 1 - maxSimilarity
+	return &separation
+}
+
+// branchCoverage - доля путей, все станции которых входят в CoreStations
+func branchCoverage(branch domain.ImprovedBranch, depoID string) float64 {
+	if len(branch.AllPaths) == 0 {
+		return 0
+	}
+
+	coreSet := make(map[string]bool, len(branch.CoreStations))
+	for _, s := range branch.CoreStations {
+		coreSet[s] = true
+	}
+
+	covered := 0
+	for _, path := range branch.AllPaths {
+		full := true
+		for _, s := range path {
+			if s != depoID && !coreSet[s] {
+				full = false
+				break
+			}
+		}
+		if full {
+			covered++
+		}
+	}
+
+	return float64(covered) / float64(len(branch.AllPaths))
+}
+
+// depotQualityScore - сводная оценка кластеризации депо (0..1).
+// Среднее по веткам от (связность + отделимость + покрытие) / 3,
+// взвешенное по количеству путей в ветке. У депо с одной веткой
+// отделимость не учитывается: (связность + покрытие) / 2.
+func depotQualityScore(branches []domain.ImprovedBranch) float64 {
+	var sum, weight float64
+	for _, b := range branches {
+		w := float64(len(b.AllPaths))
+		if w == 0 {
+			continue
+		}
+		q := b.Quality
+		if q.Separation == nil {
+			sum += w * (q.Cohesion + q.Coverage) / 2
+		} else {
+			sum += w * (q.Cohesion + *q.Separation + q.Coverage) / 3
+		}
+		weight += w
+	}
+
+	if weight == 0 {
+		return 0
+	}
+	return sum / weight
+}
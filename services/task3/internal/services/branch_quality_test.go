@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+func TestBranchCohesion(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths [][]string
+		want  float64
+	}{
+		{name: "один путь", paths: [][]string{{"D", "A", "B", "D"}}, want: 1},
+		{name: "одинаковые пути", paths: [][]string{{"D", "A", "B", "D"}, {"D", "A", "B"}, {"D", "A", "B", "A", "D"}}, want: 1},
+		{
+			// Пары: (1,2) = 1, (1,3) и (2,3) = 1/3 (Жаккар {A,B} и {A,C})
+			name:  "варианты с весами",
+			paths: [][]string{{"D", "A", "B", "D"}, {"D", "A", "B", "D"}, {"D", "A", "C", "D"}},
+			want:  (1 + 2.0/3) / 3,
+		},
+		{name: "нет общих станций", paths: [][]string{{"D", "A", "D"}, {"D", "B", "D"}}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := branchCohesion(domain.ImprovedBranch{AllPaths: tt.paths}, "D")
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("branchCohesion = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBranchCohesionDeterministic(t *testing.T) {
+	// Вариантов больше maxCohesionVariants: частые варианты в начале списка
+	// не должны давать другой результат, чем в конце
+	var paths [][]string
+	for i := 0; i < maxCohesionVariants+50; i++ {
+		paths = append(paths, []string{"D", "A", fmt.Sprintf("S%d", i), "D"})
+	}
+	for i := 0; i < 30; i++ {
+		paths = append(paths, []string{"D", "A", "B", "D"}, []string{"D", "A", "C", "D"})
+	}
+
+	want := branchCohesion(domain.ImprovedBranch{AllPaths: paths}, "D")
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 5; i++ {
+		rng.Shuffle(len(paths), func(a, b int) { paths[a], paths[b] = paths[b], paths[a] })
+		if got := branchCohesion(domain.ImprovedBranch{AllPaths: paths}, "D"); got != want {
+			t.Fatalf("связность зависит от порядка путей: %v и %v", got, want)
+		}
+	}
+}
+
+func TestBranchSeparation(t *testing.T) {
+	branch := func(stations ...string) domain.ImprovedBranch {
+		return domain.ImprovedBranch{CoreStations: stations}
+	}
+
+	tests := []struct {
+		name     string
+		branches []domain.ImprovedBranch
+		want     float64 // -1 - отделимость не определена
+	}{
+		{name: "одна ветка", branches: []domain.ImprovedBranch{branch("A", "B")}, want: -1},
+		{name: "без общих станций", branches: []domain.ImprovedBranch{branch("A", "B"), branch("C", "D")}, want: 1},
+		{
+			name:     "ближайшая из нескольких веток",
+			branches: []domain.ImprovedBranch{branch("A", "B", "C"), branch("A", "B", "D"), branch("E")},
+			want:     0.5,
+		},
+		{name: "одинаковые ветки", branches: []domain.ImprovedBranch{branch("A"), branch("A")}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := branchSeparation(tt.branches, 0)
+			if tt.want < 0 {
+				if got != nil {
+					t.Errorf("branchSeparation = %v, ожидалось nil", *got)
+				}
+				return
+			}
+			if got == nil || math.Abs(*got-tt.want) > 1e-9 {
+				t.Errorf("branchSeparation = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDepotQualityScore(t *testing.T) {
+	branch := func(paths int, q domain.BranchQuality) domain.ImprovedBranch {
+		return domain.ImprovedBranch{AllPaths: make([][]string, paths), Quality: q}
+	}
+	separation := func(v float64) *float64 {
+		return &v
+	}
+
+	tests := []struct {
+		name     string
+		branches []domain.ImprovedBranch
+		want     float64
+	}{
+		{
+			name:     "одна ветка - без отделимости",
+			branches: []domain.ImprovedBranch{branch(3, domain.BranchQuality{Cohesion: 1, Coverage: 0.5})},
+			want:     0.75,
+		},
+		{
+			name: "среднее, взвешенное по путям",
+			branches: []domain.ImprovedBranch{
+				branch(3, domain.BranchQuality{Cohesion: 1, Separation: separation(1), Coverage: 1}),
+				branch(1, domain.BranchQuality{Cohesion: 0, Separation: separation(0.6), Coverage: 0.3}),
+			},
+			want: (3 + 0.3) / 4,
+		},
+		{
+			// Отделимость 0 (ветка совпадает с другой) учитывается, в отличие от одной ветки в депо
+			name:     "ветка совпадает с другой",
+			branches: []domain.ImprovedBranch{branch(2, domain.BranchQuality{Cohesion: 1, Separation: separation(0), Coverage: 0.5})},
+			want:     0.5,
+		},
+		{name: "нет путей", branches: []domain.ImprovedBranch{branch(0, domain.BranchQuality{Cohesion: 1})}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := depotQualityScore(tt.branches); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("depotQualityScore = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
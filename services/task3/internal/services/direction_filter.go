@@ -0,0 +1,152 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// defaultLocomotiveSort - сортировка локомотивов по умолчанию (по убыванию поездок)
+const defaultLocomotiveSort = "-trips"
+
+// filterLocomotiveStats - оставляет локомотивы, подходящие под фильтр
+func filterLocomotiveStats(
+	stats map[string]domain.LocomotiveDirectionStats,
+	filter domain.PopularDirectionFilter) map[string]domain.LocomotiveDirectionStats {
+
+	result := make(map[string]domain.LocomotiveDirectionStats, len(stats))
+	for key, stat := range stats {
+		if filter.Depo != "" && stat.Depo != filter.Depo {
+			continue
+		}
+		if filter.Series != "" && !strings.EqualFold(stat.Model, filter.Series) {
+			continue
+		}
+		if stat.TotalTrips < filter.MinTrips {
+			continue
+		}
+		if filter.Direction != "" && stat.DirectionVisits[filter.Direction] == 0 {
+			continue
+		}
+		result[key] = stat
+	}
+	return result
+}
+
+// paginateTask2Response - сортирует локомотивы всех депо вместе и оставляет только запрошенную страницу.
+// Локомотивы страницы группируются по депо в порядке общего списка (депо - по первому локомотиву
+// на странице), rank - место локомотива в общем списке.
+func paginateTask2Response(response *responses.Task2Response, filter domain.PopularDirectionFilter) {
+	sortKey := filter.Sort
+	if sortKey == "" {
+		sortKey = defaultLocomotiveSort
+	}
+
+	var locomotives []responses.LocomotiveStats
+	for _, depot := range response.Depots {
+		locomotives = append(locomotives, depot.Locomotives...)
+	}
+	sortLocomotives(locomotives, sortKey)
+	for i := range locomotives {
+		locomotives[i].Rank = i + 1
+	}
+	total := len(locomotives)
+
+	page := filter.Page
+	if page < 1 || filter.Limit <= 0 {
+		page = 1
+	}
+
+	response.Pagination = responses.Pagination{
+		Page:       page,
+		Limit:      filter.Limit,
+		Total:      total,
+		TotalPages: 1,
+		Sort:       sortKey,
+	}
+
+	if filter.Limit > 0 {
+		if pages := (total + filter.Limit - 1) / filter.Limit; pages > 1 {
+			response.Pagination.TotalPages = pages
+		}
+		start := min((page-1)*filter.Limit, total)
+		end := min(start+filter.Limit, total)
+		locomotives = locomotives[start:end]
+	}
+
+	depotIndex := make(map[string]int, len(response.Depots))
+	for i, depot := range response.Depots {
+		depotIndex[depot.DepoCode] = i
+	}
+
+	depots := make([]responses.DepotResponse, 0)
+	pageDepots := make(map[string]int)
+	for _, loco := range locomotives {
+		i, exists := pageDepots[loco.Depo]
+		if !exists {
+			depot := response.Depots[depotIndex[loco.Depo]]
+			depot.Locomotives = nil
+			depots = append(depots, depot)
+			i = len(depots) - 1
+			pageDepots[loco.Depo] = i
+		}
+		depots[i].Locomotives = append(depots[i].Locomotives, loco)
+	}
+	response.Depots = depots
+}
+
+// sortLocomotives - сортирует локомотивы по ключу (trips, series, number, share, entropy; "-" - по убыванию)
+func sortLocomotives(locomotives []responses.LocomotiveStats, sortKey string) {
+	desc := strings.HasPrefix(sortKey, "-")
+	field := strings.TrimPrefix(sortKey, "-")
+
+	favoriteShare := func(l responses.LocomotiveStats) float64 {
+		if l.MostPopular == nil {
+			return 0
+		}
+		return l.MostPopular.Percentage
+	}
+
+	compare := func(a, b responses.LocomotiveStats) int {
+		switch field {
+		case "series":
+			return strings.Compare(a.Model, b.Model)
+		case "number":
+			return strings.Compare(a.Number, b.Number)
+		case "share":
+			return compareFloat(favoriteShare(a), favoriteShare(b))
+		case "entropy":
+			return compareFloat(a.Significance.Entropy, b.Significance.Entropy)
+		default:
+			return a.TotalTrips - b.TotalTrips
+		}
+	}
+
+	sort.Slice(locomotives, func(i, j int) bool {
+		c := compare(locomotives[i], locomotives[j])
+		if c == 0 {
+			// Стабильный порядок: серия, затем номер
+			if locomotives[i].Model == locomotives[j].Model {
+				return locomotives[i].Number < locomotives[j].Number
+			}
+			return locomotives[i].Model < locomotives[j].Model
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// testTask2Response - два депо с локомотивами, отсортированными по номеру
+func testTask2Response() *responses.Task2Response {
+	locomotive := func(depo, number string, trips int) responses.LocomotiveStats {
+		return responses.LocomotiveStats{Model: "ТЭ", Number: number, Depo: depo, TotalTrips: trips}
+	}
+	return &responses.Task2Response{
+		Depots: []responses.DepotResponse{
+			{DepoCode: "1", LocomotiveCount: 3, Locomotives: []responses.LocomotiveStats{
+				locomotive("1", "11", 5), locomotive("1", "12", 1), locomotive("1", "13", 3),
+			}},
+			{DepoCode: "2", LocomotiveCount: 2, Locomotives: []responses.LocomotiveStats{
+				locomotive("2", "21", 9), locomotive("2", "22", 4),
+			}},
+		},
+	}
+}
+
+func TestPaginateTask2Response(t *testing.T) {
+	type depot struct {
+		code    string
+		numbers []string
+		ranks   []int
+	}
+
+	tests := []struct {
+		name       string
+		response   *responses.Task2Response
+		filter     domain.PopularDirectionFilter
+		depots     []depot
+		pagination responses.Pagination
+	}{
+		{
+			name:     "сортировка по всем депо, а не внутри депо",
+			response: testTask2Response(),
+			filter:   domain.PopularDirectionFilter{Limit: 2},
+			depots: []depot{
+				{code: "2", numbers: []string{"21"}, ranks: []int{1}},
+				{code: "1", numbers: []string{"11"}, ranks: []int{2}},
+			},
+			pagination: responses.Pagination{Page: 1, Limit: 2, Total: 5, TotalPages: 3, Sort: "-trips"},
+		},
+		{
+			name:     "вторая страница",
+			response: testTask2Response(),
+			filter:   domain.PopularDirectionFilter{Page: 2, Limit: 2},
+			depots: []depot{
+				{code: "2", numbers: []string{"22"}, ranks: []int{3}},
+				{code: "1", numbers: []string{"13"}, ranks: []int{4}},
+			},
+			pagination: responses.Pagination{Page: 2, Limit: 2, Total: 5, TotalPages: 3, Sort: "-trips"},
+		},
+		{
+			name:     "по возрастанию, локомотивы одного депо подряд на странице",
+			response: testTask2Response(),
+			filter:   domain.PopularDirectionFilter{Sort: "trips", Limit: 3},
+			depots: []depot{
+				{code: "1", numbers: []string{"12", "13"}, ranks: []int{1, 2}},
+				{code: "2", numbers: []string{"22"}, ranks: []int{3}},
+			},
+			pagination: responses.Pagination{Page: 1, Limit: 3, Total: 5, TotalPages: 2, Sort: "trips"},
+		},
+		{
+			name:       "страница за концом списка пустая",
+			response:   testTask2Response(),
+			filter:     domain.PopularDirectionFilter{Page: 9, Limit: 2},
+			depots:     []depot{},
+			pagination: responses.Pagination{Page: 9, Limit: 2, Total: 5, TotalPages: 3, Sort: "-trips"},
+		},
+		{
+			name:     "без лимита - все локомотивы на первой странице",
+			response: testTask2Response(),
+			filter:   domain.PopularDirectionFilter{Page: 3, Sort: "number"},
+			depots: []depot{
+				{code: "1", numbers: []string{"11", "12", "13"}, ranks: []int{1, 2, 3}},
+				{code: "2", numbers: []string{"21", "22"}, ranks: []int{4, 5}},
+			},
+			pagination: responses.Pagination{Page: 1, Total: 5, TotalPages: 1, Sort: "number"},
+		},
+		{
+			name:       "без локомотивов - одна пустая страница",
+			response:   &responses.Task2Response{},
+			filter:     domain.PopularDirectionFilter{Limit: 10},
+			depots:     []depot{},
+			pagination: responses.Pagination{Page: 1, Limit: 10, TotalPages: 1, Sort: "-trips"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paginateTask2Response(tt.response, tt.filter)
+
+			if tt.response.Pagination != tt.pagination {
+				t.Errorf("pagination = %+v, ожидалось %+v", tt.response.Pagination, tt.pagination)
+			}
+
+			got := make([]depot, 0)
+			for _, d := range tt.response.Depots {
+				page := depot{code: d.DepoCode}
+				for _, loco := range d.Locomotives {
+					page.numbers = append(page.numbers, loco.Number)
+					page.ranks = append(page.ranks, loco.Rank)
+				}
+				got = append(got, page)
+			}
+			if !reflect.DeepEqual(got, tt.depots) {
+				t.Errorf("депо = %+v, ожидалось %+v", got, tt.depots)
+			}
+		})
+	}
+}
+
+func TestPaginateTask2ResponseKeepsDepotAggregates(t *testing.T) {
+	response := testTask2Response()
+	paginateTask2Response(response, domain.PopularDirectionFilter{Limit: 1})
+
+	if len(response.Depots) != 1 || response.Depots[0].LocomotiveCount != 2 {
+		t.Errorf("locomotive_count депо на странице должен остаться общим: %+v", response.Depots)
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// GetLocomotiveDirectionHistory - для API режима (любимое направление локомотива по периодам)
+func (m *mostPopularTripService) GetLocomotiveDirectionHistory(
+	series, number, bucket string,
+	opts domain.DirectionOptions) (*responses.LocomotiveDirectionHistory, error) {
+
+	if bucket == "" {
+		bucket = "month"
+	}
+
+	locomotiveStats, depotDirections := m.computeDirectionStats(opts)
+
+	stats, exists := locomotiveStats[series+"_"+number]
+	if !exists {
+		return nil, nil
+	}
+
+	return m.buildDirectionHistory(stats, depotDirections[stats.Depo], bucket), nil
+}
+
+// buildDirectionHistory - разбивает поездки локомотива по периодам и находит смены любимого направления
+func (m *mostPopularTripService) buildDirectionHistory(
+	stats domain.LocomotiveDirectionStats,
+	directions []domain.Direction,
+	bucket string) *responses.LocomotiveDirectionHistory {
+
+	dirNames := make(map[string]string)
+	for _, dir := range directions {
+		dirNames[dir.ID] = dir.Name
+	}
+
+	type periodUsage struct {
+		trips     int
+		unmatched int
+		visits    map[string]int
+	}
+
+	periods := make(map[time.Time]*periodUsage)
+	for _, trip := range stats.Trips {
+		start := periodStart(trip.StartTime, bucket)
+		p, exists := periods[start]
+		if !exists {
+			p = &periodUsage{visits: make(map[string]int)}
+			periods[start] = p
+		}
+		p.trips++
+		if trip.DirectionID == "" {
+			p.unmatched++
+			continue
+		}
+		p.visits[trip.DirectionID]++
+	}
+
+	starts := make([]time.Time, 0, len(periods))
+	for start := range periods {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+
+	history := &responses.LocomotiveDirectionHistory{
+		Model:    stats.Model,
+		Number:   stats.Number,
+		Depo:     stats.Depo,
+		DepoName: stats.DepoName,
+		Bucket:   bucket,
+		Periods:  make([]responses.DirectionPeriod, 0, len(starts)),
+		Switches: make([]responses.DirectionSwitch, 0),
+	}
+
+	var previous *responses.PeriodDirection
+	for _, start := range starts {
+		p := periods[start]
+		period := responses.DirectionPeriod{
+			PeriodStart:    start.Format("2006-01-02"),
+			Trips:          p.trips,
+			UnmatchedTrips: p.unmatched,
+			Directions:     make([]responses.PeriodDirection, 0, len(p.visits)),
+		}
+
+		for dirID, visits := range p.visits {
+			period.Directions = append(period.Directions, responses.PeriodDirection{
+				ID:     dirID,
+				Name:   dirNames[dirID],
+				Visits: visits,
+				Share:  float64(visits) / float64(p.trips) * 100,
+			})
+		}
+
+		// Сортируем по количеству поездок, первое - любимое направление периода
+		sort.Slice(period.Directions, func(i, j int) bool {
+			if period.Directions[i].Visits == period.Directions[j].Visits {
+				return period.Directions[i].ID < period.Directions[j].ID
+			}
+			return period.Directions[i].Visits > period.Directions[j].Visits
+		})
+
+		if len(period.Directions) > 0 {
+			favorite := period.Directions[0]
+			period.Favorite = &favorite
+
+			// Периоды без сопоставленных поездок не считаются сменой направления
+			if previous != nil && previous.ID != favorite.ID {
+				history.Switches = append(history.Switches, responses.DirectionSwitch{
+					PeriodStart: period.PeriodStart,
+					FromID:      previous.ID,
+					FromName:    previous.Name,
+					ToID:        favorite.ID,
+					ToName:      favorite.Name,
+					Share:       favorite.Share,
+				})
+			}
+			previous = &favorite
+		}
+
+		history.Periods = append(history.Periods, period)
+	}
+
+	return history
+}
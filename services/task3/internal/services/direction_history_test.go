@@ -0,0 +1,103 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// historyTrip - поездка в указанный день 2024 года по направлению ("" - не сопоставлена)
+func historyTrip(month time.Month, day int, directionID string) domain.Trip {
+	return domain.Trip{
+		StartTime:   time.Date(2024, month, day, 10, 0, 0, 0, time.UTC),
+		DirectionID: directionID,
+	}
+}
+
+func TestBuildDirectionHistory(t *testing.T) {
+	m := &mostPopularTripService{}
+	directions := []domain.Direction{{ID: "A", Name: "Депо → A"}, {ID: "B", Name: "Депо → B"}}
+
+	tests := []struct {
+		name      string
+		trips     []domain.Trip
+		favorites []string // любимое направление каждого периода, "" - нет сопоставленных поездок
+		switches  []responses.DirectionSwitch
+	}{
+		{
+			name: "смена направления со следующего месяца",
+			trips: []domain.Trip{
+				historyTrip(time.January, 5, "A"), historyTrip(time.January, 20, "A"), historyTrip(time.January, 25, "B"),
+				historyTrip(time.February, 3, "B"), historyTrip(time.February, 10, "B"), historyTrip(time.February, 11, "B"),
+				historyTrip(time.February, 12, "A"),
+			},
+			favorites: []string{"A", "B"},
+			switches: []responses.DirectionSwitch{
+				{PeriodStart: "2024-02-01", FromID: "A", FromName: "Депо → A", ToID: "B", ToName: "Депо → B", Share: 75},
+			},
+		},
+		{
+			name: "одно направление - смен нет",
+			trips: []domain.Trip{
+				historyTrip(time.January, 5, "A"), historyTrip(time.February, 5, "A"), historyTrip(time.March, 5, "A"),
+			},
+			favorites: []string{"A", "A", "A"},
+			switches:  []responses.DirectionSwitch{},
+		},
+		{
+			name: "период без сопоставленных поездок не прерывает направление",
+			trips: []domain.Trip{
+				historyTrip(time.January, 5, "A"), historyTrip(time.February, 5, ""), historyTrip(time.March, 5, "A"),
+			},
+			favorites: []string{"A", "", "A"},
+			switches:  []responses.DirectionSwitch{},
+		},
+		{
+			name: "смена после периода без сопоставленных поездок",
+			trips: []domain.Trip{
+				historyTrip(time.January, 5, "A"), historyTrip(time.February, 5, ""), historyTrip(time.March, 5, "B"),
+			},
+			favorites: []string{"A", "", "B"},
+			switches: []responses.DirectionSwitch{
+				{PeriodStart: "2024-03-01", FromID: "A", FromName: "Депо → A", ToID: "B", ToName: "Депо → B", Share: 100},
+			},
+		},
+		{
+			name: "при равенстве поездок побеждает меньший ID",
+			trips: []domain.Trip{
+				historyTrip(time.January, 5, "B"),
+				historyTrip(time.February, 5, "B"), historyTrip(time.February, 6, "A"),
+				historyTrip(time.March, 5, "A"), historyTrip(time.March, 6, "B"), historyTrip(time.March, 7, ""),
+			},
+			favorites: []string{"B", "A", "A"},
+			switches: []responses.DirectionSwitch{
+				{PeriodStart: "2024-02-01", FromID: "B", FromName: "Депо → B", ToID: "A", ToName: "Депо → A", Share: 50},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := domain.LocomotiveDirectionStats{Model: "ТЭ", Number: "1", Trips: tt.trips}
+			history := m.buildDirectionHistory(stats, directions, "month")
+
+			favorites := make([]string, 0, len(history.Periods))
+			for _, period := range history.Periods {
+				if period.Favorite == nil {
+					favorites = append(favorites, "")
+					continue
+				}
+				favorites = append(favorites, period.Favorite.ID)
+			}
+			if !reflect.DeepEqual(favorites, tt.favorites) {
+				t.Errorf("любимые направления %v, ожидалось %v", favorites, tt.favorites)
+			}
+			if !reflect.DeepEqual(history.Switches, tt.switches) {
+				t.Errorf("смены %+v, ожидалось %+v", history.Switches, tt.switches)
+			}
+		})
+	}
+}
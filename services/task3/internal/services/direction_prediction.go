@@ -0,0 +1,220 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// predictionPriorWeight - вес (в псевдонаблюдениях) более общей модели при
+// сглаживании переходов: чем меньше наблюдений у локомотива, тем ближе прогноз
+// к распределению по депо
+const predictionPriorWeight = 2.0
+
+// transitionCounts - матрица переходов: контекст (предыдущие направления) -> следующее направление -> количество
+type transitionCounts map[string]map[string]int
+
+func (t transitionCounts) add(context, next string) {
+	if _, exists := t[context]; !exists {
+		t[context] = make(map[string]int)
+	}
+	t[context][next]++
+}
+
+// directionTransitions - матрицы переходов первого и второго порядка
+type directionTransitions struct {
+	order1 transitionCounts
+	order2 transitionCounts
+}
+
+func newDirectionTransitions() *directionTransitions {
+	return &directionTransitions{
+		order1: make(transitionCounts),
+		order2: make(transitionCounts),
+	}
+}
+
+// addSequence - добавляет последовательность направлений в матрицы
+func (d *directionTransitions) addSequence(sequence []string) {
+	for i := 1; i < len(sequence); i++ {
+		d.order1.add(sequence[i-1], sequence[i])
+		if i >= 2 {
+			d.order2.add(sequence[i-2]+"|"+sequence[i-1], sequence[i])
+		}
+	}
+}
+
+// GetLocomotiveNextDirection - для API режима (вероятное следующее направление локомотива)
+func (m *mostPopularTripService) GetLocomotiveNextDirection(
+	series, number string,
+	order int,
+	opts domain.DirectionOptions) (*responses.NextDirectionPrediction, error) {
+
+	if order != 1 {
+		order = 2
+	}
+
+	locomotiveStats, depotDirections := m.computeDirectionStats(opts)
+
+	stats, exists := locomotiveStats[series+"_"+number]
+	if !exists {
+		return nil, nil
+	}
+
+	// Матрицы переходов депо строятся по всем его локомотивам
+	depotTransitions := newDirectionTransitions()
+	depotCounts := make(map[string]int)
+	for _, s := range locomotiveStats {
+		if s.Depo != stats.Depo {
+			continue
+		}
+		sequence := directionSequence(s.Trips)
+		depotTransitions.addSequence(sequence)
+		for _, dirID := range sequence {
+			depotCounts[dirID]++
+		}
+	}
+
+	sequence := directionSequence(stats.Trips)
+	locTransitions := newDirectionTransitions()
+	locTransitions.addSequence(sequence)
+
+	return m.buildNextDirectionPrediction(stats, depotDirections[stats.Depo], sequence, order,
+		depotCounts, depotTransitions, locTransitions), nil
+}
+
+// directionSequence - последовательность направлений поездок в порядке времени (без несопоставленных)
+func directionSequence(trips []domain.Trip) []string {
+	sorted := make([]domain.Trip, len(trips))
+	copy(sorted, trips)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime.Before(sorted[j].StartTime)
+	})
+
+	sequence := make([]string, 0, len(sorted))
+	for _, trip := range sorted {
+		if trip.DirectionID != "" {
+			sequence = append(sequence, trip.DirectionID)
+		}
+	}
+	return sequence
+}
+
+// smoothDistribution - распределение по наблюдениям, сглаженное к априорному распределению
+func smoothDistribution(counts map[string]int, prior map[string]float64, directions []string) map[string]float64 {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+
+	result := make(map[string]float64, len(directions))
+	for _, dirID := range directions {
+		result[dirID] = (float64(counts[dirID]) + predictionPriorWeight*prior[dirID]) /
+			(float64(total) + predictionPriorWeight)
+	}
+	return result
+}
+
+// buildNextDirectionPrediction - прогноз следующего направления.
+// Модели сглаживаются от общей к частной: частоты депо -> переходы депо
+// первого и второго порядка -> переходы локомотива первого и второго порядка.
+func (m *mostPopularTripService) buildNextDirectionPrediction(
+	stats domain.LocomotiveDirectionStats,
+	directions []domain.Direction,
+	sequence []string,
+	order int,
+	depotCounts map[string]int,
+	depotTransitions, locTransitions *directionTransitions) *responses.NextDirectionPrediction {
+
+	dirNames := make(map[string]string)
+	dirIDs := make([]string, 0, len(directions))
+	for _, dir := range directions {
+		dirNames[dir.ID] = dir.Name
+		dirIDs = append(dirIDs, dir.ID)
+	}
+
+	prediction := &responses.NextDirectionPrediction{
+		Model:          stats.Model,
+		Number:         stats.Number,
+		Depo:           stats.Depo,
+		DepoName:       stats.DepoName,
+		Order:          order,
+		HistoryLength:  len(sequence),
+		LastDirections: make([]responses.DirectionRef, 0, order),
+		Predictions:    make([]responses.DirectionProbability, 0, len(dirIDs)),
+	}
+
+	if len(dirIDs) == 0 {
+		return prediction
+	}
+
+	// Базовое распределение - частоты направлений депо со сглаживанием Лапласа
+	depotTotal := 0
+	for _, c := range depotCounts {
+		depotTotal += c
+	}
+	distribution := make(map[string]float64, len(dirIDs))
+	for _, dirID := range dirIDs {
+		distribution[dirID] = float64(depotCounts[dirID]+1) / float64(depotTotal+len(dirIDs))
+	}
+	prediction.Source = "depot_frequency"
+
+	var locObserved map[string]int
+	if len(sequence) > 0 {
+		last := sequence[len(sequence)-1]
+		distribution = smoothDistribution(depotTransitions.order1[last], distribution, dirIDs)
+		prediction.Source = "depot_order1"
+
+		context2 := ""
+		if order == 2 && len(sequence) >= 2 {
+			context2 = sequence[len(sequence)-2] + "|" + last
+			distribution = smoothDistribution(depotTransitions.order2[context2], distribution, dirIDs)
+			prediction.Source = "depot_order2"
+		}
+
+		if len(locTransitions.order1[last]) > 0 {
+			distribution = smoothDistribution(locTransitions.order1[last], distribution, dirIDs)
+			locObserved = locTransitions.order1[last]
+			prediction.Source = "locomotive_order1"
+		}
+		if context2 != "" && len(locTransitions.order2[context2]) > 0 {
+			distribution = smoothDistribution(locTransitions.order2[context2], distribution, dirIDs)
+			locObserved = locTransitions.order2[context2]
+			prediction.Source = "locomotive_order2"
+		}
+
+		start := len(sequence) - order
+		if start < 0 {
+			start = 0
+		}
+		for _, dirID := range sequence[start:] {
+			prediction.LastDirections = append(prediction.LastDirections, responses.DirectionRef{
+				ID:   dirID,
+				Name: dirNames[dirID],
+			})
+		}
+	}
+
+	for _, dirID := range dirIDs {
+		prediction.Predictions = append(prediction.Predictions, responses.DirectionProbability{
+			ID:           dirID,
+			Name:         dirNames[dirID],
+			Probability:  distribution[dirID],
+			Observations: locObserved[dirID],
+		})
+	}
+
+	// Сортируем по вероятности
+	sort.Slice(prediction.Predictions, func(i, j int) bool {
+		if prediction.Predictions[i].Probability == prediction.Predictions[j].Probability {
+			return prediction.Predictions[i].ID < prediction.Predictions[j].ID
+		}
+		return prediction.Predictions[i].Probability > prediction.Predictions[j].Probability
+	})
+
+	mostLikely := prediction.Predictions[0]
+	prediction.MostLikely = &mostLikely
+
+	return prediction
+}
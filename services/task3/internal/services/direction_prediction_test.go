@@ -0,0 +1,160 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+func TestBuildNextDirectionPrediction(t *testing.T) {
+	directions := []domain.Direction{{ID: "A", Name: "На A"}, {ID: "B", Name: "На B"}}
+
+	tests := []struct {
+		name        string
+		directions  []domain.Direction
+		depot       [][]string // последовательности направлений локомотивов депо
+		sequence    []string   // последовательность направлений локомотива
+		order       int
+		source      string
+		last        []string
+		probability map[string]float64
+	}{
+		{
+			name:        "без истории - частоты депо со сглаживанием Лапласа",
+			directions:  directions,
+			depot:       [][]string{{"A", "A", "A", "B"}},
+			order:       2,
+			source:      "depot_frequency",
+			last:        []string{},
+			probability: map[string]float64{"A": 4.0 / 6, "B": 2.0 / 6},
+		},
+		{
+			name:        "локомотив не выезжал после последнего направления - переходы депо",
+			directions:  directions,
+			depot:       [][]string{{"A", "B", "A", "B"}},
+			sequence:    []string{"A", "B"},
+			order:       1,
+			source:      "depot_order1",
+			last:        []string{"B"},
+			probability: map[string]float64{"A": 2.0 / 3, "B": 1.0 / 3},
+		},
+		{
+			// Депо: частоты 4/7, 3/7 -> порядок 1 {B:2}; локомотив: порядок 1 {B:1}
+			name:        "переходы локомотива первого порядка",
+			directions:  directions,
+			depot:       [][]string{{"A", "B", "A", "B", "A"}},
+			sequence:    []string{"B", "A", "B", "A"},
+			order:       1,
+			source:      "locomotive_order1",
+			last:        []string{"A"},
+			probability: map[string]float64{"A": 4.0 / 21, "B": 17.0 / 21},
+		},
+		{
+			// Депо: частоты 0.5/0.5 -> порядок 1 {A:1} -> порядок 2 {A:1};
+			// локомотив: порядок 1 {A:1} -> порядок 2 {A:1}
+			name:        "переходы локомотива второго порядка",
+			directions:  directions,
+			depot:       [][]string{{"A", "B", "A", "B"}},
+			sequence:    []string{"A", "B", "A", "B"},
+			order:       2,
+			source:      "locomotive_order2",
+			last:        []string{"A", "B"},
+			probability: map[string]float64{"A": 73.0 / 81, "B": 8.0 / 81},
+		},
+		{
+			name:        "короткая история - второй порядок недоступен",
+			directions:  directions,
+			depot:       [][]string{{"A", "B", "A", "B"}},
+			sequence:    []string{"B"},
+			order:       2,
+			source:      "depot_order1",
+			last:        []string{"B"},
+			probability: map[string]float64{"A": 2.0 / 3, "B": 1.0 / 3},
+		},
+		{
+			name:        "нет направлений депо",
+			sequence:    []string{"A"},
+			order:       1,
+			last:        []string{},
+			probability: map[string]float64{},
+		},
+	}
+
+	m := &mostPopularTripService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depotTransitions := newDirectionTransitions()
+			depotCounts := make(map[string]int)
+			for _, sequence := range tt.depot {
+				depotTransitions.addSequence(sequence)
+				for _, dirID := range sequence {
+					depotCounts[dirID]++
+				}
+			}
+			locTransitions := newDirectionTransitions()
+			locTransitions.addSequence(tt.sequence)
+
+			prediction := m.buildNextDirectionPrediction(domain.LocomotiveDirectionStats{Depo: "D"},
+				tt.directions, tt.sequence, tt.order, depotCounts, depotTransitions, locTransitions)
+
+			if prediction.Source != tt.source {
+				t.Errorf("source = %q, ожидалось %q", prediction.Source, tt.source)
+			}
+
+			last := make([]string, 0)
+			for _, ref := range prediction.LastDirections {
+				last = append(last, ref.ID)
+			}
+			if !reflect.DeepEqual(last, tt.last) {
+				t.Errorf("последние направления %v, ожидалось %v", last, tt.last)
+			}
+
+			if len(prediction.Predictions) != len(tt.probability) {
+				t.Fatalf("прогнозов %d, ожидалось %d", len(prediction.Predictions), len(tt.probability))
+			}
+			sum := 0.0
+			for i, p := range prediction.Predictions {
+				if math.Abs(p.Probability-tt.probability[p.ID]) > 1e-9 {
+					t.Errorf("P(%s) = %v, ожидалось %v", p.ID, p.Probability, tt.probability[p.ID])
+				}
+				if i > 0 && p.Probability > prediction.Predictions[i-1].Probability {
+					t.Errorf("прогнозы не отсортированы по вероятности: %+v", prediction.Predictions)
+				}
+				sum += p.Probability
+			}
+
+			if len(tt.probability) == 0 {
+				if prediction.MostLikely != nil {
+					t.Errorf("most_likely = %+v без направлений", prediction.MostLikely)
+				}
+				return
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Errorf("сумма вероятностей %v", sum)
+			}
+			if prediction.MostLikely == nil || prediction.MostLikely.ID != prediction.Predictions[0].ID {
+				t.Errorf("most_likely = %+v, ожидалось %s", prediction.MostLikely, prediction.Predictions[0].ID)
+			}
+		})
+	}
+}
+
+func TestDirectionSequence(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trips := []domain.Trip{
+		{StartTime: start.Add(3 * time.Hour), DirectionID: "C"},
+		{StartTime: start, DirectionID: "A"},
+		{StartTime: start.Add(2 * time.Hour)},
+		{StartTime: start.Add(time.Hour), DirectionID: "B"},
+	}
+
+	if got, want := directionSequence(trips), []string{"A", "B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("directionSequence = %v, ожидалось %v", got, want)
+	}
+	if trips[0].DirectionID != "C" {
+		t.Error("directionSequence изменил порядок исходных поездок")
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"math"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+// Метки любимого направления локомотива
+const (
+	favoriteStrong = "strong_favorite"
+	favoriteWeak   = "weak_favorite"
+	favoriteNone   = "no_favorite"
+)
+
+const (
+	// strongFavoritePValue - уровень значимости для сильного любимого направления
+	strongFavoritePValue = 0.01
+	// weakFavoritePValue - уровень значимости для слабого любимого направления
+	weakFavoritePValue = 0.05
+	// strongFavoriteShare - минимальная доля поездок по сильному любимому направлению
+	strongFavoriteShare = 0.5
+)
+
+// evaluateFavoriteSignificance - проверяет, отличается ли распределение поездок
+// локомотива по направлениям от базового (по депо или равномерного), считает
+// энтропию распределения и присваивает метку любимого направления
+func (m *mostPopularTripService) evaluateFavoriteSignificance(
+	stats map[string]domain.LocomotiveDirectionStats,
+	depotDirections map[string][]domain.Direction,
+	baseline string) {
+
+	if baseline == "" {
+		baseline = "depot"
+	}
+
+	// Распределение поездок по направлениям для каждого депо
+	depotVisits := make(map[string]map[string]int)
+	for _, stat := range stats {
+		if _, exists := depotVisits[stat.Depo]; !exists {
+			depotVisits[stat.Depo] = make(map[string]int)
+		}
+		for dirID, visits := range stat.DirectionVisits {
+			depotVisits[stat.Depo][dirID] += visits
+		}
+	}
+
+	for key, stat := range stats {
+		directions := depotDirections[stat.Depo]
+		expected := baselineDistribution(directions, depotVisits[stat.Depo], baseline)
+
+		matched := 0
+		for _, visits := range stat.DirectionVisits {
+			matched += visits
+		}
+
+		stat.Baseline = baseline
+		stat.Entropy = directionEntropy(stat.DirectionVisits)
+		if len(directions) > 1 {
+			stat.NormalizedEntropy = stat.Entropy / math.Log2(float64(len(directions)))
+		}
+
+		stat.ChiSquare, stat.DegreesOfFreedom = chiSquareStatistic(stat.DirectionVisits, directions, expected, matched)
+		stat.PValue = 1
+		if stat.DegreesOfFreedom > 0 {
+			stat.PValue = chiSquarePValue(stat.ChiSquare, stat.DegreesOfFreedom)
+		}
+
+		stat.FavoriteLabel = favoriteLabel(stat, expected, matched)
+		stats[key] = stat
+	}
+}
+
+// baselineDistribution - ожидаемые доли направлений депо.
+// Для "depot" используются частоты по всем локомотивам депо со сглаживанием Лапласа,
+// для "uniform" - равные доли.
+func baselineDistribution(directions []domain.Direction, visits map[string]int, baseline string) map[string]float64 {
+	result := make(map[string]float64, len(directions))
+	if len(directions) == 0 {
+		return result
+	}
+
+	total := 0
+	for _, dir := range directions {
+		total += visits[dir.ID]
+	}
+
+	for _, dir := range directions {
+		if baseline == "uniform" {
+			result[dir.ID] = 1 / float64(len(directions))
+			continue
+		}
+		result[dir.ID] = float64(visits[dir.ID]+1) / float64(total+len(directions))
+	}
+	return result
+}
+
+// directionEntropy - энтропия Шеннона распределения поездок по направлениям (в битах)
+func directionEntropy(visits map[string]int) float64 {
+	total := 0
+	for _, v := range visits {
+		total += v
+	}
+	if total == 0 {
+		return 0
+	}
+
+	entropy := 0.0
+	for _, v := range visits {
+		if v == 0 {
+			continue
+		}
+		p := float64(v) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// chiSquareStatistic - статистика хи-квадрат для наблюдаемых поездок против базового распределения
+func chiSquareStatistic(
+	visits map[string]int,
+	directions []domain.Direction,
+	expected map[string]float64,
+	matched int) (float64, int) {
+
+	if matched == 0 || len(directions) < 2 {
+		return 0, 0
+	}
+
+	chi := 0.0
+	for _, dir := range directions {
+		e := float64(matched) * expected[dir.ID]
+		if e == 0 {
+			continue
+		}
+		diff := float64(visits[dir.ID]) - e
+		chi += diff * diff / e
+	}
+	return chi, len(directions) - 1
+}
+
+// chiSquarePValue - вероятность получить статистику не меньше chi при df степенях свободы
+func chiSquarePValue(chi float64, df int) float64 {
+	if chi <= 0 {
+		return 1
+	}
+	return upperIncompleteGamma(float64(df)/2, chi/2)
+}
+
+// upperIncompleteGamma - регуляризованная верхняя неполная гамма-функция Q(a, x)
+func upperIncompleteGamma(a, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-12
+		tiny          = 1e-300
+	)
+
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgamma)
+
+	if x < a+1 {
+		// Ряд для нижней функции P(a, x)
+		sum := 1 / a
+		term := sum
+		for n := 1; n < maxIterations; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*epsilon {
+				break
+			}
+		}
+		return math.Max(0, 1-sum*prefix)
+	}
+
+	// Непрерывная дробь (метод Лентца) для Q(a, x)
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < maxIterations; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return math.Min(1, h*prefix)
+}
+
+// favoriteLabel - метка любимого направления. Любимое направление есть, если
+// распределение значимо отличается от базового и самое частое направление
+// встречается чаще, чем ожидается по базовому распределению.
+func favoriteLabel(stat domain.LocomotiveDirectionStats, expected map[string]float64, matched int) string {
+	if stat.MostPopularDirection == "" || matched == 0 || stat.PValue >= weakFavoritePValue {
+		return favoriteNone
+	}
+
+	share := float64(stat.MaxVisits) / float64(matched)
+	if share <= expected[stat.MostPopularDirection] {
+		return favoriteNone
+	}
+
+	if stat.PValue < strongFavoritePValue && share >= strongFavoriteShare {
+		return favoriteStrong
+	}
+	return favoriteWeak
+}
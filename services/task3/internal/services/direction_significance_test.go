@@ -0,0 +1,75 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+func TestChiSquarePValue(t *testing.T) {
+	tests := []struct {
+		name string
+		chi  float64
+		df   int
+		want float64
+	}{
+		// Критические значения хи-квадрат из таблиц
+		{name: "df=1, 5%", chi: 3.841458820694124, df: 1, want: 0.05},
+		{name: "df=1, 1%", chi: 6.634896601021214, df: 1, want: 0.01},
+		{name: "df=2, 5%", chi: 5.991464547107979, df: 2, want: 0.05},
+		{name: "df=10, 5%", chi: 18.307038053275146, df: 10, want: 0.05},
+		// При df = 2 p = exp(-chi/2), при df = 4 p = exp(-chi/2) * (1 + chi/2)
+		{name: "df=2", chi: 2, df: 2, want: math.Exp(-1)},
+		{name: "df=4", chi: 1, df: 4, want: math.Exp(-0.5) * 1.5},
+		{name: "большая статистика", chi: 200, df: 3, want: 0},
+		{name: "нулевая статистика", chi: 0, df: 3, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chiSquarePValue(tt.chi, tt.df); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("chiSquarePValue(%v, %d) = %v, ожидалось %v", tt.chi, tt.df, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpperIncompleteGamma(t *testing.T) {
+	tests := []struct {
+		name string
+		a, x float64
+		want float64
+	}{
+		// Q(1, x) = exp(-x), Q(1/2, x) = erfc(sqrt(x)); x < a+1 - ряд, иначе непрерывная дробь
+		{name: "a=1, ряд", a: 1, x: 0.5, want: math.Exp(-0.5)},
+		{name: "a=1, непрерывная дробь", a: 1, x: 5, want: math.Exp(-5)},
+		{name: "a=1/2, ряд", a: 0.5, x: 0.2, want: math.Erfc(math.Sqrt(0.2))},
+		{name: "a=1/2, непрерывная дробь", a: 0.5, x: 4, want: math.Erfc(2)},
+		{name: "a=3, граница методов", a: 3, x: 4, want: math.Exp(-4) * (1 + 4 + 8)},
+		{name: "a=3, перед границей", a: 3, x: 3.999999, want: math.Exp(-3.999999) * (1 + 3.999999 + 3.999999*3.999999/2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := upperIncompleteGamma(tt.a, tt.x); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("upperIncompleteGamma(%v, %v) = %v, ожидалось %v", tt.a, tt.x, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChiSquareStatistic(t *testing.T) {
+	directions := []domain.Direction{{ID: "A"}, {ID: "B"}}
+	expected := map[string]float64{"A": 0.5, "B": 0.5}
+
+	// (8-5)²/5 + (2-5)²/5
+	chi, df := chiSquareStatistic(map[string]int{"A": 8, "B": 2}, directions, expected, 10)
+	if math.Abs(chi-3.6) > 1e-9 || df != 1 {
+		t.Errorf("chiSquareStatistic = %v, %d; ожидалось 3.6, 1", chi, df)
+	}
+
+	if chi, df := chiSquareStatistic(map[string]int{"A": 3}, directions[:1], expected, 3); chi != 0 || df != 0 {
+		t.Errorf("одно направление: %v, %d; ожидалось 0, 0", chi, df)
+	}
+}
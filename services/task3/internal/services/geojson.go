@@ -0,0 +1,166 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// GetStationsGeoJSON - станции депо со статистикой посещений (StationStats) в формате GeoJSON
+func (v *visualizationService) GetStationsGeoJSON(depoID string) (*responses.FeatureCollection, error) {
+	depoLocomotives, stations, err := v.loadDepotMapData(depoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(depoLocomotives) == 0 {
+		return nil, nil
+	}
+
+	stationStats := v.collectStationStats(depoLocomotives, stations)
+
+	collection := newFeatureCollection()
+	for _, stat := range sortedStationStats(stationStats) {
+		locomotives := append([]string(nil), stat.Locomotives...)
+		sort.Strings(locomotives)
+
+		collection.Features = append(collection.Features, pointFeature(stat.Longitude, stat.Latitude,
+			map[string]interface{}{
+				"id":               stat.StationID,
+				"name":             stat.StationName,
+				"visits":           stat.VisitCount,
+				"popularity":       stat.Popularity,
+				"locomotive_count": len(locomotives),
+				"locomotives":      locomotives,
+				"is_depot":         stat.StationID == depoID,
+			}))
+	}
+
+	return collection, nil
+}
+
+// GetRoutesGeoJSON - маршруты поездок локомотивов депо (LocomotiveRoute) в формате GeoJSON
+func (v *visualizationService) GetRoutesGeoJSON(depoID string) (*responses.FeatureCollection, error) {
+	depoLocomotives, stations, err := v.loadDepotMapData(depoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(depoLocomotives) == 0 {
+		return nil, nil
+	}
+
+	routes := v.buildLocomotiveRoutes(depoLocomotives, stations)
+
+	keys := make([]string, 0, len(routes))
+	for key := range routes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	collection := newFeatureCollection()
+	for _, key := range keys {
+		for i, route := range routes[key] {
+			coordinates := make([][]float64, 0, len(route.Points))
+			stationIDs := make([]string, 0, len(route.Points))
+			for _, p := range route.Points {
+				coordinates = append(coordinates, []float64{p.Lon, p.Lat})
+				stationIDs = append(stationIDs, p.StationID)
+			}
+
+			collection.Features = append(collection.Features, responses.Feature{
+				Type: "Feature",
+				Geometry: responses.Geometry{
+					Type:        "LineString",
+					Coordinates: coordinates,
+				},
+				Properties: map[string]interface{}{
+					"locomotive": route.LocomotiveKey,
+					"model":      route.Model,
+					"number":     route.Number,
+					"trip_index": i,
+					"trips":      route.Trips,
+					"stations":   stationIDs,
+				},
+			})
+		}
+	}
+
+	return collection, nil
+}
+
+// GetHeatGeoJSON - точки тепловой карты депо (посещаемые станции с весом) в формате GeoJSON
+func (v *visualizationService) GetHeatGeoJSON(depoID string) (*responses.FeatureCollection, error) {
+	depoLocomotives, stations, err := v.loadDepotMapData(depoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(depoLocomotives) == 0 {
+		return nil, nil
+	}
+
+	stationStats := v.collectStationStats(depoLocomotives, stations)
+
+	collection := newFeatureCollection()
+	for _, stat := range sortedStationStats(stationStats) {
+		collection.Features = append(collection.Features, pointFeature(stat.Longitude, stat.Latitude,
+			map[string]interface{}{
+				"id":        stat.StationID,
+				"weight":    stat.VisitCount,
+				"intensity": stat.Popularity,
+			}))
+	}
+
+	return collection, nil
+}
+
+// loadDepotMapData - локомотивы депо с поездками и координаты станций.
+// Выгрузки данных не выдумывают координаты: без station_info.csv возвращается ошибка.
+func (v *visualizationService) loadDepotMapData(depoID string) (map[string]domain.Locomotive, map[string]domain.Station, error) {
+	locomotives := loadData(v.dataPath)
+
+	depoLocomotives := filterLocomotivesByDepo(locomotives, depoID)
+	for key, loc := range depoLocomotives {
+		loc.Trips = splitIntoTrips(loc.Records)
+		depoLocomotives[key] = loc
+	}
+
+	stations, _, err := v.getStationCoordinates(depoID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return depoLocomotives, stations, nil
+}
+
+// sortedStationStats - посещаемые станции с координатами в порядке ID
+func sortedStationStats(stationStats map[string]*domain.StationStats) []*domain.StationStats {
+	result := make([]*domain.StationStats, 0)
+	for _, stat := range stationStats {
+		if stat.VisitCount > 0 && stat.Latitude != 0 && stat.Longitude != 0 {
+			result = append(result, stat)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StationID < result[j].StationID
+	})
+	return result
+}
+
+func newFeatureCollection() *responses.FeatureCollection {
+	return &responses.FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]responses.Feature, 0),
+	}
+}
+
+// pointFeature - точка GeoJSON (координаты в порядке [lon, lat])
+func pointFeature(lon, lat float64, properties map[string]interface{}) responses.Feature {
+	return responses.Feature{
+		Type: "Feature",
+		Geometry: responses.Geometry{
+			Type:        "Point",
+			Coordinates: []float64{lon, lat},
+		},
+		Properties: properties,
+	}
+}
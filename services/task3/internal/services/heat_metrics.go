@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+// heatCounters - показатели станции для всех метрик тепловой карты
+type heatCounters struct {
+	visits      int // станция один раз за поездку
+	totalVisits int // все заезды (подряд идущие отметки на станции - один заезд)
+	dwell       time.Duration
+	locomotives map[string]bool
+}
+
+// collectHeatStats собирает статистику станций для тепловой карты: отметки фильтруются по
+// периоду и сериям из opts, Weight - значение выбранной метрики, Popularity - Weight от максимума
+func (v *visualizationService) collectHeatStats(
+	locomotives map[string]domain.Locomotive,
+	stations map[string]domain.Station,
+	opts domain.MapOptions) map[string]*domain.StationStats {
+
+	counters := make(map[string]*heatCounters, len(stations))
+	for id := range stations {
+		counters[id] = &heatCounters{locomotives: make(map[string]bool)}
+	}
+
+	var first, last time.Time
+	for locKey, loc := range locomotives {
+		if !opts.HasSeries(loc.Series) {
+			continue
+		}
+
+		var records []domain.Record
+		for _, rec := range loc.Records {
+			if opts.Contains(rec.Timestamp) {
+				records = append(records, rec)
+			}
+		}
+		if len(records) == 0 {
+			continue
+		}
+		if first.IsZero() || records[0].Timestamp.Before(first) {
+			first = records[0].Timestamp
+		}
+		if end := records[len(records)-1].Timestamp; end.After(last) {
+			last = end
+		}
+
+		// Заезды и стоянки
+		for i := 0; i < len(records); {
+			j := i
+			for j+1 < len(records) && records[j+1].Station == records[i].Station {
+				j++
+			}
+			if c, exists := counters[records[i].Station]; exists {
+				c.totalVisits++
+				c.dwell += records[j].Timestamp.Sub(records[i].Timestamp)
+				c.locomotives[locKey] = true
+			}
+			i = j + 1
+		}
+
+		// Посещения по поездкам (поездки внутри периода)
+		for _, trip := range splitIntoTrips(records) {
+			seen := make(map[string]bool)
+			for _, stationID := range trip.Stations {
+				if c, exists := counters[stationID]; exists && !seen[stationID] {
+					c.visits++
+					seen[stationID] = true
+				}
+			}
+		}
+	}
+
+	// Сутки периода: заданное окно или промежуток между первой и последней отметкой
+	periodStart, periodEnd := first, last
+	if !opts.From.IsZero() {
+		periodStart = opts.From
+	}
+	if !opts.To.IsZero() {
+		periodEnd = opts.To
+	}
+	days := math.Max(1, math.Ceil(periodEnd.Sub(periodStart).Hours()/24))
+
+	metric := heatMetric(opts)
+	stats := make(map[string]*domain.StationStats, len(stations))
+	maxWeight := 0.0
+	for id, station := range stations {
+		c := counters[id]
+		stat := &domain.StationStats{
+			StationID:   id,
+			StationName: station.Name,
+			Latitude:    station.Latitude,
+			Longitude:   station.Longitude,
+			VisitCount:  c.visits,
+			Locomotives: []string{},
+		}
+		for locKey := range c.locomotives {
+			stat.Locomotives = append(stat.Locomotives, locKey)
+		}
+
+		switch metric {
+		case domain.HeatMetricTotalVisits:
+			stat.Weight = float64(c.totalVisits)
+		case domain.HeatMetricLocomotives:
+			stat.Weight = float64(len(c.locomotives))
+		case domain.HeatMetricDwell:
+			stat.Weight = c.dwell.Hours()
+		case domain.HeatMetricTripsPerDay:
+			stat.Weight = float64(c.visits) / days
+		default:
+			stat.Weight = float64(c.visits)
+		}
+		if stat.Weight > maxWeight {
+			maxWeight = stat.Weight
+		}
+		stats[id] = stat
+	}
+
+	// Нормализуем популярность
+	if maxWeight > 0 {
+		for _, stat := range stats {
+			stat.Popularity = stat.Weight / maxWeight
+		}
+	}
+
+	return stats
+}
+
+// heatMetric - метрика тепловой карты (по умолчанию посещения по поездкам)
+func heatMetric(opts domain.MapOptions) string {
+	if opts.HeatMetric == "" {
+		return domain.HeatMetricVisits
+	}
+	return opts.HeatMetric
+}
+
+// heatMetricTitle - название метрики для легенды
+func heatMetricTitle(metric string) string {
+	switch metric {
+	case domain.HeatMetricTotalVisits:
+		return "Все заезды на станцию"
+	case domain.HeatMetricLocomotives:
+		return "Разных локомотивов"
+	case domain.HeatMetricDwell:
+		return "Время стоянки, ч"
+	case domain.HeatMetricTripsPerDay:
+		return "Поездок в сутки"
+	default:
+		return "Посещений (раз за поездку)"
+	}
+}
+
+// formatHeatValue - абсолютное значение метрики для легенды
+func formatHeatValue(metric string, value float64) string {
+	switch metric {
+	case domain.HeatMetricDwell:
+		return fmt.Sprintf("%.1f", value)
+	case domain.HeatMetricTripsPerDay:
+		return fmt.Sprintf("%.2f", value)
+	default:
+		return fmt.Sprintf("%.0f", value)
+	}
+}
+
+// heatFilterDescription - период и серии тепловой карты для легенды (пусто - без фильтров)
+func heatFilterDescription(opts domain.MapOptions) string {
+	var parts []string
+	if !opts.From.IsZero() || !opts.To.IsZero() {
+		from, to := "…", "…"
+		if !opts.From.IsZero() {
+			from = opts.From.Format("2006-01-02")
+		}
+		if !opts.To.IsZero() {
+			to = opts.To.Format("2006-01-02")
+		}
+		parts = append(parts, fmt.Sprintf("период %s - %s", from, to))
+	}
+	if len(opts.Series) > 0 {
+		parts = append(parts, "серии "+strings.Join(opts.Series, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+func TestCollectHeatStats(t *testing.T) {
+	stations := map[string]domain.Station{
+		"D": {ID: "D", Name: "Депо"},
+		"A": {ID: "A", Name: "Станция A"},
+		"B": {ID: "B", Name: "Станция B"},
+		"C": {ID: "C", Name: "Станция C"},
+	}
+
+	// ТЭ/1: поездки [D], [A A B A D], [A D]; стоянка на A 2 ч
+	// 2М62/2: поездка [A A X D], X без координат; стоянка на A 2 ч
+	locomotives := map[string]domain.Locomotive{
+		"ТЭ/1": {Series: "ТЭ", Number: "1", Depo: "D", Records: testRecords(
+			utilizationMark{0, "D"}, utilizationMark{1, "A"}, utilizationMark{3, "A"}, utilizationMark{4, "B"},
+			utilizationMark{5, "A"}, utilizationMark{6, "D"}, utilizationMark{30, "A"}, utilizationMark{31, "D"})},
+		"2М62/2": {Series: "2М62", Number: "2", Depo: "D", Records: testRecords(
+			utilizationMark{2, "A"}, utilizationMark{4, "A"}, utilizationMark{10, "X"}, utilizationMark{12, "D"})},
+	}
+
+	tests := []struct {
+		name        string
+		opts        domain.MapOptions
+		visits      map[string]int
+		weight      map[string]float64
+		locomotives map[string][]string
+	}{
+		{
+			name:        "посещения по поездкам (по умолчанию)",
+			visits:      map[string]int{"D": 4, "A": 3, "B": 1},
+			weight:      map[string]float64{"D": 4, "A": 3, "B": 1},
+			locomotives: map[string][]string{"D": {"2М62/2", "ТЭ/1"}, "A": {"2М62/2", "ТЭ/1"}, "B": {"ТЭ/1"}},
+		},
+		{
+			name:   "все заезды",
+			opts:   domain.MapOptions{HeatMetric: domain.HeatMetricTotalVisits},
+			visits: map[string]int{"D": 4, "A": 3, "B": 1},
+			weight: map[string]float64{"D": 4, "A": 4, "B": 1},
+		},
+		{
+			name:   "разные локомотивы",
+			opts:   domain.MapOptions{HeatMetric: domain.HeatMetricLocomotives},
+			visits: map[string]int{"D": 4, "A": 3, "B": 1},
+			weight: map[string]float64{"D": 2, "A": 2, "B": 1},
+		},
+		{
+			name:   "время стоянки",
+			opts:   domain.MapOptions{HeatMetric: domain.HeatMetricDwell},
+			visits: map[string]int{"D": 4, "A": 3, "B": 1},
+			weight: map[string]float64{"A": 4},
+		},
+		{
+			// Отметки с 0 по 31 час - двое суток
+			name:   "поездок в сутки по данным",
+			opts:   domain.MapOptions{HeatMetric: domain.HeatMetricTripsPerDay},
+			visits: map[string]int{"D": 4, "A": 3, "B": 1},
+			weight: map[string]float64{"D": 2, "A": 1.5, "B": 0.5},
+		},
+		{
+			name: "поездок в сутки за период",
+			opts: domain.MapOptions{
+				HeatMetric: domain.HeatMetricTripsPerDay,
+				From:       utilizationStart,
+				To:         utilizationStart.Add(24 * time.Hour),
+			},
+			visits:      map[string]int{"D": 3, "A": 2, "B": 1},
+			weight:      map[string]float64{"D": 3, "A": 2, "B": 1},
+			locomotives: map[string][]string{"D": {"2М62/2", "ТЭ/1"}, "A": {"2М62/2", "ТЭ/1"}, "B": {"ТЭ/1"}},
+		},
+		{
+			name:        "фильтр по серии",
+			opts:        domain.MapOptions{Series: []string{"2М62"}},
+			visits:      map[string]int{"D": 1, "A": 1},
+			weight:      map[string]float64{"D": 1, "A": 1},
+			locomotives: map[string][]string{"D": {"2М62/2"}, "A": {"2М62/2"}},
+		},
+		{
+			name: "нет отметок в периоде",
+			opts: domain.MapOptions{From: utilizationStart.AddDate(1, 0, 0)},
+		},
+	}
+
+	v := &visualizationService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := v.collectHeatStats(locomotives, stations, tt.opts)
+			if len(stats) != len(stations) {
+				t.Fatalf("станций %d, ожидалось %d", len(stats), len(stations))
+			}
+
+			maxWeight := 0.0
+			for _, w := range tt.weight {
+				maxWeight = math.Max(maxWeight, w)
+			}
+
+			for id, station := range stations {
+				stat := stats[id]
+				if stat.StationID != id || stat.StationName != station.Name {
+					t.Errorf("%s: станция %q %q", id, stat.StationID, stat.StationName)
+				}
+				if stat.VisitCount != tt.visits[id] {
+					t.Errorf("%s: посещений %d, ожидалось %d", id, stat.VisitCount, tt.visits[id])
+				}
+				if math.Abs(stat.Weight-tt.weight[id]) > 1e-9 {
+					t.Errorf("%s: вес %v, ожидалось %v", id, stat.Weight, tt.weight[id])
+				}
+
+				wantPopularity := 0.0
+				if maxWeight > 0 {
+					wantPopularity = tt.weight[id] / maxWeight
+				}
+				if math.Abs(stat.Popularity-wantPopularity) > 1e-9 {
+					t.Errorf("%s: популярность %v, ожидалось %v", id, stat.Popularity, wantPopularity)
+				}
+
+				if tt.locomotives != nil {
+					got := append([]string{}, stat.Locomotives...)
+					sort.Strings(got)
+					want := tt.locomotives[id]
+					if want == nil {
+						want = []string{}
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("%s: локомотивы %v, ожидалось %v", id, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"sort"
 	"strconv"
@@ -68,12 +69,21 @@ func splitIntoTrips(records []domain.Record) []domain.Trip {
 	return trips
 }
 
-// loadData загружает данные о локомотивах из CSV файла
+// loadData загружает данные о локомотивах из CSV файла, ошибка чтения - panic
 func loadData(filename string) map[string]domain.Locomotive {
-	file, err := os.Open(filename)
+	locomotives, err := readData(filename)
 	if err != nil {
 		panic(err)
 	}
+	return locomotives
+}
+
+// readData загружает данные о локомотивах из CSV файла
+func readData(filename string) (map[string]domain.Locomotive, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
@@ -137,7 +147,7 @@ func loadData(filename string) map[string]domain.Locomotive {
 		locomotives[key] = loc
 	}
 
-	return locomotives
+	return locomotives, scanner.Err()
 }
 
 // filterLocomotivesByDepo фильтрует локомотивы по заданному депо
@@ -324,4 +334,37 @@ func calculateCenter(stations map[string]domain.Station, defaultLat, defaultLon
 	}
 
 	return defaultLat, defaultLon
-}
\ No newline at end of file
+}
+
+// weekStart возвращает начало недели (понедельник 00:00) для момента времени
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// periodStart возвращает начало периода ("week", "month", "quarter") для момента времени
+func periodStart(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case "week":
+		return weekStart(t)
+	case "quarter":
+		month := time.Month((int(t.Month())-1)/3*3 + 1)
+		return time.Date(t.Year(), month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// haversineKm возвращает расстояние между двумя точками по дуге большого круга (км)
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+const (
+	// maxAutoClusters - максимальное количество кластеров при автоматическом подборе
+	maxAutoClusters = 5
+	// kMeansMaxIterations - ограничение количества итераций k-means
+	kMeansMaxIterations = 100
+	// outlierSigma - локомотив нетипичен, если удален от центра своего кластера
+	// дальше, чем среднее расстояние по депо плюс outlierSigma стандартных отклонений
+	outlierSigma = 2.0
+)
+
+// locomotiveProfile - профиль эксплуатации локомотива и его вектор признаков
+type locomotiveProfile struct {
+	stats           domain.LocomotiveDirectionStats
+	shares          map[string]float64 // ID направления -> доля сопоставленных поездок
+	avgTripStations float64
+	unmatchedShare  float64
+	vector          []float64
+}
+
+// GetLocomotiveClusters - для API режима (группы локомотивов со схожим профилем эксплуатации)
+func (m *mostPopularTripService) GetLocomotiveClusters(
+	clusterOpts domain.ClusterOptions,
+	opts domain.DirectionOptions) (*responses.LocomotiveClusters, error) {
+
+	locomotiveStats, depotDirections := m.computeDirectionStats(opts)
+
+	byDepot := make(map[string][]domain.LocomotiveDirectionStats)
+	for _, stat := range locomotiveStats {
+		if clusterOpts.Depo != "" && stat.Depo != clusterOpts.Depo {
+			continue
+		}
+		byDepot[stat.Depo] = append(byDepot[stat.Depo], stat)
+	}
+	if clusterOpts.Depo != "" && len(byDepot) == 0 {
+		return nil, nil
+	}
+
+	depots := make([]string, 0, len(byDepot))
+	for depo := range byDepot {
+		depots = append(depots, depo)
+	}
+	sort.Strings(depots)
+
+	result := &responses.LocomotiveClusters{
+		Method: "kmeans",
+		Depots: make([]responses.DepotClusters, 0, len(depots)),
+	}
+	for _, depo := range depots {
+		stats := byDepot[depo]
+		// Фиксированный порядок, чтобы кластеры не менялись от запуска к запуску
+		sort.Slice(stats, func(i, j int) bool {
+			return stats[i].LocomotiveKey < stats[j].LocomotiveKey
+		})
+		result.Depots = append(result.Depots, m.clusterDepot(stats, depotDirections[depo], clusterOpts.K))
+	}
+
+	return result, nil
+}
+
+// clusterDepot - кластеризует локомотивы одного депо и находит нетипичные
+func (m *mostPopularTripService) clusterDepot(
+	stats []domain.LocomotiveDirectionStats,
+	directions []domain.Direction,
+	k int) responses.DepotClusters {
+
+	profiles := buildLocomotiveProfiles(stats, directions)
+
+	if k <= 0 {
+		k = autoClusterCount(len(profiles))
+	}
+	if k > len(profiles) {
+		k = len(profiles)
+	}
+
+	depot := responses.DepotClusters{
+		DepoCode:        stats[0].Depo,
+		DepoName:        stats[0].DepoName,
+		LocomotiveCount: len(profiles),
+		K:               k,
+		Clusters:        make([]responses.LocomotiveCluster, 0, k),
+		Outliers:        make([]responses.ClusterMember, 0),
+	}
+
+	points := make([][]float64, len(profiles))
+	for i, p := range profiles {
+		points[i] = p.vector
+	}
+	assignments, centroids := kMeans(points, k)
+
+	// Порог нетипичности - по расстояниям до центров кластеров во всем депо
+	distances := make([]float64, len(points))
+	var sum, sumSq float64
+	for i, point := range points {
+		distances[i] = euclideanDistance(point, centroids[assignments[i]])
+		sum += distances[i]
+		sumSq += distances[i] * distances[i]
+	}
+	mean := sum / float64(len(points))
+	std := math.Sqrt(math.Max(0, sumSq/float64(len(points))-mean*mean))
+	depot.OutlierDistance = mean + outlierSigma*std
+
+	members := make([][]int, k)
+	for i, c := range assignments {
+		members[c] = append(members[c], i)
+	}
+
+	for _, idx := range members {
+		if len(idx) == 0 {
+			continue
+		}
+
+		cluster := responses.LocomotiveCluster{
+			Size:        len(idx),
+			Profile:     clusterProfile(profiles, idx, directions),
+			Locomotives: make([]responses.ClusterMember, 0, len(idx)),
+		}
+
+		for _, i := range idx {
+			// Единственный локомотив в кластере не похож ни на один другой
+			outlier := distances[i] > depot.OutlierDistance && distances[i] > 0
+			if len(idx) == 1 && len(profiles) > k {
+				outlier = true
+			}
+
+			member := responses.ClusterMember{
+				Model:    profiles[i].stats.Model,
+				Number:   profiles[i].stats.Number,
+				Trips:    profiles[i].stats.TotalTrips,
+				Distance: distances[i],
+				Outlier:  outlier,
+			}
+			cluster.Locomotives = append(cluster.Locomotives, member)
+			if outlier {
+				depot.Outliers = append(depot.Outliers, member)
+			}
+		}
+
+		// Сортируем по удаленности от центра, первый - представитель кластера
+		sort.SliceStable(cluster.Locomotives, func(a, b int) bool {
+			return cluster.Locomotives[a].Distance < cluster.Locomotives[b].Distance
+		})
+		cluster.Representative = cluster.Locomotives[0]
+
+		depot.Clusters = append(depot.Clusters, cluster)
+	}
+
+	// Сортируем кластеры по размеру
+	sort.SliceStable(depot.Clusters, func(i, j int) bool {
+		return depot.Clusters[i].Size > depot.Clusters[j].Size
+	})
+	for i := range depot.Clusters {
+		depot.Clusters[i].ID = i + 1
+	}
+
+	sort.SliceStable(depot.Outliers, func(i, j int) bool {
+		return depot.Outliers[i].Distance > depot.Outliers[j].Distance
+	})
+
+	return depot
+}
+
+// buildLocomotiveProfiles - векторы признаков локомотивов депо: доли направлений
+// (DirectionVisits), средняя длина поездки, количество поездок и доля несопоставленных поездок.
+// Длина и количество поездок нормируются в диапазон 0..1 по депо.
+func buildLocomotiveProfiles(stats []domain.LocomotiveDirectionStats, directions []domain.Direction) []locomotiveProfile {
+	profiles := make([]locomotiveProfile, len(stats))
+
+	minLength, maxLength := math.Inf(1), math.Inf(-1)
+	minTrips, maxTrips := math.Inf(1), math.Inf(-1)
+	for i, stat := range stats {
+		p := locomotiveProfile{
+			stats:  stat,
+			shares: make(map[string]float64),
+		}
+
+		matched := 0
+		for _, visits := range stat.DirectionVisits {
+			matched += visits
+		}
+		for dirID, visits := range stat.DirectionVisits {
+			if matched > 0 {
+				p.shares[dirID] = float64(visits) / float64(matched)
+			}
+		}
+
+		stations := 0
+		for _, trip := range stat.Trips {
+			stations += len(trip.Route)
+		}
+		if len(stat.Trips) > 0 {
+			p.avgTripStations = float64(stations) / float64(len(stat.Trips))
+		}
+		if stat.TotalTrips > 0 {
+			p.unmatchedShare = float64(stat.UnmatchedTrips) / float64(stat.TotalTrips)
+		}
+
+		minLength = math.Min(minLength, p.avgTripStations)
+		maxLength = math.Max(maxLength, p.avgTripStations)
+		minTrips = math.Min(minTrips, float64(stat.TotalTrips))
+		maxTrips = math.Max(maxTrips, float64(stat.TotalTrips))
+
+		profiles[i] = p
+	}
+
+	for i := range profiles {
+		p := &profiles[i]
+		p.vector = make([]float64, 0, len(directions)+3)
+		for _, dir := range directions {
+			p.vector = append(p.vector, p.shares[dir.ID])
+		}
+		p.vector = append(p.vector,
+			minMaxScale(p.avgTripStations, minLength, maxLength),
+			minMaxScale(float64(p.stats.TotalTrips), minTrips, maxTrips),
+			p.unmatchedShare)
+	}
+
+	return profiles
+}
+
+// clusterProfile - средний профиль эксплуатации локомотивов кластера
+func clusterProfile(profiles []locomotiveProfile, idx []int, directions []domain.Direction) responses.ClusterProfile {
+	profile := responses.ClusterProfile{
+		Directions: make([]responses.PeriodDirection, 0),
+	}
+
+	n := float64(len(idx))
+	for _, dir := range directions {
+		share, visits := 0.0, 0
+		for _, i := range idx {
+			share += profiles[i].shares[dir.ID]
+			visits += profiles[i].stats.DirectionVisits[dir.ID]
+		}
+		if visits == 0 {
+			continue
+		}
+		profile.Directions = append(profile.Directions, responses.PeriodDirection{
+			ID:     dir.ID,
+			Name:   dir.Name,
+			Visits: visits,
+			Share:  share / n * 100,
+		})
+	}
+
+	// Сортируем по средней доле направления
+	sort.SliceStable(profile.Directions, func(i, j int) bool {
+		return profile.Directions[i].Share > profile.Directions[j].Share
+	})
+
+	for _, i := range idx {
+		profile.AvgTripStations += profiles[i].avgTripStations
+		profile.AvgTrips += float64(profiles[i].stats.TotalTrips)
+		profile.UnmatchedShare += profiles[i].unmatchedShare
+	}
+	profile.AvgTripStations /= n
+	profile.AvgTrips /= n
+	profile.UnmatchedShare = profile.UnmatchedShare / n * 100
+
+	return profile
+}
+
+// kMeans - кластеризация k-means с детерминированной инициализацией
+// (первый центр - точка, ближайшая к среднему, далее - самые удаленные точки)
+func kMeans(points [][]float64, k int) ([]int, [][]float64) {
+	dims := len(points[0])
+
+	mean := make([]float64, dims)
+	for _, point := range points {
+		for d, v := range point {
+			mean[d] += v / float64(len(points))
+		}
+	}
+
+	first := 0
+	for i, point := range points {
+		if euclideanDistance(point, mean) < euclideanDistance(points[first], mean) {
+			first = i
+		}
+	}
+
+	centroids := make([][]float64, 0, k)
+	centroids = append(centroids, append([]float64(nil), points[first]...))
+	for len(centroids) < k {
+		farthest, farthestDist := 0, -1.0
+		for i, point := range points {
+			nearest := math.Inf(1)
+			for _, c := range centroids {
+				nearest = math.Min(nearest, euclideanDistance(point, c))
+			}
+			if nearest > farthestDist {
+				farthest, farthestDist = i, nearest
+			}
+		}
+		centroids = append(centroids, append([]float64(nil), points[farthest]...))
+	}
+
+	assignments := make([]int, len(points))
+	for iter := 0; iter < kMeansMaxIterations; iter++ {
+		changed := iter == 0
+		for i, point := range points {
+			best := 0
+			for c := range centroids {
+				if euclideanDistance(point, centroids[c]) < euclideanDistance(point, centroids[best]) {
+					best = c
+				}
+			}
+			if assignments[i] != best {
+				assignments[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		// Пересчет центров; пустой кластер сохраняет прежний центр
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for i, point := range points {
+			c := assignments[i]
+			if sums[c] == nil {
+				sums[c] = make([]float64, dims)
+			}
+			for d, v := range point {
+				sums[c][d] += v
+			}
+			counts[c]++
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				continue
+			}
+			for d := range centroids[c] {
+				centroids[c][d] = sums[c][d] / float64(counts[c])
+			}
+		}
+	}
+
+	return assignments, centroids
+}
+
+// autoClusterCount - количество кластеров по правилу k ≈ sqrt(n/2)
+func autoClusterCount(n int) int {
+	k := int(math.Round(math.Sqrt(float64(n) / 2)))
+	if k < 1 {
+		k = 1
+	}
+	if k > maxAutoClusters {
+		k = maxAutoClusters
+	}
+	return k
+}
+
+// minMaxScale - нормирование значения в диапазон 0..1
+func minMaxScale(v, min, max float64) float64 {
+	if max <= min {
+		return 0
+	}
+	return (v - min) / (max - min)
+}
+
+// euclideanDistance - евклидово расстояние между векторами признаков
+func euclideanDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+	return math.Sqrt(sum)
+}
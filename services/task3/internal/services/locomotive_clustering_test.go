@@ -0,0 +1,97 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestKMeans(t *testing.T) {
+	tests := []struct {
+		name        string
+		points      [][]float64
+		k           int
+		assignments []int
+		centroids   [][]float64
+	}{
+		{
+			// Первый центр - точка (10, 10), ближайшая к среднему
+			name:        "две удаленные группы",
+			points:      [][]float64{{0, 0}, {10, 10}, {0, 1}, {10, 11}, {1, 0}, {11, 10}},
+			k:           2,
+			assignments: []int{1, 0, 1, 0, 1, 0},
+			centroids:   [][]float64{{31.0 / 3, 31.0 / 3}, {1.0 / 3, 1.0 / 3}},
+		},
+		{
+			name:        "один кластер - центр в среднем",
+			points:      [][]float64{{0, 0}, {2, 0}, {4, 6}},
+			k:           1,
+			assignments: []int{0, 0, 0},
+			centroids:   [][]float64{{2, 2}},
+		},
+		{
+			name:        "кластеров столько же, сколько точек",
+			points:      [][]float64{{0}, {5}, {9}},
+			k:           3,
+			assignments: []int{1, 0, 2},
+			centroids:   [][]float64{{5}, {0}, {9}},
+		},
+		{
+			name:        "одинаковые точки - лишний кластер пустой",
+			points:      [][]float64{{1, 1}, {1, 1}, {1, 1}},
+			k:           2,
+			assignments: []int{0, 0, 0},
+			centroids:   [][]float64{{1, 1}, {1, 1}},
+		},
+		{
+			// Первый центр - точка 2 (ближайшая к среднему 4.6), второй - самая удаленная точка 20
+			name:        "центры сходятся за несколько итераций",
+			points:      [][]float64{{0}, {1}, {2}, {20}, {0}},
+			k:           2,
+			assignments: []int{0, 0, 0, 1, 0},
+			centroids:   [][]float64{{0.75}, {20}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignments, centroids := kMeans(tt.points, tt.k)
+			if !reflect.DeepEqual(assignments, tt.assignments) {
+				t.Errorf("кластеры %v, ожидалось %v", assignments, tt.assignments)
+			}
+			if len(centroids) != len(tt.centroids) {
+				t.Fatalf("центров %d, ожидалось %d", len(centroids), len(tt.centroids))
+			}
+			for c := range centroids {
+				for d := range centroids[c] {
+					if math.Abs(centroids[c][d]-tt.centroids[c][d]) > 1e-9 {
+						t.Errorf("центр %d = %v, ожидалось %v", c, centroids[c], tt.centroids[c])
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestKMeansDoesNotModifyPoints(t *testing.T) {
+	points := [][]float64{{0, 0}, {4, 4}, {0, 1}}
+	kMeans(points, 2)
+	if !reflect.DeepEqual(points, [][]float64{{0, 0}, {4, 4}, {0, 1}}) {
+		t.Errorf("kMeans изменил точки: %v", points)
+	}
+}
+
+func TestAutoClusterCount(t *testing.T) {
+	tests := []struct {
+		n, want int
+	}{
+		{0, 1}, {1, 1}, {2, 1}, {8, 2}, {18, 3}, {50, 5}, {10000, maxAutoClusters},
+	}
+
+	for _, tt := range tests {
+		if got := autoClusterCount(tt.n); got != tt.want {
+			t.Errorf("autoClusterCount(%d) = %d, ожидалось %d", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// defaultLocomotivePageSize - размер страницы каталога локомотивов по умолчанию
+const defaultLocomotivePageSize = 100
+
+type locomotiveService struct {
+	dataPath    string
+	stations    map[string]domain.Station // станции с координатами
+	algorithm   AlgorithmService
+	popularTrip MostPopularTripService
+}
+
+type LocomotiveService interface {
+	ListLocomotives(filter domain.LocomotiveFilter) (*responses.LocomotiveList, error)
+	GetLocomotiveProfile(series, number string) (*responses.LocomotiveProfile, error)
+	ListSeries() (*responses.SeriesList, error)
+	GetSeriesAnalytics(series string) (*responses.SeriesAnalytics, error)
+	GetUtilizationReport(opts domain.UtilizationOptions) (*responses.UtilizationReport, error)
+	RunUtilizationReport(opts domain.UtilizationOptions)
+}
+
+func NewLocomotiveService(
+	dataPath, stationsPath string,
+	algorithm AlgorithmService,
+	popularTrip MostPopularTripService) LocomotiveService {
+
+	svc := &locomotiveService{
+		dataPath:    dataPath,
+		stations:    make(map[string]domain.Station),
+		algorithm:   algorithm,
+		popularTrip: popularTrip,
+	}
+
+	if stations, err := loadStationCoordinates(stationsPath); err == nil {
+		svc.stations = stations
+	} else {
+		fmt.Printf("Предупреждение: не удалось загрузить координаты станций: %v\n", err)
+	}
+
+	return svc
+}
+
+// ListLocomotives - каталог локомотивов с поиском по префиксу серии/номера и фильтром по депо
+func (l *locomotiveService) ListLocomotives(filter domain.LocomotiveFilter) (*responses.LocomotiveList, error) {
+	locomotives := loadData(l.dataPath)
+
+	seriesPrefix := strings.ToLower(filter.Series)
+	items := make([]responses.LocomotiveSummary, 0)
+	for _, loc := range locomotives {
+		if filter.Depo != "" && loc.Depo != filter.Depo {
+			continue
+		}
+		if seriesPrefix != "" && !strings.HasPrefix(strings.ToLower(loc.Series), seriesPrefix) {
+			continue
+		}
+		if filter.Number != "" && !strings.HasPrefix(loc.Number, filter.Number) {
+			continue
+		}
+		items = append(items, l.buildLocomotiveSummary(loc))
+	}
+
+	// Сортируем по серии и номеру
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Series == items[j].Series {
+			return items[i].Number < items[j].Number
+		}
+		return items[i].Series < items[j].Series
+	})
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultLocomotivePageSize
+	}
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+
+	list := &responses.LocomotiveList{
+		Locomotives: make([]responses.LocomotiveSummary, 0, limit),
+		Pagination: responses.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      len(items),
+			TotalPages: (len(items) + limit - 1) / limit,
+			Sort:       "series",
+		},
+	}
+
+	offset := (page - 1) * limit
+	if offset < len(items) {
+		list.Locomotives = append(list.Locomotives, items[offset:min(offset+limit, len(items))]...)
+	}
+
+	return list, nil
+}
+
+// GetLocomotiveProfile - профиль локомотива: поездки, направления (задача 2),
+// ветки депо (задача 1) и оценка пробега. Данные читаются один раз для всех разделов.
+func (l *locomotiveService) GetLocomotiveProfile(series, number string) (*responses.LocomotiveProfile, error) {
+	locomotives, err := readData(l.dataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, exists := locomotives[series+"-"+number]
+	if !exists {
+		return nil, nil
+	}
+
+	profile := &responses.LocomotiveProfile{
+		LocomotiveSummary: l.buildLocomotiveSummary(loc),
+		DepoName:          loc.Depo,
+		Stations:          len(uniqueStations(loc.Records)),
+		Trips:             l.buildTripSummary(splitIntoTrips(loc.Records)),
+		Mileage:           l.estimateMileage(loc.Records),
+	}
+	if station, ok := l.stations[loc.Depo]; ok {
+		profile.DepoName = station.Name
+	}
+
+	directions, err := l.popularTrip.GetLocomotivePopularDirectionFrom(locomotives, series, number, domain.DirectionOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if directions != nil {
+		// Список поездок уже есть в профиле в сжатом виде
+		directions.Trips = nil
+		profile.Directions = directions
+	}
+
+	branches, err := l.algorithm.GetLocomotiveBranchesFrom(locomotives, series, number)
+	if err != nil {
+		return nil, err
+	}
+	profile.Branches = branches
+
+	return profile, nil
+}
+
+// buildLocomotiveSummary - краткая информация о локомотиве для каталога
+func (l *locomotiveService) buildLocomotiveSummary(loc domain.Locomotive) responses.LocomotiveSummary {
+	summary := responses.LocomotiveSummary{
+		Series:      loc.Series,
+		Number:      loc.Number,
+		Depo:        loc.Depo,
+		RecordCount: len(loc.Records),
+		TripCount:   len(splitIntoTrips(loc.Records)),
+	}
+
+	if len(loc.Records) > 0 {
+		summary.FirstSeen = loc.Records[0].Timestamp.Format(time.RFC3339)
+		summary.LastSeen = loc.Records[len(loc.Records)-1].Timestamp.Format(time.RFC3339)
+	}
+
+	return summary
+}
+
+// buildTripSummary - агрегаты по поездкам локомотива
+func (l *locomotiveService) buildTripSummary(trips []domain.Trip) responses.LocomotiveTripSummary {
+	summary := responses.LocomotiveTripSummary{
+		Total: len(trips),
+	}
+
+	var totalHours float64
+	completed := 0
+	for _, trip := range trips {
+		if trip.EndTime.IsZero() {
+			continue
+		}
+		completed++
+		hours := trip.EndTime.Sub(trip.StartTime).Hours()
+		totalHours += hours
+		if hours > summary.LongestHours {
+			summary.LongestHours = hours
+		}
+	}
+
+	summary.Completed = completed
+	if completed > 0 {
+		summary.AvgDurationHours = totalHours / float64(completed)
+	}
+	if len(trips) > 0 {
+		summary.LastTripStart = trips[len(trips)-1].StartTime.Format(time.RFC3339)
+	}
+
+	return summary
+}
+
+// estimateMileage - оценка пробега по прямым между последовательными станциями
+func (l *locomotiveService) estimateMileage(records []domain.Record) responses.MileageEstimate {
+	estimate := responses.MileageEstimate{}
+
+	for i := 1; i < len(records); i++ {
+		from, to := records[i-1].Station, records[i].Station
+		if from == to {
+			continue
+		}
+		estimate.Segments++
+
+		a, okA := l.stations[from]
+		b, okB := l.stations[to]
+		if !okA || !okB {
+			estimate.UnmappedSegments++
+			continue
+		}
+		estimate.EstimatedKm += haversineKm(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+	}
+
+	return estimate
+}
+
+// uniqueStations - уникальные станции из записей локомотива
+func uniqueStations(records []domain.Record) map[string]bool {
+	stations := make(map[string]bool)
+	for _, rec := range records {
+		stations[rec.Station] = true
+	}
+	return stations
+}
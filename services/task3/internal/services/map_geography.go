@@ -0,0 +1,92 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// mapGeography - насколько карта соответствует реальной географии
+type mapGeography struct {
+	Generated       bool // координаты вымышленные (station_info.csv не загружен, нестрогий режим)
+	Unmapped        []responses.UnmappedStation
+	UnmappedRecords int
+}
+
+// loadMapGeography - координаты станций для карт и станции локомотивов, которых нет на карте.
+// В строгом режиме (opts.Strict) координаты никогда не выдумываются.
+func (v *visualizationService) loadMapGeography(
+	depoID string,
+	locomotives map[string]domain.Locomotive,
+	opts domain.MapOptions) (map[string]domain.Station, *mapGeography, error) {
+
+	stations, generated, err := v.getStationCoordinates(depoID, opts.Strict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	geo := &mapGeography{Generated: generated}
+	geo.Unmapped, geo.UnmappedRecords = collectUnmappedStations(locomotives, stations)
+	if len(geo.Unmapped) > 0 {
+		fmt.Printf("   ⚠️ Станций без координат: %d (отметок: %d)\n", len(geo.Unmapped), geo.UnmappedRecords)
+	}
+
+	return stations, geo, nil
+}
+
+// Warning - текст предупреждения для карты, пусто - география полная
+func (g *mapGeography) Warning() string {
+	var parts []string
+	if g.Generated {
+		parts = append(parts, "Координаты станций вымышленные: не удалось загрузить station_info.csv, карта не соответствует реальной географии.")
+	}
+	if len(g.Unmapped) > 0 {
+		parts = append(parts, fmt.Sprintf("Станций без координат: %d (отметок: %d) - они не показаны на карте, маршруты проведены без них.",
+			len(g.Unmapped), g.UnmappedRecords))
+	}
+	return strings.Join(parts, " ")
+}
+
+// collectUnmappedStations - станции из отметок локомотивов без координат (больше отметок - первыми)
+// и общее число таких отметок
+func collectUnmappedStations(
+	locomotives map[string]domain.Locomotive,
+	stations map[string]domain.Station) ([]responses.UnmappedStation, int) {
+
+	records := make(map[string]int)
+	locos := make(map[string]map[string]bool)
+	total := 0
+	for locKey, loc := range locomotives {
+		for _, rec := range loc.Records {
+			if _, exists := stations[rec.Station]; exists {
+				continue
+			}
+			records[rec.Station]++
+			if locos[rec.Station] == nil {
+				locos[rec.Station] = make(map[string]bool)
+			}
+			locos[rec.Station][locKey] = true
+			total++
+		}
+	}
+
+	result := make([]responses.UnmappedStation, 0, len(records))
+	for stationID, count := range records {
+		result = append(result, responses.UnmappedStation{
+			StationID:   stationID,
+			Records:     count,
+			Locomotives: len(locos[stationID]),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Records != result[j].Records {
+			return result[i].Records > result[j].Records
+		}
+		return result[i].StationID < result[j].StationID
+	})
+
+	return result, total
+}
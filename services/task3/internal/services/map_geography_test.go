@@ -0,0 +1,94 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+func TestCollectUnmappedStations(t *testing.T) {
+	stations := map[string]domain.Station{"D": {ID: "D"}, "A": {ID: "A"}}
+
+	tests := []struct {
+		name        string
+		locomotives map[string]domain.Locomotive
+		want        []responses.UnmappedStation
+		total       int
+	}{
+		{
+			name: "все станции на карте",
+			locomotives: map[string]domain.Locomotive{
+				"ТЭ/1": {Records: testRecords(utilizationMark{0, "D"}, utilizationMark{1, "A"})},
+			},
+			want: []responses.UnmappedStation{},
+		},
+		{
+			name: "больше отметок - первыми, при равенстве по коду станции",
+			locomotives: map[string]domain.Locomotive{
+				"ТЭ/1": {Records: testRecords(utilizationMark{0, "Y"}, utilizationMark{1, "A"}, utilizationMark{2, "Z"},
+					utilizationMark{3, "X"}, utilizationMark{4, "Y"})},
+				"ТЭ/2": {Records: testRecords(utilizationMark{0, "Y"}, utilizationMark{1, "X"}, utilizationMark{2, "D"})},
+			},
+			want: []responses.UnmappedStation{
+				{StationID: "Y", Records: 3, Locomotives: 2},
+				{StationID: "X", Records: 2, Locomotives: 2},
+				{StationID: "Z", Records: 1, Locomotives: 1},
+			},
+			total: 6,
+		},
+		{
+			name: "повторные отметки одного локомотива",
+			locomotives: map[string]domain.Locomotive{
+				"ТЭ/1": {Records: testRecords(utilizationMark{0, "X"}, utilizationMark{1, "X"}, utilizationMark{2, "X"})},
+			},
+			want:  []responses.UnmappedStation{{StationID: "X", Records: 3, Locomotives: 1}},
+			total: 3,
+		},
+		{
+			name: "нет локомотивов",
+			want: []responses.UnmappedStation{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total := collectUnmappedStations(tt.locomotives, stations)
+			if !reflect.DeepEqual(got, tt.want) || total != tt.total {
+				t.Errorf("станции %+v, отметок %d; ожидалось %+v, %d", got, total, tt.want, tt.total)
+			}
+		})
+	}
+}
+
+func TestMapGeographyWarning(t *testing.T) {
+	unmapped := []responses.UnmappedStation{{StationID: "X", Records: 3, Locomotives: 1}}
+
+	tests := []struct {
+		name string
+		geo  mapGeography
+		want string
+	}{
+		{name: "география полная", geo: mapGeography{}, want: ""},
+		{
+			name: "станции без координат",
+			geo:  mapGeography{Unmapped: unmapped, UnmappedRecords: 3},
+			want: "Станций без координат: 1 (отметок: 3) - они не показаны на карте, маршруты проведены без них.",
+		},
+		{
+			name: "вымышленные координаты и станции без координат",
+			geo:  mapGeography{Generated: true, Unmapped: unmapped, UnmappedRecords: 3},
+			want: "Координаты станций вымышленные: не удалось загрузить station_info.csv, карта не соответствует реальной географии. " +
+				"Станций без координат: 1 (отметок: 3) - они не показаны на карте, маршруты проведены без них.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.geo.Warning(); got != tt.want {
+				t.Errorf("Warning() = %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
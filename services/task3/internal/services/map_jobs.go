@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+const (
+	// mapJobManifest - описание задания в его директории (ответ генерации)
+	mapJobManifest = "job.json"
+	// maxMapJobs - одновременно выполняемых заданий (каждое загружает CSV целиком)
+	maxMapJobs = 2
+
+	mapJobEventProgress = "progress"
+	mapJobEventDone     = "done"
+	mapJobEventError    = "error"
+)
+
+// ErrTooManyMapJobs - все слоты генерации карт заняты, задание не запущено
+var ErrTooManyMapJobs = errors.New("слишком много одновременных заданий генерации карт, повторите позже")
+
+// Этапы генерации карт по порядку (Stage в событиях прогресса)
+const (
+	mapStageLoad = iota + 1
+	mapStageTrips
+	mapStageFilter
+	mapStageCoordinates
+	mapStageStats
+	mapStageRoutes
+	mapStageSort
+	mapStageOverview
+	mapStageHeatmap
+	mapStageBranches
+	mapStageFlow
+	mapStageLocomotives
+
+	// mapJobStages - количество этапов генерации карт (Total в событиях прогресса)
+	mapJobStages = iota
+)
+
+var mapJobStageIcons = [mapJobStages + 1]string{"", "1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣", "🔟", "1️⃣1️⃣", "1️⃣2️⃣"}
+
+// mapProgress - сообщает о переходе генерации карт к этапу stage
+type mapProgress func(stage int, message string)
+
+// mapJobRun - события задания генерации карт, запущенного на этом сервере
+type mapJobRun struct {
+	mu       sync.Mutex
+	events   []responses.MapJobEvent
+	done     bool
+	finished time.Time
+	updated  chan struct{} // закрывается при каждом новом событии
+}
+
+func newMapJobRun() *mapJobRun {
+	return &mapJobRun{
+		updated: make(chan struct{}),
+	}
+}
+
+// publish добавляет событие и будит ожидающих подписчиков
+func (r *mapJobRun) publish(event responses.MapJobEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+	if event.Event != mapJobEventProgress {
+		r.done = true
+		r.finished = time.Now()
+	}
+	close(r.updated)
+	r.updated = make(chan struct{})
+}
+
+// since - события начиная с from, признак завершения и канал ожидания следующих событий
+func (r *mapJobRun) since(from int) ([]responses.MapJobEvent, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[from:], r.done, r.updated
+}
+
+// last - последнее событие задания
+func (r *mapJobRun) last() (responses.MapJobEvent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return responses.MapJobEvent{}, false
+	}
+	return r.events[len(r.events)-1], r.done
+}
+
+// StartMapsJob - запускает генерацию карт в фоне, прогресс доступен через WatchMapJob
+func (v *visualizationService) StartMapsJob(depoID string, maxLocomotives int, opts domain.MapOptions) (string, error) {
+	select {
+	case v.jobSlots <- struct{}{}:
+	default:
+		return "", ErrTooManyMapJobs
+	}
+
+	jobID, err := v.newMapJob()
+	if err != nil {
+		<-v.jobSlots
+		return "", err
+	}
+
+	run := newMapJobRun()
+	v.jobsMu.Lock()
+	v.jobs[jobID] = run
+	v.jobsMu.Unlock()
+
+	go func() {
+		defer func() { <-v.jobSlots }()
+		// Паника генерации (например, в разборе данных) завершает задание, а не сервер
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Printf("❌ паника генерации карт %s: %v\n", jobID, r)
+				os.RemoveAll(filepath.Join(v.mapsDir, jobID))
+				run.publish(responses.MapJobEvent{
+					Event: mapJobEventError,
+					Error: fmt.Sprintf("внутренняя ошибка генерации: %v", r),
+				})
+			}
+		}()
+
+		report := func(stage int, message string) {
+			fmt.Printf("%s %s\n", mapJobStageIcons[stage], message)
+			run.publish(responses.MapJobEvent{
+				Event:   mapJobEventProgress,
+				Stage:   stage,
+				Total:   mapJobStages,
+				Message: message,
+			})
+		}
+
+		response, err := v.generateMaps(jobID, depoID, maxLocomotives, opts, report)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.RemoveAll(filepath.Join(v.mapsDir, jobID))
+			run.publish(responses.MapJobEvent{
+				Event: mapJobEventError,
+				Error: err.Error(),
+			})
+			return
+		}
+
+		run.publish(responses.MapJobEvent{
+			Event:  mapJobEventDone,
+			Result: response,
+		})
+	}()
+
+	return jobID, nil
+}
+
+// WatchMapJob - события задания с начала генерации; канал закрывается после
+// завершения задания или отмены ctx. false - задание не найдено.
+func (v *visualizationService) WatchMapJob(ctx context.Context, jobID string) (<-chan responses.MapJobEvent, bool) {
+	run := v.mapJobRun(jobID)
+	if run == nil {
+		// Задание завершено до перезапуска сервера - отдаем сохраненный результат
+		job, err := v.GetMapJob(jobID)
+		if err != nil || job == nil {
+			return nil, false
+		}
+		run = newMapJobRun()
+		run.publish(responses.MapJobEvent{
+			Event:  mapJobEventDone,
+			Result: &job.GenerateMapsResponse,
+		})
+	}
+
+	events := make(chan responses.MapJobEvent)
+	go func() {
+		defer close(events)
+
+		sent := 0
+		for {
+			batch, done, updated := run.since(sent)
+			for _, event := range batch {
+				select {
+				case events <- event:
+					sent++
+				case <-ctx.Done():
+					return
+				}
+			}
+			if done {
+				return
+			}
+
+			select {
+			case <-updated:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, true
+}
+
+func (v *visualizationService) mapJobRun(jobID string) *mapJobRun {
+	v.jobsMu.Lock()
+	defer v.jobsMu.Unlock()
+	return v.jobs[jobID]
+}
+
+// newMapJob - создает директорию нового задания генерации карт
+func (v *visualizationService) newMapJob() (string, error) {
+	jobID := uuid.New().String()
+	if err := os.MkdirAll(filepath.Join(v.mapsDir, jobID), 0755); err != nil {
+		return "", fmt.Errorf("не удалось создать директорию задания: %w", err)
+	}
+	return jobID, nil
+}
+
+// saveMapJob - сохраняет описание задания рядом с картами
+func (v *visualizationService) saveMapJob(response *responses.GenerateMapsResponse) error {
+	data, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(v.mapsDir, response.JobID, mapJobManifest), data, 0644)
+}
+
+// GetMapJob - задание генерации карт и список его файлов; nil - задание не найдено или удалено
+func (v *visualizationService) GetMapJob(jobID string) (*responses.MapJobResponse, error) {
+	// ID задания - UUID, иначе путь мог бы выйти за пределы директории карт
+	if _, err := uuid.Parse(jobID); err != nil {
+		return nil, nil
+	}
+
+	job := &responses.MapJobResponse{
+		GenerateMapsResponse: responses.GenerateMapsResponse{JobID: jobID},
+		Status:               "done",
+		Artifacts:            make([]responses.MapArtifact, 0),
+	}
+
+	// Задание этого сервера, которое еще выполняется или завершилось ошибкой
+	if run := v.mapJobRun(jobID); run != nil {
+		last, done := run.last()
+		switch {
+		case !done:
+			job.Status = "running"
+		case last.Event == mapJobEventError:
+			job.Status = "failed"
+			job.Error = last.Error
+			return job, nil
+		}
+	}
+
+	dir := filepath.Join(v.mapsDir, jobID)
+	if job.Status == "done" {
+		data, err := os.ReadFile(filepath.Join(dir, mapJobManifest))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &job.GenerateMapsResponse); err != nil {
+			return nil, fmt.Errorf("описание задания %s повреждено: %w", jobID, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == mapJobManifest {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		job.Artifacts = append(job.Artifacts, responses.MapArtifact{
+			Name: entry.Name(),
+			URL:  path.Join("/maps", jobID, entry.Name()),
+			Size: info.Size(),
+		})
+	}
+	sort.Slice(job.Artifacts, func(i, j int) bool {
+		return job.Artifacts[i].Name < job.Artifacts[j].Name
+	})
+
+	return job, nil
+}
+
+// StartJanitor - запускает периодическое удаление заданий старше ttl
+func (v *visualizationService) StartJanitor(ttl time.Duration) {
+	v.jobTTL = ttl
+	v.Cleanup()
+
+	// Проверяем несколько раз за время жизни задания, но не чаще раза в минуту
+	interval := ttl / 4
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			v.Cleanup()
+		}
+	}()
+}
+
+// Cleanup удаляет задания генерации карт старше TTL (без TTL задания не удаляются)
+func (v *visualizationService) Cleanup() {
+	if v.jobTTL <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-v.jobTTL)
+
+	// События завершенных заданий
+	v.jobsMu.Lock()
+	for jobID, run := range v.jobs {
+		run.mu.Lock()
+		expired := run.done && run.finished.Before(deadline)
+		run.mu.Unlock()
+		if expired {
+			delete(v.jobs, jobID)
+		}
+	}
+	v.jobsMu.Unlock()
+
+	entries, err := os.ReadDir(v.mapsDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := uuid.Parse(entry.Name()); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(deadline) {
+			continue
+		}
+		// Долгая генерация могла не успеть обновить директорию
+		if run := v.mapJobRun(entry.Name()); run != nil {
+			if _, done := run.last(); !done {
+				continue
+			}
+		}
+
+		if err := os.RemoveAll(filepath.Join(v.mapsDir, entry.Name())); err != nil {
+			fmt.Printf("⚠️ Не удалось удалить задание %s: %v\n", entry.Name(), err)
+		} else {
+			fmt.Printf("🗑️ Удалено задание генерации карт %s\n", entry.Name())
+		}
+	}
+}
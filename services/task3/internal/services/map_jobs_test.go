@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// testMapJobsData - две поездки двух локомотивов депо 589108 (станции из data/station_info.csv)
+const testMapJobsData = `series,number,datetime,station,depo
+ВЛ80С,1000,2024-01-01T22:00:00,589108,589108
+ВЛ80С,1000,2024-01-02T03:00:00,48442,589108
+ВЛ80С,1000,2024-01-02T04:00:00,311807,589108
+ВЛ80С,1000,2024-01-02T10:00:00,48442,589108
+ВЛ80С,1000,2024-01-02T16:00:00,589108,589108
+ВЛ80С,1001,2024-01-03T01:00:00,589108,589108
+ВЛ80С,1001,2024-01-03T05:00:00,48442,589108
+ВЛ80С,1001,2024-01-03T09:00:00,589108,589108
+`
+
+// testVisualizationService - сервис с директорией карт во временной директории теста
+func testVisualizationService(t *testing.T, dataPath string) *visualizationService {
+	t.Helper()
+	return &visualizationService{
+		dataPath:  dataPath,
+		mapsDir:   t.TempDir(),
+		tileLayer: osmTileLayer,
+		jobs:      make(map[string]*mapJobRun),
+		jobSlots:  make(chan struct{}, maxMapJobs),
+	}
+}
+
+// watchMapJob - все события задания до закрытия потока
+func watchMapJob(t *testing.T, v *visualizationService, jobID string) []responses.MapJobEvent {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	events, ok := v.WatchMapJob(ctx, jobID)
+	if !ok {
+		t.Fatalf("задание %s не найдено", jobID)
+	}
+	var result []responses.MapJobEvent
+	for event := range events {
+		result = append(result, event)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("задание %s не завершилось: %+v", jobID, result)
+	}
+	return result
+}
+
+func TestMapJobRunPublishSince(t *testing.T) {
+	run := newMapJobRun()
+
+	events, done, updated := run.since(0)
+	if len(events) != 0 || done {
+		t.Fatalf("новое задание: события %+v, завершено %v", events, done)
+	}
+
+	run.publish(responses.MapJobEvent{Event: mapJobEventProgress, Stage: mapStageLoad})
+	select {
+	case <-updated:
+	default:
+		t.Fatal("publish не разбудил ожидающих")
+	}
+
+	events, done, updated = run.since(0)
+	if len(events) != 1 || events[0].Stage != mapStageLoad || done {
+		t.Fatalf("после этапа: события %+v, завершено %v", events, done)
+	}
+	if last, done := run.last(); last.Stage != mapStageLoad || done {
+		t.Errorf("last = %+v, %v; ожидался этап %d", last, done, mapStageLoad)
+	}
+
+	run.publish(responses.MapJobEvent{Event: mapJobEventDone})
+	<-updated
+	events, done, _ = run.since(1)
+	if len(events) != 1 || events[0].Event != mapJobEventDone || !done {
+		t.Fatalf("после завершения: события %+v, завершено %v", events, done)
+	}
+	if run.finished.IsZero() {
+		t.Error("время завершения не задано")
+	}
+}
+
+func TestStartMapsJobTooManyJobs(t *testing.T) {
+	v := testVisualizationService(t, "unused.csv")
+	for i := 0; i < maxMapJobs; i++ {
+		v.jobSlots <- struct{}{}
+	}
+
+	jobID, err := v.StartMapsJob("589108", 1, domain.MapOptions{})
+	if !errors.Is(err, ErrTooManyMapJobs) || jobID != "" {
+		t.Fatalf("StartMapsJob = %q, %v; ожидалось %v", jobID, err, ErrTooManyMapJobs)
+	}
+	if entries, _ := os.ReadDir(v.mapsDir); len(entries) != 0 {
+		t.Errorf("создано заданий: %d, ожидалось 0", len(entries))
+	}
+
+	// Освободившийся слот снова принимает задание
+	<-v.jobSlots
+	jobID, err = v.StartMapsJob("589108", 1, domain.MapOptions{})
+	if err != nil || jobID == "" {
+		t.Fatalf("после освобождения слота: %q, %v", jobID, err)
+	}
+	watchMapJob(t, v, jobID)
+}
+
+func TestStartMapsJobStages(t *testing.T) {
+	dataPath := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(dataPath, []byte(testMapJobsData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := testVisualizationService(t, dataPath)
+
+	jobID, err := v.StartMapsJob("589108", 2, domain.MapOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := watchMapJob(t, v, jobID)
+
+	// Каждый этап ровно один раз, по порядку
+	if len(events) != mapJobStages+1 {
+		t.Fatalf("событий %d, ожидалось %d: %+v", len(events), mapJobStages+1, events)
+	}
+	for i, event := range events[:mapJobStages] {
+		if event.Event != mapJobEventProgress || event.Stage != i+1 || event.Total != mapJobStages {
+			t.Errorf("событие %d: %+v, ожидался этап %d из %d", i, event, i+1, mapJobStages)
+		}
+	}
+	done := events[mapJobStages]
+	if done.Event != mapJobEventDone || done.Result == nil || done.Result.JobID != jobID {
+		t.Fatalf("последнее событие %+v, ожидался результат задания %s", done, jobID)
+	}
+	if len(done.Result.Maps.Locomotives) != 2 {
+		t.Errorf("карт локомотивов %d, ожидалось 2", len(done.Result.Maps.Locomotives))
+	}
+
+	// После перезапуска сервера событий в памяти нет - результат читается из job.json
+	v.jobs = make(map[string]*mapJobRun)
+	replay := watchMapJob(t, v, jobID)
+	if len(replay) != 1 || replay[0].Event != mapJobEventDone || replay[0].Result == nil ||
+		replay[0].Result.Maps.Overview != done.Result.Maps.Overview {
+		t.Errorf("повтор из %s: %+v, ожидался результат %+v", mapJobManifest, replay, done.Result)
+	}
+}
+
+func TestStartMapsJobError(t *testing.T) {
+	v := testVisualizationService(t, filepath.Join(t.TempDir(), "missing.csv"))
+
+	jobID, err := v.StartMapsJob("589108", 1, domain.MapOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := watchMapJob(t, v, jobID)
+
+	if len(events) != 2 || events[0].Stage != mapStageLoad || events[1].Event != mapJobEventError || events[1].Error == "" {
+		t.Fatalf("события %+v, ожидались загрузка данных и ошибка", events)
+	}
+	if _, err := os.Stat(filepath.Join(v.mapsDir, jobID)); !os.IsNotExist(err) {
+		t.Errorf("директория задания с ошибкой не удалена: %v", err)
+	}
+	if job, err := v.GetMapJob(jobID); err != nil || job == nil || job.Status != "failed" {
+		t.Errorf("GetMapJob = %+v, %v; ожидался статус failed", job, err)
+	}
+}
+
+func TestGetMapJobInvalidID(t *testing.T) {
+	v := testVisualizationService(t, "unused.csv")
+	// Файл вне директории заданий, к которому ведет путь из ID
+	if err := os.WriteFile(filepath.Join(filepath.Dir(v.mapsDir), mapJobManifest), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, jobID := range []string{"../x", "..", "", "job", "3f2b8c1e-6d0a-4e55-9a43-0c7d2f1b9e10"} {
+		if job, err := v.GetMapJob(jobID); job != nil || err != nil {
+			t.Errorf("GetMapJob(%q) = %+v, %v; ожидалось nil", jobID, job, err)
+		}
+	}
+}
+
+func TestGetMapJobArtifactsIsolated(t *testing.T) {
+	v := testVisualizationService(t, "unused.csv")
+
+	files := map[string][]string{}
+	for _, name := range []string{"depot_1_overview.html", "depot_1_heatmap.html"} {
+		jobID, err := v.newMapJob()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, exists := files[jobID]; exists {
+			t.Fatalf("повторный ID задания %s", jobID)
+		}
+		if err := v.saveMapJob(&responses.GenerateMapsResponse{JobID: jobID, DepotID: "1"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := v.writeMapFile(jobID, name, []byte("<html></html>")); err != nil {
+			t.Fatal(err)
+		}
+		files[jobID] = []string{name}
+	}
+
+	for jobID, want := range files {
+		job, err := v.GetMapJob(jobID)
+		if err != nil || job == nil {
+			t.Fatalf("GetMapJob(%s) = %+v, %v", jobID, job, err)
+		}
+		if job.JobID != jobID || job.Status != "done" || job.DepotID != "1" {
+			t.Errorf("задание %+v, ожидалось %s", job.GenerateMapsResponse, jobID)
+		}
+		if len(job.Artifacts) != len(want) {
+			t.Fatalf("файлы задания %s: %+v, ожидалось %v", jobID, job.Artifacts, want)
+		}
+		for i, artifact := range job.Artifacts {
+			if artifact.Name != want[i] || artifact.URL != "/maps/"+jobID+"/"+want[i] {
+				t.Errorf("файл задания %s: %+v, ожидалось %s", jobID, artifact, want[i])
+			}
+		}
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	v := testVisualizationService(t, "unused.csv")
+	v.jobTTL = time.Hour
+	old := time.Now().Add(-2 * time.Hour)
+
+	newJob := func(modTime time.Time) string {
+		t.Helper()
+		jobID, err := v.newMapJob()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filepath.Join(v.mapsDir, jobID), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		return jobID
+	}
+
+	expired := newJob(old)
+	fresh := newJob(time.Now())
+
+	// Долгое задание еще выполняется - директория не обновлялась, но удалять ее нельзя
+	running := newJob(old)
+	v.jobs[running] = newMapJobRun()
+	v.jobs[running].publish(responses.MapJobEvent{Event: mapJobEventProgress, Stage: mapStageLoad})
+
+	// Завершенное задание: события в памяти удаляются вместе с директорией
+	finished := newJob(old)
+	v.jobs[finished] = newMapJobRun()
+	v.jobs[finished].publish(responses.MapJobEvent{Event: mapJobEventDone})
+	v.jobs[finished].finished = old
+
+	// Не задание (имя не UUID)
+	other := filepath.Join(v.mapsDir, "legacy")
+	if err := os.Mkdir(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(other, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	v.Cleanup()
+
+	for jobID, kept := range map[string]bool{expired: false, fresh: true, running: true, finished: false, "legacy": true} {
+		_, err := os.Stat(filepath.Join(v.mapsDir, jobID))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s: директория есть %v, ожидалось %v", jobID, exists, kept)
+		}
+	}
+	if _, exists := v.jobs[finished]; exists {
+		t.Error("события завершенного задания не удалены")
+	}
+	if _, exists := v.jobs[running]; !exists {
+		t.Error("события выполняемого задания удалены")
+	}
+
+	// Без TTL задания не удаляются
+	v.jobTTL = 0
+	stale := newJob(old)
+	v.Cleanup()
+	if _, err := os.Stat(filepath.Join(v.mapsDir, stale)); err != nil {
+		t.Errorf("задание удалено без TTL: %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/mapassets"
+)
+
+const (
+	// basemapLocal - подложка без тайлов, рисуется по станциям и перемещениям из данных
+	basemapLocal = "local"
+	// maxBasemapEdgeKm - более длинные скачки между отметками не рисуются как ребра сети
+	maxBasemapEdgeKm = 300.0
+	// mapAssetsURL - адрес встроенных ресурсов Leaflet на сервере
+	mapAssetsURL = "/static/map/"
+)
+
+// osmTileLayer - тайлы OpenStreetMap (по умолчанию и для файлов консольного режима)
+var osmTileLayer = domain.TileLayer{
+	URL:     "https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png",
+	MinZoom: 0,
+	MaxZoom: 19,
+}
+
+// mapAsset - подключение ресурса Leaflet: ссылка или встроенное в страницу содержимое
+type mapAsset struct {
+	URL    string
+	Script template.JS
+	Style  template.CSS
+}
+
+// mapPage - общие данные HTML карты: ресурсы Leaflet и подложка
+type mapPage struct {
+	LeafletCSS mapAsset
+	LeafletJS  mapAsset
+	HeatJS     mapAsset
+	Tiles      domain.TileLayer
+	Basemap    *localBasemap // nil - подложка из тайлов Tiles
+	Warning    string        // предупреждение о неполной или вымышленной географии, пусто - не показывается
+
+	job string // задание генерации (подкаталог директории карт); пусто - консольный режим
+}
+
+// localBasemap - подложка без тайлов
+type localBasemap struct {
+	Regions []basemapRegion `json:"regions"`
+	Edges   [][][]float64   `json:"edges"` // пары точек [lon, lat]
+}
+
+// basemapRegion - контур региона (выпуклая оболочка станций региона)
+type basemapRegion struct {
+	Name    string      `json:"name"`
+	Outline [][]float64 `json:"outline"`
+	Center  []float64   `json:"center"`
+}
+
+// mapLegendItem - строка легенды карты
+type mapLegendItem struct {
+	Color string
+	Label string
+}
+
+// overviewMapData - данные общей карты депо (overview.html)
+type overviewMapData struct {
+	mapPage
+	DepoID   string
+	Region   string
+	Legend   []mapLegendItem
+	Bounds   [][]float64
+	Stations []JSStation
+	Routes   []JSRoute
+}
+
+// heatmapData - данные тепловой карты (heatmap.html)
+type heatmapData struct {
+	mapPage
+	DepoID   string
+	Center   []float64
+	HeatData [][]float64 // [lat, lon, доля от максимума метрики]
+	Legend   heatmapLegend
+}
+
+// heatmapLegend - легенда тепловой карты: метрика, фильтры и значения на цветах градиента
+type heatmapLegend struct {
+	Title   string
+	Filters string
+	Stops   []heatmapLegendStop
+}
+
+type heatmapLegendStop struct {
+	Color string
+	Value string
+}
+
+// locomotiveMapData - данные карты локомотива (locomotive.html)
+type locomotiveMapData struct {
+	mapPage
+	Key          string
+	Series       string
+	Number       string
+	Depo         string
+	TripCount    int
+	StationCount int
+	Center       []float64
+	Stations     []map[string]interface{}
+	Routes       [][][]float64
+}
+
+// playbackMapData - данные карты воспроизведения перемещений локомотива (playback.html)
+type playbackMapData struct {
+	mapPage
+	Key    string
+	Series string
+	Number string
+	Depo   string
+	Visits []playbackVisit
+}
+
+// playbackVisit - посещение станции для воспроизведения, время в мс Unix
+type playbackVisit struct {
+	Station   string  `json:"station"`
+	Name      string  `json:"name"`
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+	Arrival   int64   `json:"arrival"`
+	Departure int64   `json:"departure"`
+	Trip      int     `json:"trip"`
+}
+
+// flowMapData - данные карты потоков по перегонам (flow.html)
+type flowMapData struct {
+	mapPage
+	DepoID   string
+	Region   string
+	Bounds   [][]float64
+	Legend   []mapLegendItem // цвета и число проходов
+	Segments []flowSegment
+	Top      []flowSegment // самые загруженные перегоны для таблицы
+}
+
+// flowSegment - перегон на карте потоков
+type flowSegment struct {
+	Rank        int         `json:"rank"`
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	FromName    string      `json:"from_name"`
+	ToName      string      `json:"to_name"`
+	Points      [][]float64 `json:"points"` // [lon, lat]
+	Traversals  int         `json:"traversals"`
+	Forward     int         `json:"forward"`
+	Backward    int         `json:"backward"`
+	Locomotives int         `json:"locomotives"`
+	DistanceKm  float64     `json:"distance_km"`
+	Color       string      `json:"color"`
+	Weight      float64     `json:"weight"`
+}
+
+// branchesMapData - данные карты веток депо (branches.html)
+type branchesMapData struct {
+	mapPage
+	DepoID   string
+	Region   string
+	Bounds   [][]float64
+	Branches []JSBranch
+}
+
+// newMapPage - ресурсы и подложка для HTML карт. Для API ресурсы и тайлы подключаются
+// с сервера, для консольного режима ресурсы встраиваются в саму страницу,
+// чтобы файл открывался без сети.
+func (v *visualizationService) newMapPage(
+	locomotives map[string]domain.Locomotive,
+	stations map[string]domain.Station,
+	opts domain.MapOptions,
+	inline bool) mapPage {
+
+	page := mapPage{
+		LeafletCSS: newMapAsset("leaflet.css", inline),
+		LeafletJS:  newMapAsset("leaflet.js", inline),
+		HeatJS:     newMapAsset("leaflet-heat.js", inline),
+		Tiles:      v.tileLayer,
+	}
+	if inline {
+		// Файл открывается с диска, адреса тайлов сервера недоступны
+		page.Tiles = osmTileLayer
+	}
+	if opts.Basemap == basemapLocal {
+		page.Basemap = buildLocalBasemap(locomotives, stations)
+	}
+	return page
+}
+
+// newMapAsset - ссылка на ресурс сервера или его содержимое (наличие ресурсов проверяет mapassets.Check при запуске)
+func newMapAsset(name string, inline bool) mapAsset {
+	if !inline {
+		return mapAsset{URL: mapAssetsURL + name}
+	}
+
+	data, _ := mapassets.Read(name)
+	if strings.HasSuffix(name, ".css") {
+		return mapAsset{Style: template.CSS(data)}
+	}
+	return mapAsset{Script: template.JS(data)}
+}
+
+// renderMap - заполняет шаблон карты и сохраняет его в директорию карт (или задания)
+func (v *visualizationService) renderMap(job, templateName, filename string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := mapassets.Templates.ExecuteTemplate(&buf, templateName, data); err != nil {
+		return "", fmt.Errorf("ошибка шаблона %s: %w", templateName, err)
+	}
+
+	return v.writeMapFile(job, filename, buf.Bytes())
+}
+
+// writeMapFile сохраняет файл задания в директории карт и возвращает его URL
+func (v *visualizationService) writeMapFile(job, filename string, content []byte) (string, error) {
+	dir := filepath.Join(v.mapsDir, job)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("не удалось создать директорию: %w", err)
+	}
+
+	fullPath := filepath.Join(dir, filename)
+	if err := os.WriteFile(fullPath, content, 0644); err != nil {
+		return "", err
+	}
+
+	return path.Join("/maps", job, filename), nil
+}
+
+// buildLocalBasemap - подложка по данным: ребра между последовательными станциями
+// всех локомотивов и контуры регионов по станциям, через которые они проходят
+func buildLocalBasemap(locomotives map[string]domain.Locomotive, stations map[string]domain.Station) *localBasemap {
+	basemap := &localBasemap{
+		Regions: make([]basemapRegion, 0),
+		Edges:   make([][][]float64, 0),
+	}
+
+	used := make(map[string]bool)
+	edges := make(map[[2]string]bool)
+	for _, loc := range locomotives {
+		for i, rec := range loc.Records {
+			used[rec.Station] = true
+			if i == 0 || loc.Records[i-1].Station == rec.Station {
+				continue
+			}
+
+			from, to := loc.Records[i-1].Station, rec.Station
+			if from > to {
+				from, to = to, from
+			}
+			edges[[2]string{from, to}] = true
+		}
+	}
+
+	keys := make([][2]string, 0, len(edges))
+	for edge := range edges {
+		keys = append(keys, edge)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] == keys[j][0] {
+			return keys[i][1] < keys[j][1]
+		}
+		return keys[i][0] < keys[j][0]
+	})
+
+	for _, edge := range keys {
+		a, okA := stations[edge[0]]
+		b, okB := stations[edge[1]]
+		if !okA || !okB || haversineKm(a.Latitude, a.Longitude, b.Latitude, b.Longitude) > maxBasemapEdgeKm {
+			continue
+		}
+		basemap.Edges = append(basemap.Edges, [][]float64{
+			{a.Longitude, a.Latitude},
+			{b.Longitude, b.Latitude},
+		})
+	}
+
+	// Станции по регионам (регион определяется по префиксу кода, как для депо)
+	regionPoints := make(map[string][][]float64)
+	for id := range used {
+		station, exists := stations[id]
+		if !exists {
+			continue
+		}
+		region := getRegionByDepo(id)
+		if region == "Россия" || region == "Неизвестно" {
+			continue
+		}
+		regionPoints[region] = append(regionPoints[region], []float64{station.Longitude, station.Latitude})
+	}
+
+	names := make([]string, 0, len(regionPoints))
+	for name := range regionPoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		outline := convexHull(regionPoints[name])
+		if len(outline) < 3 {
+			continue
+		}
+
+		center := []float64{0, 0}
+		for _, p := range outline {
+			center[0] += p[0] / float64(len(outline))
+			center[1] += p[1] / float64(len(outline))
+		}
+
+		basemap.Regions = append(basemap.Regions, basemapRegion{
+			Name:    name,
+			Outline: outline,
+			Center:  center,
+		})
+	}
+
+	return basemap
+}
+
+// convexHull - выпуклая оболочка точек [lon, lat] (алгоритм Эндрю)
+func convexHull(points [][]float64) [][]float64 {
+	if len(points) < 3 {
+		return points
+	}
+
+	sorted := make([][]float64, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i][0] == sorted[j][0] {
+			return sorted[i][1] < sorted[j][1]
+		}
+		return sorted[i][0] < sorted[j][0]
+	})
+
+	cross := func(o, a, b []float64) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+
+	hull := make([][]float64, 0, 2*len(sorted))
+	// Нижняя оболочка
+	for _, p := range sorted {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// Верхняя оболочка
+	lower := len(hull) + 1
+	for i := len(sorted) - 2; i >= 0; i-- {
+		p := sorted[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	return hull[:len(hull)-1]
+}
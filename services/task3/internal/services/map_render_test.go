@@ -0,0 +1,59 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvexHull(t *testing.T) {
+	tests := []struct {
+		name   string
+		points [][]float64
+		want   [][]float64
+	}{
+		{
+			name:   "квадрат с внутренней точкой - против часовой стрелки от левой нижней",
+			points: [][]float64{{2, 2}, {1, 1}, {0, 2}, {2, 0}, {0, 0}},
+			want:   [][]float64{{0, 0}, {2, 0}, {2, 2}, {0, 2}},
+		},
+		{
+			name:   "точки на сторонах не входят в оболочку",
+			points: [][]float64{{0, 0}, {1, 0}, {2, 0}, {2, 1}, {2, 2}, {0, 2}},
+			want:   [][]float64{{0, 0}, {2, 0}, {2, 2}, {0, 2}},
+		},
+		{
+			name:   "повторяющиеся точки",
+			points: [][]float64{{0, 0}, {0, 1}, {0, 0}, {1, 0}, {0, 1}},
+			want:   [][]float64{{0, 0}, {1, 0}, {0, 1}},
+		},
+		{
+			name:   "точки на одной прямой - отрезок",
+			points: [][]float64{{1, 1}, {0, 0}, {2, 2}},
+			want:   [][]float64{{0, 0}, {2, 2}},
+		},
+		{
+			name:   "меньше трех точек",
+			points: [][]float64{{1, 2}, {0, 0}},
+			want:   [][]float64{{1, 2}, {0, 0}},
+		},
+		{
+			name:   "координаты станций [lon, lat]",
+			points: [][]float64{{37.6, 55.7}, {30.3, 59.9}, {39.7, 47.2}, {36.0, 54.0}},
+			want:   [][]float64{{30.3, 59.9}, {39.7, 47.2}, {37.6, 55.7}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := make([][]float64, len(tt.points))
+			copy(input, tt.points)
+
+			if got := convexHull(tt.points); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convexHull = %v, ожидалось %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(input, tt.points) {
+				t.Errorf("convexHull изменил порядок точек: %v", tt.points)
+			}
+		})
+	}
+}
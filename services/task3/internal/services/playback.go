@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// trackVisit - посещение станции локомотивом: подряд идущие отметки на одной станции
+type trackVisit struct {
+	StationID string
+	Name      string
+	Lat       float64
+	Lon       float64
+	Arrival   time.Time // первая отметка на станции
+	Departure time.Time // последняя отметка на станции
+	Trip      int       // номер поездки (как в splitIntoTrips)
+	Records   int
+}
+
+// buildTrackVisits - посещения станций локомотивом в порядке времени.
+// Станции без координат пропускаются, номер поездки при этом сохраняется.
+func buildTrackVisits(loc domain.Locomotive, stations map[string]domain.Station) []trackVisit {
+	visits := make([]trackVisit, 0)
+
+	trip := 0
+	for i, rec := range loc.Records {
+		if i > 0 && loc.Records[i-1].Station == rec.Station && len(visits) > 0 &&
+			visits[len(visits)-1].StationID == rec.Station {
+			last := &visits[len(visits)-1]
+			last.Departure = rec.Timestamp
+			last.Records++
+		} else if station, exists := stations[rec.Station]; exists {
+			visits = append(visits, trackVisit{
+				StationID: rec.Station,
+				Name:      station.Name,
+				Lat:       station.Latitude,
+				Lon:       station.Longitude,
+				Arrival:   rec.Timestamp,
+				Departure: rec.Timestamp,
+				Trip:      trip,
+				Records:   1,
+			})
+		}
+
+		// Отметка в депо завершает поездку
+		if rec.Station == rec.Depo {
+			trip++
+		}
+	}
+
+	return visits
+}
+
+// GetLocomotiveTrackGeoJSON - посещения станций локомотивом с отметками времени в формате GeoJSON.
+// nil - локомотив не найден.
+func (v *visualizationService) GetLocomotiveTrackGeoJSON(locomotiveKey string) (*responses.FeatureCollection, error) {
+	locomotives := loadData(v.dataPath)
+
+	loc, exists := locomotives[locomotiveKey]
+	if !exists {
+		return nil, nil
+	}
+
+	stations, _, err := v.getStationCoordinates(loc.Depo, true)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := newFeatureCollection()
+	for i, visit := range buildTrackVisits(loc, stations) {
+		collection.Features = append(collection.Features, pointFeature(visit.Lon, visit.Lat,
+			map[string]interface{}{
+				"locomotive":   locomotiveKey,
+				"visit_index":  i,
+				"trip_index":   visit.Trip,
+				"station":      visit.StationID,
+				"name":         visit.Name,
+				"time":         visit.Arrival.Format(time.RFC3339),
+				"end_time":     visit.Departure.Format(time.RFC3339),
+				"duration_min": int(visit.Departure.Sub(visit.Arrival).Minutes()),
+				"records":      visit.Records,
+				"is_depot":     visit.StationID == loc.Depo,
+			}))
+	}
+
+	return collection, nil
+}
+
+// generateLocomotivePlaybackHTMLAPI создает карту воспроизведения перемещений локомотива
+// (ползунок времени, пуск/пауза, скорость) рядом с картой generateLocomotiveHTMLAPI
+func (v *visualizationService) generateLocomotivePlaybackHTMLAPI(
+	locomotiveKey string,
+	loc domain.Locomotive,
+	stations map[string]domain.Station,
+	page mapPage) (string, error) {
+
+	visits := buildTrackVisits(loc, stations)
+	if len(visits) == 0 {
+		return "", fmt.Errorf("у локомотива %s нет отметок на станциях с координатами", locomotiveKey)
+	}
+
+	jsVisits := make([]playbackVisit, 0, len(visits))
+	for _, visit := range visits {
+		jsVisits = append(jsVisits, playbackVisit{
+			Station:   visit.StationID,
+			Name:      visit.Name,
+			Lat:       visit.Lat,
+			Lon:       visit.Lon,
+			Arrival:   visit.Arrival.UnixMilli(),
+			Departure: visit.Departure.UnixMilli(),
+			Trip:      visit.Trip,
+		})
+	}
+
+	data := playbackMapData{
+		mapPage: page,
+		Key:     locomotiveKey,
+		Series:  loc.Series,
+		Number:  loc.Number,
+		Depo:    loc.Depo,
+		Visits:  jsVisits,
+	}
+
+	safeKey := strings.ReplaceAll(locomotiveKey, "-", "_")
+	return v.renderMap(page.job, "playback.html", fmt.Sprintf("locomotive_%s_playback.html", safeKey), data)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+func TestBuildTrackVisits(t *testing.T) {
+	stations := map[string]domain.Station{
+		"D": {ID: "D", Name: "Депо", Latitude: 55, Longitude: 37},
+		"A": {ID: "A", Name: "Станция A", Latitude: 56, Longitude: 38},
+		"B": {ID: "B", Name: "Станция B", Latitude: 57, Longitude: 39},
+	}
+
+	// visit - ожидаемое посещение: станция, часы прибытия и отправления, поездка, число отметок
+	type visit struct {
+		station            string
+		arrival, departure float64
+		trip, records      int
+	}
+
+	tests := []struct {
+		name  string
+		marks []utilizationMark
+		want  []visit
+	}{
+		{
+			name:  "подряд идущие отметки на станции - одно посещение",
+			marks: []utilizationMark{{0, "A"}, {1, "A"}, {3, "A"}, {4, "B"}},
+			want:  []visit{{"A", 0, 3, 0, 3}, {"B", 4, 4, 0, 1}},
+		},
+		{
+			name:  "отметка в депо завершает поездку",
+			marks: []utilizationMark{{0, "A"}, {1, "D"}, {2, "B"}, {3, "D"}, {4, "A"}},
+			want:  []visit{{"A", 0, 0, 0, 1}, {"D", 1, 1, 0, 1}, {"B", 2, 2, 1, 1}, {"D", 3, 3, 1, 1}, {"A", 4, 4, 2, 1}},
+		},
+		{
+			// Каждая отметка в депо - отдельная поездка, как в splitIntoTrips
+			name:  "несколько отметок в депо подряд",
+			marks: []utilizationMark{{0, "D"}, {2, "D"}, {3, "A"}},
+			want:  []visit{{"D", 0, 2, 0, 2}, {"A", 3, 3, 2, 1}},
+		},
+		{
+			name:  "станции без координат пропускаются",
+			marks: []utilizationMark{{0, "X"}, {1, "X"}, {2, "A"}, {3, "X"}, {4, "A"}},
+			want:  []visit{{"A", 2, 2, 0, 1}, {"A", 4, 4, 0, 1}},
+		},
+		{
+			name: "нет отметок",
+			want: []visit{},
+		},
+	}
+
+	hours := func(h float64) time.Time {
+		return utilizationStart.Add(time.Duration(h * float64(time.Hour)))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := testRecords(tt.marks...)
+			loc := domain.Locomotive{Series: "ТЭ", Number: "1", Depo: "D", Records: records}
+
+			want := make([]trackVisit, 0, len(tt.want))
+			for _, v := range tt.want {
+				station := stations[v.station]
+				want = append(want, trackVisit{
+					StationID: v.station,
+					Name:      station.Name,
+					Lat:       station.Latitude,
+					Lon:       station.Longitude,
+					Arrival:   hours(v.arrival),
+					Departure: hours(v.departure),
+					Trip:      v.trip,
+					Records:   v.records,
+				})
+			}
+
+			got := buildTrackVisits(loc, stations)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("посещения %+v, ожидалось %+v", got, want)
+			}
+
+			trips := splitIntoTrips(records)
+			for _, v := range got {
+				if v.Trip >= len(trips) || !contains(trips[v.Trip].Stations, v.StationID) {
+					t.Errorf("посещение %s не входит в поездку %d из splitIntoTrips", v.StationID, v.Trip)
+				}
+			}
+		})
+	}
+}
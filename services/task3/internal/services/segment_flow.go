@@ -0,0 +1,222 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+// defaultTopSegments - размер таблицы самых загруженных перегонов по умолчанию
+const defaultTopSegments = 20
+
+// defaultFlowBounds - границы пустой карты потоков ([[minLat, minLon], [maxLat, maxLon]])
+var defaultFlowBounds = [][]float64{{41, 19}, {70, 180}}
+
+// segmentFlow - проходы локомотивов депо по перегону между соседними станциями маршрута
+type segmentFlow struct {
+	From, To         string // ID станций, From < To
+	FromName, ToName string
+	Forward          int // проходов From -> To
+	Backward         int // проходов To -> From
+	Locomotives      map[string]bool
+	DistanceKm       float64
+	fromLat, fromLon float64
+	toLat, toLon     float64
+}
+
+// Traversals - проходов по перегону в обе стороны
+func (s *segmentFlow) Traversals() int {
+	return s.Forward + s.Backward
+}
+
+// buildSegmentFlows собирает проходы по перегонам (пары подряд идущих станций в отметках локомотива,
+// повторные отметки на станции схлопываются), самые загруженные - первыми. Отметки берутся целиком,
+// а не по поездкам: поездка начинается после депо, и перегон депо - первая станция в нее не входит.
+func buildSegmentFlows(locomotives map[string]domain.Locomotive, stations map[string]domain.Station) []*segmentFlow {
+	segments := make(map[[2]string]*segmentFlow)
+
+	for locKey, loc := range locomotives {
+		route := make([]string, 0, len(loc.Records))
+		for _, rec := range loc.Records {
+			route = append(route, rec.Station)
+		}
+		route = removeDuplicates(route)
+
+		for i := 1; i < len(route); i++ {
+			from, fromExists := stations[route[i-1]]
+			to, toExists := stations[route[i]]
+			if !fromExists || !toExists {
+				continue
+			}
+
+			key := [2]string{route[i-1], route[i]}
+			forward := key[0] < key[1]
+			if !forward {
+				from, to = to, from
+				key[0], key[1] = key[1], key[0]
+			}
+
+			segment, exists := segments[key]
+			if !exists {
+				segment = &segmentFlow{
+					From:        key[0],
+					To:          key[1],
+					FromName:    from.Name,
+					ToName:      to.Name,
+					Locomotives: make(map[string]bool),
+					DistanceKm:  haversineKm(from.Latitude, from.Longitude, to.Latitude, to.Longitude),
+					fromLat:     from.Latitude,
+					fromLon:     from.Longitude,
+					toLat:       to.Latitude,
+					toLon:       to.Longitude,
+				}
+				segments[key] = segment
+			}
+
+			if forward {
+				segment.Forward++
+			} else {
+				segment.Backward++
+			}
+			segment.Locomotives[locKey] = true
+		}
+	}
+
+	result := make([]*segmentFlow, 0, len(segments))
+	for _, segment := range segments {
+		result = append(result, segment)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Traversals() != result[j].Traversals() {
+			return result[i].Traversals() > result[j].Traversals()
+		}
+		if result[i].From != result[j].From {
+			return result[i].From < result[j].From
+		}
+		return result[i].To < result[j].To
+	})
+
+	return result
+}
+
+// topSegments - размер таблицы самых загруженных перегонов
+func topSegments(opts domain.MapOptions) int {
+	if opts.TopSegments <= 0 {
+		return defaultTopSegments
+	}
+	return opts.TopSegments
+}
+
+// generateFlowHTMLAPI создает карту потоков по перегонам (толщина и цвет - по числу проходов)
+// с таблицей самых загруженных перегонов
+func (v *visualizationService) generateFlowHTMLAPI(
+	depoID string,
+	segments []*segmentFlow,
+	opts domain.MapOptions,
+	page mapPage) (string, error) {
+
+	// Без перегонов (нет координат станций маршрутов) карта пустая, причину показывает предупреждение
+	maxTraversals := 0.0
+	if len(segments) > 0 {
+		maxTraversals = float64(segments[0].Traversals())
+	}
+	minLat, maxLat := 90.0, -90.0
+	minLon, maxLon := 180.0, -180.0
+
+	jsSegments := make([]flowSegment, 0, len(segments))
+	for i, segment := range segments {
+		share := float64(segment.Traversals()) / maxTraversals
+		jsSegments = append(jsSegments, flowSegment{
+			Rank:        i + 1,
+			From:        segment.From,
+			To:          segment.To,
+			FromName:    segment.FromName,
+			ToName:      segment.ToName,
+			Points:      [][]float64{{segment.fromLon, segment.fromLat}, {segment.toLon, segment.toLat}},
+			Traversals:  segment.Traversals(),
+			Forward:     segment.Forward,
+			Backward:    segment.Backward,
+			Locomotives: len(segment.Locomotives),
+			DistanceKm:  math.Round(segment.DistanceKm*10) / 10,
+			Color:       hexColor(heatColor(share)),
+			Weight:      math.Round((1.5+10.5*share)*10) / 10,
+		})
+
+		minLat = math.Min(minLat, math.Min(segment.fromLat, segment.toLat))
+		maxLat = math.Max(maxLat, math.Max(segment.fromLat, segment.toLat))
+		minLon = math.Min(minLon, math.Min(segment.fromLon, segment.toLon))
+		maxLon = math.Max(maxLon, math.Max(segment.fromLon, segment.toLon))
+	}
+
+	top := topSegments(opts)
+	if top > len(jsSegments) {
+		top = len(jsSegments)
+	}
+
+	bounds := [][]float64{{minLat, minLon}, {maxLat, maxLon}}
+	if len(segments) == 0 {
+		bounds = defaultFlowBounds
+	}
+
+	legend := make([]mapLegendItem, 0, len(heatGradient))
+	for i := len(heatGradient) - 1; i >= 0 && maxTraversals > 0; i-- {
+		legend = append(legend, mapLegendItem{
+			Color: heatGradient[i].color,
+			Label: fmt.Sprintf("%.0f", heatGradient[i].stop*maxTraversals),
+		})
+	}
+
+	data := flowMapData{
+		mapPage:  page,
+		DepoID:   depoID,
+		Region:   getRegionByDepo(depoID),
+		Bounds:   bounds,
+		Legend:   legend,
+		Segments: jsSegments,
+		Top:      jsSegments[:top],
+	}
+
+	return v.renderMap(page.job, "flow.html", fmt.Sprintf("depot_%s_flow.html", depoID), data)
+}
+
+// generateSegmentsCSVAPI сохраняет таблицу самых загруженных перегонов в CSV
+func (v *visualizationService) generateSegmentsCSVAPI(
+	depoID string,
+	segments []*segmentFlow,
+	opts domain.MapOptions,
+	job string) (string, error) {
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"rank", "from_id", "from_name", "to_id", "to_name",
+		"traversals", "forward", "backward", "locomotives", "distance_km"})
+
+	for i, segment := range segments {
+		if i >= topSegments(opts) {
+			break
+		}
+		w.Write([]string{
+			strconv.Itoa(i + 1),
+			segment.From,
+			segment.FromName,
+			segment.To,
+			segment.ToName,
+			strconv.Itoa(segment.Traversals()),
+			strconv.Itoa(segment.Forward),
+			strconv.Itoa(segment.Backward),
+			strconv.Itoa(len(segment.Locomotives)),
+			strconv.FormatFloat(segment.DistanceKm, 'f', 1, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+
+	return v.writeMapFile(job, fmt.Sprintf("depot_%s_segments.csv", depoID), buf.Bytes())
+}
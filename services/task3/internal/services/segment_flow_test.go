@@ -0,0 +1,107 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+// testLocomotive - локомотив депо с отметками на станциях по порядку, раз в час
+func testLocomotive(depo string, stations ...string) domain.Locomotive {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loc := domain.Locomotive{Series: "ТЭ", Number: "1", Depo: depo}
+	for i, station := range stations {
+		loc.Records = append(loc.Records, domain.Record{
+			Series:    loc.Series,
+			Number:    loc.Number,
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Station:   station,
+			Depo:      depo,
+		})
+	}
+	loc.Trips = splitIntoTrips(loc.Records)
+	return loc
+}
+
+// testStations - станции с координатами на одной широте
+func testStations(ids ...string) map[string]domain.Station {
+	stations := make(map[string]domain.Station, len(ids))
+	for i, id := range ids {
+		stations[id] = domain.Station{ID: id, Name: "Станция " + id, Latitude: 55, Longitude: 37 + float64(i)*0.1}
+	}
+	return stations
+}
+
+func TestBuildSegmentFlows(t *testing.T) {
+	type flow struct {
+		from, to          string
+		forward, backward int
+		locomotives       int
+	}
+
+	tests := []struct {
+		name        string
+		locomotives map[string]domain.Locomotive
+		stations    map[string]domain.Station
+		want        []flow
+	}{
+		{
+			name:        "перегоны от депо учитываются",
+			locomotives: map[string]domain.Locomotive{"ТЭ-1": testLocomotive("D", "D", "A", "B", "D", "C", "D")},
+			stations:    testStations("A", "B", "C", "D"),
+			want: []flow{
+				{from: "C", to: "D", forward: 1, backward: 1, locomotives: 1},
+				{from: "A", to: "B", forward: 1, locomotives: 1},
+				{from: "A", to: "D", backward: 1, locomotives: 1},
+				{from: "B", to: "D", forward: 1, locomotives: 1},
+			},
+		},
+		{
+			name:        "повторные отметки на станции схлопываются",
+			locomotives: map[string]domain.Locomotive{"ТЭ-1": testLocomotive("D", "A", "A", "A", "B", "B")},
+			stations:    testStations("A", "B"),
+			want:        []flow{{from: "A", to: "B", forward: 1, locomotives: 1}},
+		},
+		{
+			name:        "станции без координат пропускаются",
+			locomotives: map[string]domain.Locomotive{"ТЭ-1": testLocomotive("D", "A", "X", "B", "C")},
+			stations:    testStations("A", "B", "C"),
+			want:        []flow{{from: "B", to: "C", forward: 1, locomotives: 1}},
+		},
+		{
+			name: "проходы разных локомотивов суммируются",
+			locomotives: map[string]domain.Locomotive{
+				"ТЭ-1": testLocomotive("D", "A", "B"),
+				"ТЭ-2": testLocomotive("D", "B", "A", "B"),
+			},
+			stations: testStations("A", "B"),
+			want:     []flow{{from: "A", to: "B", forward: 2, backward: 1, locomotives: 2}},
+		},
+		{
+			name:        "одна станция - нет перегонов",
+			locomotives: map[string]domain.Locomotive{"ТЭ-1": testLocomotive("D", "A", "A")},
+			stations:    testStations("A"),
+			want:        []flow{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildSegmentFlows(tt.locomotives, tt.stations)
+			if len(got) != len(tt.want) {
+				t.Fatalf("перегонов %d, ожидалось %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				segment := got[i]
+				if segment.From != want.from || segment.To != want.to ||
+					segment.Forward != want.forward || segment.Backward != want.backward ||
+					len(segment.Locomotives) != want.locomotives {
+					t.Errorf("перегон %d: %s-%s %d/%d лок. %d, ожидалось %s-%s %d/%d лок. %d", i,
+						segment.From, segment.To, segment.Forward, segment.Backward, len(segment.Locomotives),
+						want.from, want.to, want.forward, want.backward, want.locomotives)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// seriesAccumulator - агрегаты задачи 2 по серии локомотивов
+type seriesAccumulator struct {
+	series      string
+	locomotives int
+	trips       int
+	depots      map[string]*seriesDepotAccumulator
+}
+
+// seriesDepotAccumulator - агрегаты серии внутри депо
+type seriesDepotAccumulator struct {
+	depoName    string
+	locomotives int
+	trips       int
+	visits      map[string]int    // ID направления -> поездки
+	names       map[string]string // ID направления -> название
+}
+
+// ListSeries - для API режима (сводка по всем сериям локомотивов)
+func (l *locomotiveService) ListSeries() (*responses.SeriesList, error) {
+	accumulators, err := l.collectSeriesStats()
+	if err != nil {
+		return nil, err
+	}
+
+	dominated := make(map[string]int)
+	for _, branch := range l.seriesBranchShares() {
+		if branch.Dominant {
+			dominated[branch.Series]++
+		}
+	}
+
+	list := &responses.SeriesList{
+		Series: make([]responses.SeriesSummary, 0, len(accumulators)),
+	}
+	for _, acc := range accumulators {
+		depots := make([]string, 0, len(acc.depots))
+		for depo := range acc.depots {
+			depots = append(depots, depo)
+		}
+		sort.Strings(depots)
+
+		list.Series = append(list.Series, responses.SeriesSummary{
+			Series:                acc.series,
+			LocomotiveCount:       acc.locomotives,
+			TotalTrips:            acc.trips,
+			AvgTripsPerLocomotive: float64(acc.trips) / float64(acc.locomotives),
+			Depots:                depots,
+			DominatedBranches:     dominated[acc.series],
+		})
+	}
+
+	// Сортируем по количеству локомотивов
+	sort.Slice(list.Series, func(i, j int) bool {
+		if list.Series[i].LocomotiveCount == list.Series[j].LocomotiveCount {
+			return list.Series[i].Series < list.Series[j].Series
+		}
+		return list.Series[i].LocomotiveCount > list.Series[j].LocomotiveCount
+	})
+
+	return list, nil
+}
+
+// GetSeriesAnalytics - для API режима (направления и ветки одной серии по депо)
+func (l *locomotiveService) GetSeriesAnalytics(series string) (*responses.SeriesAnalytics, error) {
+	accumulators, err := l.collectSeriesStats()
+	if err != nil {
+		return nil, err
+	}
+
+	var acc *seriesAccumulator
+	for name, a := range accumulators {
+		if strings.EqualFold(name, series) {
+			acc = a
+			break
+		}
+	}
+	if acc == nil {
+		return nil, nil
+	}
+
+	analytics := &responses.SeriesAnalytics{
+		Series:                acc.series,
+		LocomotiveCount:       acc.locomotives,
+		TotalTrips:            acc.trips,
+		AvgTripsPerLocomotive: float64(acc.trips) / float64(acc.locomotives),
+		Depots:                make([]responses.SeriesDepot, 0, len(acc.depots)),
+		Branches:              make([]responses.SeriesBranchShare, 0),
+	}
+
+	depots := make([]string, 0, len(acc.depots))
+	for depo := range acc.depots {
+		depots = append(depots, depo)
+	}
+	sort.Strings(depots)
+
+	for _, depo := range depots {
+		d := acc.depots[depo]
+		depot := responses.SeriesDepot{
+			DepoCode:              depo,
+			DepoName:              d.depoName,
+			LocomotiveCount:       d.locomotives,
+			TotalTrips:            d.trips,
+			AvgTripsPerLocomotive: float64(d.trips) / float64(d.locomotives),
+			Directions:            make([]responses.PeriodDirection, 0, len(d.visits)),
+		}
+
+		matched := 0
+		for _, visits := range d.visits {
+			matched += visits
+		}
+		for dirID, visits := range d.visits {
+			depot.Directions = append(depot.Directions, responses.PeriodDirection{
+				ID:     dirID,
+				Name:   d.names[dirID],
+				Visits: visits,
+				Share:  float64(visits) / float64(matched) * 100,
+			})
+		}
+
+		// Сортируем по количеству поездок
+		sort.Slice(depot.Directions, func(i, j int) bool {
+			if depot.Directions[i].Visits == depot.Directions[j].Visits {
+				return depot.Directions[i].ID < depot.Directions[j].ID
+			}
+			return depot.Directions[i].Visits > depot.Directions[j].Visits
+		})
+
+		analytics.Depots = append(analytics.Depots, depot)
+	}
+
+	for _, branch := range l.seriesBranchShares() {
+		if branch.Series == acc.series {
+			analytics.Branches = append(analytics.Branches, branch)
+		}
+	}
+
+	return analytics, nil
+}
+
+// collectSeriesStats - агрегирует статистику направлений задачи 2 по сериям и депо
+func (l *locomotiveService) collectSeriesStats() (map[string]*seriesAccumulator, error) {
+	task2, err := l.popularTrip.GetPopularDirections(domain.DirectionOptions{}, domain.PopularDirectionFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	accumulators := make(map[string]*seriesAccumulator)
+	for _, depot := range task2.Depots {
+		dirNames := make(map[string]string)
+		for _, dir := range depot.Directions {
+			dirNames[dir.ID] = dir.Name
+		}
+
+		for _, loco := range depot.Locomotives {
+			acc, exists := accumulators[loco.Model]
+			if !exists {
+				acc = &seriesAccumulator{
+					series: loco.Model,
+					depots: make(map[string]*seriesDepotAccumulator),
+				}
+				accumulators[loco.Model] = acc
+			}
+			acc.locomotives++
+			acc.trips += loco.TotalTrips
+
+			d, exists := acc.depots[depot.DepoCode]
+			if !exists {
+				d = &seriesDepotAccumulator{
+					depoName: depot.DepoName,
+					visits:   make(map[string]int),
+					names:    make(map[string]string),
+				}
+				acc.depots[depot.DepoCode] = d
+			}
+			d.locomotives++
+			d.trips += loco.TotalTrips
+
+			for _, dir := range loco.Directions {
+				d.visits[dir.ID] += dir.Visits
+				d.names[dir.ID] = dirNames[dir.ID]
+			}
+		}
+	}
+
+	return accumulators, nil
+}
+
+// seriesBranchShares - доли серий в поездках по каждой ветке депо (задача 1).
+// Серия доминирует на ветке, если на нее приходится наибольшая доля поездок.
+func (l *locomotiveService) seriesBranchShares() []responses.SeriesBranchShare {
+	locomotives := loadData(l.dataPath)
+	for key, loc := range locomotives {
+		loc.Trips = splitIntoTrips(loc.Records)
+		locomotives[key] = loc
+	}
+
+	depotBranches := buildImprovedBranches(locomotives)
+
+	depots := make([]string, 0, len(depotBranches))
+	for depo := range depotBranches {
+		depots = append(depots, depo)
+	}
+	sort.Strings(depots)
+
+	result := make([]responses.SeriesBranchShare, 0)
+	for _, depo := range depots {
+		for _, branch := range depotBranches[depo] {
+			if len(branch.Trips) == 0 {
+				continue
+			}
+
+			trips := make(map[string]int)
+			for _, trip := range branch.Trips {
+				trips[trip.Series]++
+			}
+
+			dominant := ""
+			for series, count := range trips {
+				if count > trips[dominant] || (count == trips[dominant] && series < dominant) {
+					dominant = series
+				}
+			}
+
+			seriesList := make([]string, 0, len(trips))
+			for series := range trips {
+				seriesList = append(seriesList, series)
+			}
+			sort.Strings(seriesList)
+
+			for _, series := range seriesList {
+				result = append(result, responses.SeriesBranchShare{
+					DepoCode:       depo,
+					BranchID:       branch.BranchID,
+					Series:         series,
+					Trips:          trips[series],
+					BranchTrips:    len(branch.Trips),
+					Share:          float64(trips[series]) / float64(len(branch.Trips)) * 100,
+					Dominant:       series == dominant,
+					DominantSeries: dominant,
+				})
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/staticmap"
+)
+
+// heatGradient - те же цвета, что у тепловой карты Leaflet (heatmap.html)
+var heatGradient = []struct {
+	stop  float64
+	color string
+}{
+	{0.2, "#0000ff"},
+	{0.4, "#00ffff"},
+	{0.6, "#00ff00"},
+	{0.8, "#ffff00"},
+	{1.0, "#ff0000"},
+}
+
+// RenderStaticMap собирает статичную карту депо (станции, тепловые круги, маршруты)
+// для отрисовки в PNG или SVG. nil - депо не найдено.
+func (v *visualizationService) RenderStaticMap(depoID string, opts domain.StaticMapOptions) (*staticmap.Map, error) {
+	depoLocomotives, stations, err := v.loadDepotMapData(depoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(depoLocomotives) == 0 {
+		return nil, nil
+	}
+
+	stationStats := sortedStationStats(v.collectStationStats(depoLocomotives, stations))
+	if len(stationStats) == 0 {
+		return nil, fmt.Errorf("у депо %s нет станций с координатами", depoID)
+	}
+
+	m := &staticmap.Map{
+		Width:  opts.Width,
+		Height: opts.Height,
+		Title:  fmt.Sprintf("Депо %s (%s)", depoID, getRegionByDepo(depoID)),
+	}
+
+	if opts.Has(domain.StaticLayerRoutes) {
+		routes := v.buildLocomotiveRoutes(depoLocomotives, stations)
+		colors := []string{"#FF6B6B", "#4ECDC4", "#45B7D1", "#96CEB4", "#FFEAA7", "#C7B198", "#DFC2C2", "#B2B2B2"}
+
+		for i, locKey := range getTopLocomotives(depoLocomotives, opts.MaxLocomotives) {
+			lineColor := staticmap.Hex(colors[i%len(colors)])
+			m.Legend = append(m.Legend, staticmap.LegendItem{Color: lineColor, Label: locKey})
+			for _, route := range routes[locKey] {
+				line := staticmap.Polyline{Color: staticmap.WithAlpha(lineColor, 0.7), Width: 3}
+				for _, p := range route.Points {
+					line.Points = append(line.Points, staticmap.Point{Lat: p.Lat, Lon: p.Lon})
+				}
+				m.Lines = append(m.Lines, line)
+			}
+		}
+	}
+
+	if opts.Has(domain.StaticLayerHeat) {
+		maxVisits := 0
+		for _, stat := range stationStats {
+			if stat.VisitCount > maxVisits {
+				maxVisits = stat.VisitCount
+			}
+		}
+
+		// Крупные круги рисуем первыми, чтобы мелкие оставались видны
+		heat := append([]*domain.StationStats(nil), stationStats...)
+		sort.SliceStable(heat, func(i, j int) bool {
+			return heat[i].VisitCount > heat[j].VisitCount
+		})
+		for _, stat := range heat {
+			m.Circles = append(m.Circles, staticmap.Circle{
+				Center: staticmap.Point{Lat: stat.Latitude, Lon: stat.Longitude},
+				Radius: 4 + 20*math.Sqrt(float64(stat.VisitCount)/float64(maxVisits)),
+				Fill:   staticmap.WithAlpha(heatColor(stat.Popularity), 0.45),
+			})
+		}
+
+		m.Legend = append(m.Legend,
+			staticmap.LegendItem{Color: heatColor(0.2), Label: "Низкая посещаемость"},
+			staticmap.LegendItem{Color: heatColor(0.6), Label: "Средняя посещаемость"},
+			staticmap.LegendItem{Color: heatColor(1), Label: fmt.Sprintf("Высокая (%d посещений)", maxVisits)})
+	}
+
+	if opts.Has(domain.StaticLayerStations) {
+		stationColor := staticmap.Hex("#333333")
+		depotColor := staticmap.Hex("#d62728")
+		white := staticmap.Hex("#ffffff")
+
+		var depot *domain.StationStats
+		for _, stat := range stationStats {
+			if stat.StationID == depoID {
+				depot = stat
+				continue
+			}
+			m.Circles = append(m.Circles, staticmap.Circle{
+				Center:      staticmap.Point{Lat: stat.Latitude, Lon: stat.Longitude},
+				Radius:      2.5,
+				Fill:        stationColor,
+				Stroke:      white,
+				StrokeWidth: 1,
+			})
+		}
+		if depot != nil {
+			m.Circles = append(m.Circles, staticmap.Circle{
+				Center:      staticmap.Point{Lat: depot.Latitude, Lon: depot.Longitude},
+				Radius:      7,
+				Fill:        depotColor,
+				Stroke:      white,
+				StrokeWidth: 2,
+			})
+			m.Legend = append(m.Legend, staticmap.LegendItem{Color: depotColor, Label: "Депо " + depoID})
+		}
+		m.Legend = append(m.Legend, staticmap.LegendItem{Color: stationColor, Label: "Станция"})
+	}
+
+	return m, nil
+}
+
+// heatColor - цвет градиента тепловой карты для популярности 0..1
+func heatColor(popularity float64) color.NRGBA {
+	if popularity <= heatGradient[0].stop {
+		return staticmap.Hex(heatGradient[0].color)
+	}
+	for i := 1; i < len(heatGradient); i++ {
+		if popularity <= heatGradient[i].stop {
+			from, to := staticmap.Hex(heatGradient[i-1].color), staticmap.Hex(heatGradient[i].color)
+			t := (popularity - heatGradient[i-1].stop) / (heatGradient[i].stop - heatGradient[i-1].stop)
+			mix := func(a, b uint8) uint8 {
+				return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+			}
+			return color.NRGBA{mix(from.R, to.R), mix(from.G, to.G), mix(from.B, to.B), 0xff}
+		}
+	}
+	return staticmap.Hex(heatGradient[len(heatGradient)-1].color)
+}
+
+// hexColor - цвет в записи #RRGGBB для HTML карт
+func hexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
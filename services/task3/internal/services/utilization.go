@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// locomotiveDays - состояние локомотива по дням: true - был на линии, false - весь день в депо
+type locomotiveDays map[time.Time]bool
+
+// GetUtilizationReport - для API режима (использование парка по локомотивам и депо)
+func (l *locomotiveService) GetUtilizationReport(opts domain.UtilizationOptions) (*responses.UtilizationReport, error) {
+	locomotives := loadData(l.dataPath)
+
+	keys := make([]string, 0, len(locomotives))
+	for key := range locomotives {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	depots := make(map[string]*responses.DepotUtilization)
+	depotDays := make(map[string]map[time.Time]*responses.DailyUtilization)
+	for _, key := range keys {
+		loc := locomotives[key]
+		if opts.Depo != "" && loc.Depo != opts.Depo {
+			continue
+		}
+
+		records := make([]domain.Record, 0, len(loc.Records))
+		for _, rec := range loc.Records {
+			if opts.Contains(rec.Timestamp) {
+				records = append(records, rec)
+			}
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		days := activityDays(records)
+		util := buildLocomotiveUtilization(loc, records, days)
+
+		depot, exists := depots[loc.Depo]
+		if !exists {
+			depot = &responses.DepotUtilization{
+				DepoCode:    loc.Depo,
+				DepoName:    loc.Depo,
+				Daily:       make([]responses.DailyUtilization, 0),
+				Locomotives: make([]responses.LocomotiveUtilization, 0),
+			}
+			if station, ok := l.stations[loc.Depo]; ok {
+				depot.DepoName = station.Name
+			}
+			depots[loc.Depo] = depot
+			depotDays[loc.Depo] = make(map[time.Time]*responses.DailyUtilization)
+		}
+
+		depot.LocomotiveCount++
+		depot.ObservedDays += util.ObservedDays
+		depot.ActiveDays += util.ActiveDays
+		depot.DepotDays += util.DepotDays
+		depot.Trips += util.Trips
+		if util.LongestIdleHours > depot.LongestIdleHours {
+			depot.LongestIdleHours = util.LongestIdleHours
+			depot.LongestIdleLocomotive = loc.Series + " " + loc.Number
+		}
+		depot.Locomotives = append(depot.Locomotives, util)
+
+		for day, online := range days {
+			daily, exists := depotDays[loc.Depo][day]
+			if !exists {
+				daily = &responses.DailyUtilization{Date: day.Format("2006-01-02")}
+				depotDays[loc.Depo][day] = daily
+			}
+			if online {
+				daily.OnLine++
+			} else {
+				daily.AtDepot++
+			}
+		}
+	}
+
+	if opts.Depo != "" && len(depots) == 0 {
+		return nil, nil
+	}
+
+	report := &responses.UtilizationReport{
+		Depots: make([]responses.DepotUtilization, 0, len(depots)),
+	}
+	if !opts.From.IsZero() {
+		report.From = opts.From.Format("2006-01-02")
+	}
+	if !opts.To.IsZero() {
+		report.To = opts.To.Format("2006-01-02")
+	}
+
+	codes := make([]string, 0, len(depots))
+	for code := range depots {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		depot := depots[code]
+		if depot.ObservedDays > 0 {
+			depot.ActiveShare = float64(depot.ActiveDays) / float64(depot.ObservedDays) * 100
+		}
+		if depot.ActiveDays > 0 {
+			depot.TripsPerActiveDay = float64(depot.Trips) / float64(depot.ActiveDays)
+		}
+
+		dates := make([]time.Time, 0, len(depotDays[code]))
+		for day := range depotDays[code] {
+			dates = append(dates, day)
+		}
+		sort.Slice(dates, func(i, j int) bool {
+			return dates[i].Before(dates[j])
+		})
+		for _, day := range dates {
+			depot.Daily = append(depot.Daily, *depotDays[code][day])
+		}
+
+		report.Depots = append(report.Depots, *depot)
+	}
+
+	return report, nil
+}
+
+// RunUtilizationReport - основной метод для консольного режима
+func (l *locomotiveService) RunUtilizationReport(opts domain.UtilizationOptions) {
+	report, err := l.GetUtilizationReport(opts)
+	if err != nil {
+		fmt.Printf("Ошибка построения отчета: %v\n", err)
+		return
+	}
+	if report == nil {
+		fmt.Printf("Депо %s не найдено\n", opts.Depo)
+		return
+	}
+
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Println("ИСПОЛЬЗОВАНИЕ ПАРКА ЛОКОМОТИВОВ")
+	fmt.Println(strings.Repeat("=", 80))
+	if report.From != "" || report.To != "" {
+		fmt.Printf("Период: %s - %s\n", report.From, report.To)
+	}
+
+	for _, depot := range report.Depots {
+		fmt.Printf("\n🏭 Депо %s (%s)\n", depot.DepoCode, depot.DepoName)
+		fmt.Printf("  • Локомотивов: %d\n", depot.LocomotiveCount)
+		fmt.Printf("  • Дней на линии: %d из %d (%.1f%%), в депо: %d\n",
+			depot.ActiveDays, depot.ObservedDays, depot.ActiveShare, depot.DepotDays)
+		fmt.Printf("  • Поездок: %d, на активный день: %.2f\n", depot.Trips, depot.TripsPerActiveDay)
+		if depot.LongestIdleLocomotive != "" {
+			fmt.Printf("  • Самый долгий простой: %.1f ч (%s)\n", depot.LongestIdleHours, depot.LongestIdleLocomotive)
+		}
+
+		fmt.Printf("\n  %-12s %-8s %8s %8s %8s %8s %12s\n",
+			"Серия", "Номер", "Линия", "Депо", "Доля,%", "Поездок", "Простой, ч")
+		for _, loc := range depot.Locomotives {
+			fmt.Printf("  %-12s %-8s %8d %8d %8.1f %8d %12.1f\n",
+				loc.Series, loc.Number, loc.ActiveDays, loc.DepotDays,
+				loc.ActiveShare, loc.Trips, loc.LongestIdleHours)
+		}
+
+		fmt.Printf("\n  %-12s %8s %8s\n", "Дата", "Линия", "Депо")
+		for _, daily := range depot.Daily {
+			fmt.Printf("  %-12s %8d %8d\n", daily.Date, daily.OnLine, daily.AtDepot)
+		}
+	}
+}
+
+// buildLocomotiveUtilization - показатели использования локомотива по его записям за период
+func buildLocomotiveUtilization(loc domain.Locomotive, records []domain.Record, days locomotiveDays) responses.LocomotiveUtilization {
+	util := responses.LocomotiveUtilization{
+		Series:       loc.Series,
+		Number:       loc.Number,
+		Depo:         loc.Depo,
+		ObservedDays: len(days),
+		Trips:        len(splitIntoTrips(records)),
+	}
+
+	for _, online := range days {
+		if online {
+			util.ActiveDays++
+		} else {
+			util.DepotDays++
+		}
+	}
+	if util.ObservedDays > 0 {
+		util.ActiveShare = float64(util.ActiveDays) / float64(util.ObservedDays) * 100
+	}
+	if util.ActiveDays > 0 {
+		util.TripsPerActiveDay = float64(util.Trips) / float64(util.ActiveDays)
+	}
+
+	// Простой - от первой отметки в депо до следующей отметки на линии
+	var idleStart time.Time
+	for i, rec := range records {
+		atDepot := rec.Station == rec.Depo
+		if atDepot && idleStart.IsZero() {
+			idleStart = rec.Timestamp
+		}
+		if idleStart.IsZero() || (atDepot && i < len(records)-1) {
+			continue
+		}
+
+		hours := rec.Timestamp.Sub(idleStart).Hours()
+		if hours > util.LongestIdleHours {
+			util.LongestIdleHours = hours
+			util.LongestIdleStart = idleStart.Format(time.RFC3339)
+		}
+		if !atDepot {
+			idleStart = time.Time{}
+		}
+	}
+
+	return util
+}
+
+// activityDays - календарные дни, покрытые записями локомотива. Между отметками
+// локомотив считается там, где была последняя отметка: день на линии, если
+// хотя бы часть дня локомотив был вне депо.
+func activityDays(records []domain.Record) locomotiveDays {
+	days := make(locomotiveDays)
+
+	for i, rec := range records {
+		online := rec.Station != rec.Depo
+		end := rec.Timestamp
+		if i < len(records)-1 {
+			end = records[i+1].Timestamp
+		}
+
+		for day := dayStart(rec.Timestamp); !day.After(end); day = day.AddDate(0, 0, 1) {
+			// Интервал, закончившийся ровно в полночь, не занимает следующий день
+			if day.Equal(end) && !day.Equal(rec.Timestamp) {
+				break
+			}
+			days[day] = days[day] || online
+		}
+	}
+
+	return days
+}
+
+// dayStart - начало календарного дня
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+)
+
+// utilizationStart - начало периода в тестах использования парка
+var utilizationStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// utilizationMark - отметка локомотива депо D на станции через hours часов от utilizationStart
+type utilizationMark struct {
+	hours   float64
+	station string
+}
+
+func testRecords(marks ...utilizationMark) []domain.Record {
+	records := make([]domain.Record, 0, len(marks))
+	for _, m := range marks {
+		records = append(records, domain.Record{
+			Series:    "ТЭ",
+			Number:    "1",
+			Timestamp: utilizationStart.Add(time.Duration(m.hours * float64(time.Hour))),
+			Station:   m.station,
+			Depo:      "D",
+		})
+	}
+	return records
+}
+
+func TestBuildLocomotiveUtilizationIdle(t *testing.T) {
+	tests := []struct {
+		name      string
+		marks     []utilizationMark
+		idleHours float64
+		idleStart float64 // часы от utilizationStart, -1 - простоя нет
+	}{
+		{name: "простой до выезда на линию", marks: []utilizationMark{{0, "D"}, {5, "A"}}, idleHours: 5, idleStart: 0},
+		{
+			name:      "несколько отметок в депо подряд - один простой",
+			marks:     []utilizationMark{{0, "D"}, {3, "D"}, {6, "D"}, {10, "A"}, {12, "D"}, {13, "A"}},
+			idleHours: 10, idleStart: 0,
+		},
+		{
+			name:      "самый долгий из нескольких простоев",
+			marks:     []utilizationMark{{0, "D"}, {2, "A"}, {3, "D"}, {20, "D"}, {21, "B"}},
+			idleHours: 18, idleStart: 3,
+		},
+		{name: "данные заканчиваются в депо", marks: []utilizationMark{{0, "A"}, {2, "D"}, {30, "D"}}, idleHours: 28, idleStart: 2},
+		{name: "локомотив не заходил в депо", marks: []utilizationMark{{0, "A"}, {2, "B"}}, idleStart: -1},
+		{name: "одна отметка в депо", marks: []utilizationMark{{0, "D"}}, idleStart: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := testRecords(tt.marks...)
+			loc := domain.Locomotive{Series: "ТЭ", Number: "1", Depo: "D", Records: records}
+			util := buildLocomotiveUtilization(loc, records, activityDays(records))
+
+			wantStart := ""
+			if tt.idleStart >= 0 {
+				wantStart = utilizationStart.Add(time.Duration(tt.idleStart * float64(time.Hour))).Format(time.RFC3339)
+			}
+			if math.Abs(util.LongestIdleHours-tt.idleHours) > 1e-9 || util.LongestIdleStart != wantStart {
+				t.Errorf("простой %v ч с %q, ожидалось %v ч с %q",
+					util.LongestIdleHours, util.LongestIdleStart, tt.idleHours, wantStart)
+			}
+		})
+	}
+}
+
+func TestActivityDays(t *testing.T) {
+	day := func(n int) time.Time {
+		return utilizationStart.AddDate(0, 0, n)
+	}
+
+	tests := []struct {
+		name  string
+		marks []utilizationMark
+		want  locomotiveDays
+	}{
+		{
+			// Полночь 4-го дня - конец интервала в депо и отметка на линии
+			name:  "на линии и в депо по дням",
+			marks: []utilizationMark{{10, "A"}, {36, "D"}, {72, "A"}},
+			want:  locomotiveDays{day(0): true, day(1): true, day(2): false, day(3): true},
+		},
+		{
+			name:  "весь день в депо",
+			marks: []utilizationMark{{8, "D"}, {20, "D"}},
+			want:  locomotiveDays{day(0): false},
+		},
+		{
+			name:  "интервал до полуночи не занимает следующий день",
+			marks: []utilizationMark{{20, "D"}, {24, "D"}, {30, "A"}},
+			want:  locomotiveDays{day(0): false, day(1): true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := activityDays(testRecords(tt.marks...))
+			if len(got) != len(tt.want) {
+				t.Fatalf("дней %d (%v), ожидалось %d", len(got), got, len(tt.want))
+			}
+			for d, online := range tt.want {
+				if state, ok := got[d]; !ok || state != online {
+					t.Errorf("%s: на линии %v (есть %v), ожидалось %v", d.Format("2006-01-02"), state, ok, online)
+				}
+			}
+		})
+	}
+
+	records := testRecords(utilizationMark{0, "D"}, utilizationMark{12, "A"}, utilizationMark{30, "D"}, utilizationMark{40, "A"})
+	util := buildLocomotiveUtilization(domain.Locomotive{Depo: "D"}, records, activityDays(records))
+	if util.ObservedDays != 2 || util.ActiveDays != 2 || util.DepotDays != 0 || util.ActiveShare != 100 {
+		t.Errorf("дни %+v, ожидалось 2 дня на линии", util)
+	}
+}
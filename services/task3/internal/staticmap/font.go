@@ -0,0 +1,106 @@
+package staticmap
+
+import "unicode"
+
+const (
+	glyphWidth   = 5
+	glyphAdvance = 6 // ширина символа с интервалом
+)
+
+// font - растровый шрифт 5x7: цифры, латиница и кириллица (строчные рисуются прописными),
+// строка глифа - 5 бит, старший бит слева
+var font = map[rune][7]byte{
+	' ': {},
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A': {0x0E, 0x11, 0x11, 0x11, 0x1F, 0x11, 0x11},
+	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	'А': {0x0E, 0x11, 0x11, 0x11, 0x1F, 0x11, 0x11},
+	'Б': {0x1F, 0x10, 0x10, 0x1E, 0x11, 0x11, 0x1E},
+	'В': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'Г': {0x1F, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10},
+	'Д': {0x06, 0x0A, 0x0A, 0x0A, 0x0A, 0x1F, 0x11},
+	'Е': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'Ё': {0x0A, 0x1F, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'Ж': {0x15, 0x15, 0x15, 0x0E, 0x15, 0x15, 0x15},
+	'З': {0x0E, 0x11, 0x01, 0x06, 0x01, 0x11, 0x0E},
+	'И': {0x11, 0x11, 0x13, 0x15, 0x19, 0x11, 0x11},
+	'Й': {0x0A, 0x04, 0x11, 0x13, 0x15, 0x19, 0x11},
+	'К': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'Л': {0x07, 0x09, 0x09, 0x09, 0x09, 0x09, 0x11},
+	'М': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'Н': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'О': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'П': {0x1F, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11},
+	'Р': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'С': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'Т': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'У': {0x11, 0x11, 0x11, 0x0F, 0x01, 0x11, 0x0E},
+	'Ф': {0x04, 0x0E, 0x15, 0x15, 0x15, 0x0E, 0x04},
+	'Х': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Ц': {0x12, 0x12, 0x12, 0x12, 0x12, 0x1F, 0x01},
+	'Ч': {0x11, 0x11, 0x11, 0x0F, 0x01, 0x01, 0x01},
+	'Ш': {0x15, 0x15, 0x15, 0x15, 0x15, 0x15, 0x1F},
+	'Щ': {0x15, 0x15, 0x15, 0x15, 0x15, 0x1F, 0x01},
+	'Ъ': {0x18, 0x08, 0x08, 0x0E, 0x09, 0x09, 0x0E},
+	'Ы': {0x11, 0x11, 0x11, 0x19, 0x15, 0x15, 0x19},
+	'Ь': {0x10, 0x10, 0x10, 0x1E, 0x11, 0x11, 0x1E},
+	'Э': {0x0E, 0x11, 0x01, 0x07, 0x01, 0x11, 0x0E},
+	'Ю': {0x12, 0x15, 0x15, 0x1D, 0x15, 0x15, 0x12},
+	'Я': {0x0F, 0x11, 0x11, 0x0F, 0x05, 0x09, 0x11},
+	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'_': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+	'+': {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	',': {0x00, 0x00, 0x00, 0x00, 0x0C, 0x04, 0x08},
+	':': {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'/': {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'%': {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+	'<': {0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02},
+	'>': {0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},
+	'=': {0x00, 0x00, 0x1F, 0x00, 0x1F, 0x00, 0x00},
+	'?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	'№': {0x12, 0x1A, 0x1A, 0x16, 0x16, 0x12, 0x13},
+}
+
+// glyph - строки глифа; неизвестные символы рисуются как '?'
+func glyph(r rune) [7]byte {
+	if g, ok := font[unicode.ToUpper(r)]; ok {
+		return g
+	}
+	return font['?']
+}
@@ -0,0 +1,189 @@
+package staticmap
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// PNG записывает карту в формате PNG
+func (m *Map) PNG(w io.Writer) error {
+	c := newCanvas(m.Width, m.Height)
+	pr := m.newProjection()
+
+	for _, line := range m.Lines {
+		points := make([][2]float64, 0, len(line.Points))
+		for _, p := range line.Points {
+			x, y := pr.project(p)
+			points = append(points, [2]float64{x, y})
+		}
+		c.polyline(points, line.Width, line.Color)
+	}
+
+	for _, circle := range m.Circles {
+		x, y := pr.project(circle.Center)
+		c.circle(x, y, circle.Radius, circle.Fill, circle.Stroke, circle.StrokeWidth)
+	}
+
+	if m.Title != "" {
+		const scale = 2
+		width := textWidth(m.Title, scale) + 20
+		c.rect(10, 10, width, 8*scale+14, panelColor, panelBorder)
+		c.text(20, 17, m.Title, scale, textColor)
+	}
+
+	if len(m.Legend) > 0 {
+		l := m.legendLayout(glyphAdvance, 20)
+		c.rect(int(l.x), int(l.y), int(l.width), int(l.height), panelColor, panelBorder)
+		for i, item := range m.Legend {
+			top := int(l.y) + 10 + i*int(l.row)
+			c.rect(int(l.x)+10, top, 14, 14, item.Color, item.Color)
+			c.text(int(l.x)+32, top+4, item.Label, 1, textColor)
+		}
+	}
+
+	return png.Encode(w, c.img)
+}
+
+// canvas - растровое изображение с непрозрачным фоном и сглаживанием фигур
+type canvas struct {
+	img  *image.RGBA
+	mask []float64 // покрытие пикселей ломаной, чтобы стыки отрезков не смешивались дважды
+}
+
+func newCanvas(width, height int) *canvas {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i] = backgroundColor.R
+		img.Pix[i+1] = backgroundColor.G
+		img.Pix[i+2] = backgroundColor.B
+		img.Pix[i+3] = 0xff
+	}
+	return &canvas{
+		img:  img,
+		mask: make([]float64, width*height),
+	}
+}
+
+// blend смешивает цвет с пикселем с учетом покрытия 0..1
+func (c *canvas) blend(x, y int, col color.NRGBA, coverage float64) {
+	if coverage <= 0 || !(image.Point{x, y}.In(c.img.Rect)) {
+		return
+	}
+	a := float64(col.A) / 255 * math.Min(1, coverage)
+	i := c.img.PixOffset(x, y)
+	pix := c.img.Pix[i : i+3 : i+3]
+	pix[0] = uint8(float64(col.R)*a + float64(pix[0])*(1-a) + 0.5)
+	pix[1] = uint8(float64(col.G)*a + float64(pix[1])*(1-a) + 0.5)
+	pix[2] = uint8(float64(col.B)*a + float64(pix[2])*(1-a) + 0.5)
+}
+
+// bounds - пиксели прямоугольника, обрезанного по изображению
+func (c *canvas) bounds(minX, minY, maxX, maxY float64) image.Rectangle {
+	r := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1)
+	return r.Intersect(c.img.Rect)
+}
+
+func (c *canvas) circle(cx, cy, radius float64, fill, stroke color.NRGBA, strokeWidth float64) {
+	outer := radius + strokeWidth/2 + 1
+	r := c.bounds(cx-outer, cy-outer, cx+outer, cy+outer)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			if fill.A > 0 {
+				c.blend(x, y, fill, radius-d+0.5)
+			}
+			if stroke.A > 0 && strokeWidth > 0 {
+				c.blend(x, y, stroke, strokeWidth/2-math.Abs(d-radius)+0.5)
+			}
+		}
+	}
+}
+
+func (c *canvas) polyline(points [][2]float64, width float64, col color.NRGBA) {
+	if len(points) < 2 || col.A == 0 {
+		return
+	}
+
+	half := width / 2
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	area := c.bounds(minX-half-1, minY-half-1, maxX+half+1, maxY+half+1)
+	stride := c.img.Rect.Dx()
+
+	// Покрытие - максимум по отрезкам, затем одно смешивание на пиксель
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		r := c.bounds(math.Min(a[0], b[0])-half-1, math.Min(a[1], b[1])-half-1,
+			math.Max(a[0], b[0])+half+1, math.Max(a[1], b[1])+half+1)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				d := segmentDistance(float64(x)+0.5, float64(y)+0.5, a, b)
+				if cov := half - d + 0.5; cov > c.mask[y*stride+x] {
+					c.mask[y*stride+x] = math.Min(1, cov)
+				}
+			}
+		}
+	}
+
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			c.blend(x, y, col, c.mask[y*stride+x])
+			c.mask[y*stride+x] = 0
+		}
+	}
+}
+
+// rect - прямоугольник с заливкой и рамкой в 1 пиксель
+func (c *canvas) rect(x, y, width, height int, fill, border color.NRGBA) {
+	for py := y; py < y+height; py++ {
+		for px := x; px < x+width; px++ {
+			col := fill
+			if px == x || py == y || px == x+width-1 || py == y+height-1 {
+				col = border
+			}
+			c.blend(px, py, col, 1)
+		}
+	}
+}
+
+// text - строка растровым шрифтом 5x7, scale - размер пикселя шрифта
+func (c *canvas) text(x, y int, s string, scale int, col color.NRGBA) {
+	for _, r := range s {
+		rows := glyph(r)
+		for row, bits := range rows {
+			for bit := 0; bit < glyphWidth; bit++ {
+				if bits&(1<<(glyphWidth-1-bit)) == 0 {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						c.blend(x+bit*scale+dx, y+row*scale+dy, col, 1)
+					}
+				}
+			}
+		}
+		x += glyphAdvance * scale
+	}
+}
+
+// textWidth - ширина строки растрового шрифта в пикселях
+func textWidth(s string, scale int) int {
+	return len([]rune(s)) * glyphAdvance * scale
+}
+
+// segmentDistance - расстояние от точки до отрезка ab
+func segmentDistance(px, py float64, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, ((px-a[0])*dx+(py-a[1])*dy)/lengthSq))
+	}
+	return math.Hypot(px-(a[0]+t*dx), py-(a[1]+t*dy))
+}
@@ -0,0 +1,173 @@
+// Package staticmap рисует статичные карты (PNG и SVG) без внешних зависимостей:
+// точки в проекции Web Mercator, круги, ломаные и легенда. Используется для
+// картинок в отчетах, где интерактивная HTML карта не подходит.
+package staticmap
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// padding - отступ от краев изображения до крайних точек карты
+	padding = 40.0
+	// maxMercatorLat - граница широты проекции Web Mercator
+	maxMercatorLat = 85.05112878
+)
+
+var (
+	backgroundColor = color.NRGBA{0xf7, 0xf5, 0xf0, 0xff}
+	textColor       = color.NRGBA{0x33, 0x33, 0x33, 0xff}
+	panelColor      = color.NRGBA{0xff, 0xff, 0xff, 0xe6}
+	panelBorder     = color.NRGBA{0xcc, 0xcc, 0xcc, 0xff}
+)
+
+// Point - географические координаты
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Circle - круг с центром в точке, радиус в пикселях
+type Circle struct {
+	Center      Point
+	Radius      float64
+	Fill        color.NRGBA
+	Stroke      color.NRGBA
+	StrokeWidth float64
+}
+
+// Polyline - ломаная (маршрут)
+type Polyline struct {
+	Points []Point
+	Color  color.NRGBA
+	Width  float64
+}
+
+// LegendItem - строка легенды
+type LegendItem struct {
+	Color color.NRGBA
+	Label string
+}
+
+// Map - сцена статичной карты. Слои рисуются в порядке: ломаные, круги, заголовок, легенда.
+type Map struct {
+	Width   int
+	Height  int
+	Title   string
+	Lines   []Polyline
+	Circles []Circle
+	Legend  []LegendItem
+}
+
+// Hex - цвет из записи #RRGGBB
+func Hex(s string) color.NRGBA {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil {
+		return textColor
+	}
+	return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
+
+// WithAlpha - цвет с заданной непрозрачностью 0..1
+func WithAlpha(c color.NRGBA, alpha float64) color.NRGBA {
+	c.A = uint8(math.Round(math.Max(0, math.Min(1, alpha)) * 255))
+	return c
+}
+
+// projection - Web Mercator, вписанный в изображение с сохранением пропорций
+type projection struct {
+	minX, maxY float64
+	scale      float64
+	offX, offY float64
+}
+
+func mercator(p Point) (float64, float64) {
+	lat := math.Max(-maxMercatorLat, math.Min(maxMercatorLat, p.Lat))
+	x := p.Lon * math.Pi / 180
+	y := math.Log(math.Tan(math.Pi/4 + lat*math.Pi/360))
+	return x, y
+}
+
+// newProjection - проекция, в которую помещаются все точки карты
+func (m *Map) newProjection() projection {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	add := func(p Point) {
+		x, y := mercator(p)
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	for _, line := range m.Lines {
+		for _, p := range line.Points {
+			add(p)
+		}
+	}
+	for _, c := range m.Circles {
+		add(c.Center)
+	}
+
+	if math.IsInf(minX, 1) {
+		return projection{scale: 1, offX: float64(m.Width) / 2, offY: float64(m.Height) / 2}
+	}
+
+	// Одна точка или точки на одной линии - показываем окрестность
+	const minSpan = 0.002
+	if maxX-minX < minSpan {
+		minX, maxX = (minX+maxX)/2-minSpan/2, (minX+maxX)/2+minSpan/2
+	}
+	if maxY-minY < minSpan {
+		minY, maxY = (minY+maxY)/2-minSpan/2, (minY+maxY)/2+minSpan/2
+	}
+
+	width := math.Max(1, float64(m.Width)-2*padding)
+	height := math.Max(1, float64(m.Height)-2*padding)
+	scale := math.Min(width/(maxX-minX), height/(maxY-minY))
+
+	return projection{
+		minX:  minX,
+		maxY:  maxY,
+		scale: scale,
+		offX:  (float64(m.Width) - (maxX-minX)*scale) / 2,
+		offY:  (float64(m.Height) - (maxY-minY)*scale) / 2,
+	}
+}
+
+// project - координаты точки в пикселях
+func (pr projection) project(p Point) (float64, float64) {
+	x, y := mercator(p)
+	return pr.offX + (x-pr.minX)*pr.scale, pr.offY + (pr.maxY-y)*pr.scale
+}
+
+// legendLayout - размеры панели легенды (в пикселях) при ширине символа charWidth
+type legendLayout struct {
+	x, y          float64
+	width, height float64
+	row           float64
+}
+
+func (m *Map) legendLayout(charWidth, row float64) legendLayout {
+	longest := 0
+	for _, item := range m.Legend {
+		if n := len([]rune(item.Label)); n > longest {
+			longest = n
+		}
+	}
+	width := 10 + 14 + 8 + float64(longest)*charWidth + 10
+	height := 10 + float64(len(m.Legend))*row + 6
+
+	return legendLayout{
+		x:      float64(m.Width) - width - 10,
+		y:      10,
+		width:  width,
+		height: height,
+		row:    row,
+	}
+}
+
+func hexString(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,112 @@
+package staticmap
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestProjection(t *testing.T) {
+	tests := []struct {
+		name  string
+		m     Map
+		probe []Point
+		want  [][2]float64
+	}{
+		{
+			name:  "пустая карта - центр изображения",
+			m:     Map{Width: 200, Height: 100},
+			probe: []Point{{}},
+			want:  [][2]float64{{100, 50}},
+		},
+		{
+			name:  "одна точка - в центре",
+			m:     Map{Width: 200, Height: 100, Circles: []Circle{{Center: Point{Lat: 55.75, Lon: 37.62}}}},
+			probe: []Point{{Lat: 55.75, Lon: 37.62}},
+			want:  [][2]float64{{100, 50}},
+		},
+		{
+			name:  "точки по долготе - растянуты по ширине за вычетом отступов",
+			m:     Map{Width: 200, Height: 100, Lines: []Polyline{{Points: []Point{{0, 0}, {0, 10}}}}},
+			probe: []Point{{0, 0}, {0, 10}, {0, 5}},
+			want:  [][2]float64{{40, 50}, {160, 50}, {100, 50}},
+		},
+		{
+			name:  "точки по широте - север сверху, по высоте за вычетом отступов",
+			m:     Map{Width: 200, Height: 100, Circles: []Circle{{Center: Point{0, 0}}, {Center: Point{10, 0}}}},
+			probe: []Point{{10, 0}, {0, 0}},
+			want:  [][2]float64{{100, 40}, {100, 60}},
+		},
+		{
+			name:  "широта за границей проекции ограничивается",
+			m:     Map{Width: 200, Height: 100, Circles: []Circle{{Center: Point{90, 0}}, {Center: Point{89, 0}}}},
+			probe: []Point{{90, 0}, {89, 0}},
+			want:  [][2]float64{{100, 50}, {100, 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := tt.m.newProjection()
+			for i, p := range tt.probe {
+				x, y := pr.project(p)
+				if math.Abs(x-tt.want[i][0]) > 1e-6 || math.Abs(y-tt.want[i][1]) > 1e-6 {
+					t.Errorf("project(%+v) = (%v, %v), ожидалось %v", p, x, y, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProjectionKeepsAspect(t *testing.T) {
+	m := Map{Width: 400, Height: 300, Circles: []Circle{
+		{Center: Point{Lat: 59.93, Lon: 30.31}},
+		{Center: Point{Lat: 55.75, Lon: 37.62}},
+		{Center: Point{Lat: 56.84, Lon: 60.60}},
+	}}
+	pr := m.newProjection()
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range m.Circles {
+		x, y := pr.project(c.Center)
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+
+	if minX < padding-1e-6 || maxX > float64(m.Width)-padding+1e-6 ||
+		minY < padding-1e-6 || maxY > float64(m.Height)-padding+1e-6 {
+		t.Errorf("точки (%v..%v, %v..%v) выходят за отступы", minX, maxX, minY, maxY)
+	}
+	if math.Abs((minX+maxX)/2-float64(m.Width)/2) > 1e-6 || math.Abs((minY+maxY)/2-float64(m.Height)/2) > 1e-6 {
+		t.Errorf("точки (%v..%v, %v..%v) не по центру", minX, maxX, minY, maxY)
+	}
+	// Одна из сторон занимает всю доступную длину, масштаб по осям одинаковый
+	if math.Abs(minX-padding) > 1e-6 && math.Abs(minY-padding) > 1e-6 {
+		t.Errorf("точки (%v..%v, %v..%v) не вписаны в изображение", minX, maxX, minY, maxY)
+	}
+}
+
+func TestHex(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.NRGBA
+	}{
+		{"#1f77b4", color.NRGBA{0x1f, 0x77, 0xb4, 0xff}},
+		{"d62728", color.NRGBA{0xd6, 0x27, 0x28, 0xff}},
+		{"#zzz", textColor},
+	}
+
+	for _, tt := range tests {
+		if got := Hex(tt.in); got != tt.want {
+			t.Errorf("Hex(%q) = %v, ожидалось %v", tt.in, got, tt.want)
+		}
+	}
+
+	base := color.NRGBA{1, 2, 3, 0xff}
+	for alpha, want := range map[float64]uint8{-1: 0, 0.5: 128, 2: 255} {
+		if got := WithAlpha(base, alpha); got.A != want || got.R != 1 {
+			t.Errorf("WithAlpha(%v) = %v, ожидалось A=%d", alpha, got, want)
+		}
+	}
+}
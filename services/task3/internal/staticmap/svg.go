@@ -0,0 +1,94 @@
+package staticmap
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+const (
+	svgFontSize  = 12.0
+	svgCharWidth = 7.0 // средняя ширина символа для расчета панели легенды
+)
+
+// SVG записывает карту в формате SVG
+func (m *Map) SVG(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	pr := m.newProjection()
+
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Arial, sans-serif">`+"\n",
+		m.Width, m.Height, m.Width, m.Height)
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hexString(backgroundColor))
+
+	for _, line := range m.Lines {
+		if len(line.Points) < 2 {
+			continue
+		}
+		points := make([]string, 0, len(line.Points))
+		for _, p := range line.Points {
+			x, y := pr.project(p)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		fmt.Fprintf(out, `<polyline points="%s" fill="none" stroke-linejoin="round" stroke-linecap="round"%s/>`+"\n",
+			strings.Join(points, " "), svgStroke(line.Color, line.Width))
+	}
+
+	for _, c := range m.Circles {
+		x, y := pr.project(c.Center)
+		fmt.Fprintf(out, `<circle cx="%.1f" cy="%.1f" r="%.1f"%s%s/>`+"\n",
+			x, y, c.Radius, svgFill(c.Fill), svgStroke(c.Stroke, c.StrokeWidth))
+	}
+
+	if m.Title != "" {
+		width := float64(len([]rune(m.Title)))*svgCharWidth*1.3 + 20
+		fmt.Fprintf(out, `<rect x="10" y="10" width="%.0f" height="30" rx="4"%s%s/>`+"\n",
+			width, svgFill(panelColor), svgStroke(panelBorder, 1))
+		fmt.Fprintf(out, `<text x="20" y="31" font-size="%.0f" font-weight="bold"%s>%s</text>`+"\n",
+			svgFontSize*1.3, svgFill(textColor), svgEscape(m.Title))
+	}
+
+	if len(m.Legend) > 0 {
+		l := m.legendLayout(svgCharWidth, 20)
+		fmt.Fprintf(out, `<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" rx="4"%s%s/>`+"\n",
+			l.x, l.y, l.width, l.height, svgFill(panelColor), svgStroke(panelBorder, 1))
+		for i, item := range m.Legend {
+			top := l.y + 10 + float64(i)*l.row
+			fmt.Fprintf(out, `<rect x="%.0f" y="%.0f" width="14" height="14" rx="2"%s/>`+"\n",
+				l.x+10, top, svgFill(item.Color))
+			fmt.Fprintf(out, `<text x="%.0f" y="%.0f" font-size="%.0f"%s>%s</text>`+"\n",
+				l.x+32, top+11, svgFontSize, svgFill(textColor), svgEscape(item.Label))
+		}
+	}
+
+	fmt.Fprintln(out, "</svg>")
+	return out.Flush()
+}
+
+func svgFill(c color.NRGBA) string {
+	if c.A == 0 {
+		return ` fill="none"`
+	}
+	if c.A == 0xff {
+		return fmt.Sprintf(` fill="%s"`, hexString(c))
+	}
+	return fmt.Sprintf(` fill="%s" fill-opacity="%.2f"`, hexString(c), float64(c.A)/255)
+}
+
+func svgStroke(c color.NRGBA, width float64) string {
+	if c.A == 0 || width <= 0 {
+		return ""
+	}
+	if c.A == 0xff {
+		return fmt.Sprintf(` stroke="%s" stroke-width="%.1f"`, hexString(c), width)
+	}
+	return fmt.Sprintf(` stroke="%s" stroke-width="%.1f" stroke-opacity="%.2f"`, hexString(c), width, float64(c.A)/255)
+}
+
+func svgEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
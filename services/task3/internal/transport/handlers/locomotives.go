@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/services"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/requests"
+)
+
+type LocomotiveHandler struct {
+	locomotiveService services.LocomotiveService
+}
+
+func NewLocomotiveHandler(locomotiveService services.LocomotiveService) *LocomotiveHandler {
+	return &LocomotiveHandler{
+		locomotiveService: locomotiveService,
+	}
+}
+
+// ListLocomotives возвращает каталог локомотивов с поиском и пагинацией
+// @Summary List locomotives
+// @Description Returns locomotives filtered by series/number prefix and depot
+// @Tags locomotives
+// @Produce json
+// @Param series query string false "Series prefix"
+// @Param number query string false "Number prefix"
+// @Param depo query string false "Depot code"
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size"
+// @Success 200 {object} responses.LocomotiveList
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/locomotives [get]
+func (h *LocomotiveHandler) ListLocomotives(c *gin.Context) {
+	var req requests.LocomotiveListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.locomotiveService.ListLocomotives(domain.LocomotiveFilter{
+		Series: req.Series,
+		Number: req.Number,
+		Depo:   req.Depo,
+		Page:   req.Page,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list locomotives: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetLocomotiveProfile возвращает профиль локомотива
+// @Summary Get locomotive profile
+// @Description Returns trips, directions, depot branches and estimated mileage of a locomotive
+// @Tags locomotives
+// @Produce json
+// @Param series path string true "Locomotive series"
+// @Param number path string true "Locomotive number"
+// @Success 200 {object} responses.LocomotiveProfile
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/locomotives/{series}/{number} [get]
+func (h *LocomotiveHandler) GetLocomotiveProfile(c *gin.Context) {
+	var req requests.LocomotiveProfileRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.locomotiveService.GetLocomotiveProfile(req.Series, req.Number)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to build locomotive profile: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Locomotive not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// ListSeries возвращает сводку по сериям локомотивов
+// @Summary List locomotive series
+// @Description Returns locomotive count, trips per locomotive and dominated branches for each series
+// @Tags locomotives
+// @Produce json
+// @Success 200 {object} responses.SeriesList
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/series [get]
+func (h *LocomotiveHandler) ListSeries(c *gin.Context) {
+	data, err := h.locomotiveService.ListSeries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list series: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetSeriesAnalytics возвращает аналитику по серии локомотивов
+// @Summary Get series analytics
+// @Description Returns direction distribution per depot and branch shares of a locomotive series
+// @Tags locomotives
+// @Produce json
+// @Param series path string true "Locomotive series"
+// @Success 200 {object} responses.SeriesAnalytics
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/series/{series} [get]
+func (h *LocomotiveHandler) GetSeriesAnalytics(c *gin.Context) {
+	var req requests.SeriesAnalyticsRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.locomotiveService.GetSeriesAnalytics(req.Series)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to build series analytics: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Series not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetUtilizationReport возвращает отчет об использовании парка
+// @Summary Get fleet utilization report
+// @Description Returns active days, days in depot, trips per active day, longest idle stretch and a daily on-line/at-depot series per depot
+// @Tags locomotives
+// @Produce json
+// @Param depo query string false "Depot code"
+// @Param from query string false "Period start (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Period end, exclusive (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} responses.UtilizationReport
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/utilization [get]
+func (h *LocomotiveHandler) GetUtilizationReport(c *gin.Context) {
+	var req requests.UtilizationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := domain.UtilizationOptions{Depo: req.Depo}
+	var err error
+	if opts.From, err = parseTimeParam(req.From); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opts.To, err = parseTimeParam(req.To); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.locomotiveService.GetUtilizationReport(opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to build utilization report: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Depot not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
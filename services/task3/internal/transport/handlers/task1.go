@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
 	"github.com/mihnpro/Hackathon_TMX/internal/services"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/requests"
 )
 
 type Task1Handler struct {
@@ -71,6 +75,157 @@ func (h *Task1Handler) GetDepotBranches(c *gin.Context) {
 	c.JSON(http.StatusOK, data)
 }
 
+// GetBranchDetails возвращает детализацию ветки депо
+// @Summary Get branch details
+// @Description Returns locomotives, weekly usage and path variants of a depot branch
+// @Tags task1
+// @Accept json
+// @Produce json
+// @Param depo path string true "Depot code"
+// @Param branchId path string true "Branch ID"
+// @Success 200 {object} responses.BranchDetails
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task1/depots/{depo}/branches/{branchId} [get]
+func (h *Task1Handler) GetBranchDetails(c *gin.Context) {
+	var req requests.BranchDetailsRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.task1Service.GetBranchDetails(req.Depo, req.BranchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to analyze branches: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Branch not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetLocomotiveBranches возвращает ветки депо, которые обслуживает локомотив
+// @Summary Get locomotive branches
+// @Description Returns depot branches served by a locomotive with trip counts and last run
+// @Tags task1
+// @Accept json
+// @Produce json
+// @Param series path string true "Locomotive series"
+// @Param number path string true "Locomotive number"
+// @Success 200 {object} responses.LocomotiveBranches
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task1/locomotives/{series}/{number}/branches [get]
+func (h *Task1Handler) GetLocomotiveBranches(c *gin.Context) {
+	var req requests.LocomotiveBranchesRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.task1Service.GetLocomotiveBranches(req.Series, req.Number)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to analyze branches: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Locomotive not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// CompareBranches сравнивает ветки двух прогонов
+// @Summary Compare branches
+// @Description Returns added, removed and modified branches between two datasets or time windows
+// @Tags task1
+// @Accept json
+// @Produce json
+// @Param base_data query string false "Base dataset: CSV file name in the service data directory"
+// @Param target_data query string false "Target dataset: CSV file name in the service data directory"
+// @Param base_from query string false "Base window start (YYYY-MM-DD or RFC3339)"
+// @Param base_to query string false "Base window end, exclusive (YYYY-MM-DD or RFC3339)"
+// @Param target_from query string false "Target window start (YYYY-MM-DD or RFC3339)"
+// @Param target_to query string false "Target window end, exclusive (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} responses.BranchDiff
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task1/diff [get]
+func (h *Task1Handler) CompareBranches(c *gin.Context) {
+	var req requests.BranchDiffRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	base := domain.BranchRun{Dataset: req.BaseData}
+	target := domain.BranchRun{Dataset: req.TargetData}
+	params := []struct {
+		value string
+		dst   *time.Time
+	}{
+		{req.BaseFrom, &base.From},
+		{req.BaseTo, &base.To},
+		{req.TargetFrom, &target.From},
+		{req.TargetTo, &target.To},
+	}
+	for _, p := range params {
+		t, err := parseTimeParam(p.value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		*p.dst = t
+	}
+
+	// Пустое окно дало бы пустой прогон, и все ветки другого прогона оказались бы добавленными
+	if !base.From.IsZero() && !base.To.IsZero() && !base.From.Before(base.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "base_from must be before base_to"})
+		return
+	}
+	if !target.From.IsZero() && !target.To.IsZero() && !target.From.Before(target.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_from must be before target_to"})
+		return
+	}
+
+	if base == target {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Base and target runs must differ",
+		})
+		return
+	}
+
+	data, err := h.task1Service.CompareBranches(base, target)
+	if errors.Is(err, services.ErrUnknownDataset) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to compare branches: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
 // GetAllDepots возвращает список всех депо
 // @Summary Get all depots
 // @Description Returns list of all depot codes
@@ -97,4 +252,15 @@ func (h *Task1Handler) GetAllDepots(c *gin.Context) {
 		"total_depots": len(depots),
 		"depots":       depots,
 	})
-}
\ No newline at end of file
+}
+
+// parseTimeParam разбирает дату из query-параметра (YYYY-MM-DD или RFC3339)
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
+	"github.com/mihnpro/Hackathon_TMX/internal/services"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
+)
+
+// fakeAlgorithmService - сервис задачи 1, запоминающий сравниваемые прогоны
+type fakeAlgorithmService struct {
+	services.AlgorithmService
+	calls        int
+	base, target domain.BranchRun
+}
+
+func (f *fakeAlgorithmService) CompareBranches(base, target domain.BranchRun) (*responses.BranchDiff, error) {
+	f.calls++
+	f.base, f.target = base, target
+	return &responses.BranchDiff{}, nil
+}
+
+func TestCompareBranchesWindows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		base   domain.BranchRun
+		target domain.BranchRun
+	}{
+		{
+			name:   "два окна",
+			query:  "base_from=2024-01-01&base_to=2024-01-15&target_from=2024-01-15",
+			status: http.StatusOK,
+			base:   domain.BranchRun{From: day(1), To: day(15)},
+			target: domain.BranchRun{From: day(15)},
+		},
+		{name: "начало базового окна после конца", query: "base_from=2024-01-15&base_to=2024-01-01", status: http.StatusBadRequest},
+		{name: "базовое окно нулевой длины", query: "base_from=2024-01-15&base_to=2024-01-15", status: http.StatusBadRequest},
+		{name: "пустое сравниваемое окно", query: "base_to=2024-01-15&target_from=2024-01-20&target_to=2024-01-10", status: http.StatusBadRequest},
+		{name: "одинаковые прогоны", query: "base_from=2024-01-01&target_from=2024-01-01", status: http.StatusBadRequest},
+		{name: "некорректная дата", query: "base_to=31.01.2024", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeAlgorithmService{}
+			router := gin.New()
+			router.GET("/diff", NewTask1Handler(service).CompareBranches)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/diff?"+tt.query, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("статус %d (%s), ожидалось %d", w.Code, w.Body.String(), tt.status)
+			}
+			if tt.status != http.StatusOK {
+				if service.calls != 0 {
+					t.Error("сравнение запущено для некорректного запроса")
+				}
+				return
+			}
+			if service.base != tt.base || service.target != tt.target {
+				t.Errorf("прогоны %+v, %+v; ожидалось %+v, %+v", service.base, service.target, tt.base, tt.target)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
 	"github.com/mihnpro/Hackathon_TMX/internal/services"
 	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/requests"
 
@@ -22,7 +23,29 @@ func NewTask2Handler(task2Service services.MostPopularTripService) *Task2Handler
 
 // GetPopularDirections возвращает результаты анализа популярных направлений
 func (h *Task2Handler) GetPopularDirections(c *gin.Context) {
-	data, err := h.task2Service.GetPopularDirections()
+	var match requests.DirectionMatchRequest
+	if err := c.ShouldBindQuery(&match); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.PopularDirectionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := domain.PopularDirectionFilter{
+		Depo:      req.Depo,
+		Series:    req.Series,
+		MinTrips:  req.MinTrips,
+		Direction: req.Direction,
+		Sort:      req.Sort,
+		Page:      req.Page,
+		Limit:     req.Limit,
+	}
+
+	data, err := h.task2Service.GetPopularDirections(directionOptions(match), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to analyze popular directions: " + err.Error(),
@@ -41,7 +64,13 @@ func (h *Task2Handler) GetLocomotivePopularDirection(c *gin.Context) {
 		return
 	}
 
-	data, err := h.task2Service.GetLocomotivePopularDirection(req.Series, req.Number)
+	var match requests.DirectionMatchRequest
+	if err := c.ShouldBindQuery(&match); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.task2Service.GetLocomotivePopularDirection(req.Series, req.Number, directionOptions(match))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to analyze popular directions: " + err.Error(),
@@ -58,3 +87,125 @@ func (h *Task2Handler) GetLocomotivePopularDirection(c *gin.Context) {
 
 	c.JSON(http.StatusOK, data)
 }
+
+// GetLocomotiveDirectionHistory возвращает любимое направление локомотива по периодам и смены направления
+func (h *Task2Handler) GetLocomotiveDirectionHistory(c *gin.Context) {
+	var req requests.LocomotiveDirectionRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var match requests.DirectionMatchRequest
+	if err := c.ShouldBindQuery(&match); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var history requests.DirectionHistoryRequest
+	if err := c.ShouldBindQuery(&history); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.task2Service.GetLocomotiveDirectionHistory(req.Series, req.Number, history.Bucket, directionOptions(match))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to analyze direction history: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Locomotive not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetLocomotiveNextDirection возвращает распределение вероятностей следующего направления локомотива
+func (h *Task2Handler) GetLocomotiveNextDirection(c *gin.Context) {
+	var req requests.LocomotiveDirectionRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var match requests.DirectionMatchRequest
+	if err := c.ShouldBindQuery(&match); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var next requests.NextDirectionRequest
+	if err := c.ShouldBindQuery(&next); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.task2Service.GetLocomotiveNextDirection(req.Series, req.Number, next.Order, directionOptions(match))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to predict next direction: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Locomotive not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetLocomotiveClusters возвращает группы локомотивов со схожим профилем эксплуатации
+func (h *Task2Handler) GetLocomotiveClusters(c *gin.Context) {
+	var match requests.DirectionMatchRequest
+	if err := c.ShouldBindQuery(&match); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.LocomotiveClustersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clusterOpts := domain.ClusterOptions{
+		Depo: req.Depo,
+		K:    req.K,
+	}
+
+	data, err := h.task2Service.GetLocomotiveClusters(clusterOpts, directionOptions(match))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to cluster locomotives: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Depot not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// directionOptions преобразует параметры запроса в настройки анализа направлений
+func directionOptions(req requests.DirectionMatchRequest) domain.DirectionOptions {
+	return domain.DirectionOptions{
+		MinSimilarity: req.MinSimilarity,
+		SplitVariants: req.SplitVariants,
+		Baseline:      req.Baseline,
+	}
+}
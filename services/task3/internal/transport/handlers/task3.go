@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	
+	"github.com/mihnpro/Hackathon_TMX/internal/domain"
 	"github.com/mihnpro/Hackathon_TMX/internal/services"
+	"github.com/mihnpro/Hackathon_TMX/internal/staticmap"
+	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/requests"
 	"github.com/mihnpro/Hackathon_TMX/internal/transport/models/responses"
 )
 
@@ -19,15 +26,16 @@ func NewTask3Handler(task3Service services.VisualizationService) *Task3Handler {
 	}
 }
 
-// GenerateMaps генерирует карты для депо
+// GenerateMaps запускает генерацию карт для депо в фоне
 // @Summary Generate maps for depot
-// @Description Generates overview map, heatmap and locomotive maps for a depot
+// @Description Starts generation of overview map, heatmap and locomotive maps for a depot as a background job. Stations without coordinates are reported in the result; strict mode fails instead of inventing coordinates
 // @Tags task3
 // @Accept json
 // @Produce json
 // @Param request body responses.GenerateMapsRequest true "Generation parameters"
-// @Success 200 {object} responses.GenerateMapsResponse
+// @Success 202 {object} responses.MapJobAccepted
 // @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/task3/generate [post]
 func (h *Task3Handler) GenerateMaps(c *gin.Context) {
@@ -42,7 +50,32 @@ func (h *Task3Handler) GenerateMaps(c *gin.Context) {
 		req.MaxLocomotives = 10
 	}
 
-	data, err := h.task3Service.GenerateMapsAPI(req.DepoID, req.MaxLocomotives)
+	opts := domain.MapOptions{
+		Basemap:     req.Basemap,
+		HeatMetric:  req.HeatMetric,
+		Series:      req.Series,
+		TopSegments: req.TopSegments,
+		Strict:      req.Strict,
+	}
+	var err error
+	if opts.From, err = parseTimeParam(req.From); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opts.To, err = parseTimeParam(req.To); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && !opts.From.Before(opts.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	jobID, err := h.task3Service.StartMapsJob(req.DepoID, req.MaxLocomotives, opts)
+	if errors.Is(err, services.ErrTooManyMapJobs) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to generate maps: " + err.Error(),
@@ -50,7 +83,42 @@ func (h *Task3Handler) GenerateMaps(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, data)
+	c.JSON(http.StatusAccepted, responses.MapJobAccepted{
+		JobID:     jobID,
+		Status:    "running",
+		EventsURL: "/api/v1/task3/jobs/" + jobID + "/events",
+		JobURL:    "/api/v1/task3/maps/" + jobID,
+	})
+}
+
+// GetJobEvents передает прогресс генерации карт как Server-Sent Events
+// @Summary Stream map generation progress
+// @Description Streams stage progress of a map generation job as Server-Sent Events (progress, then done with GenerateMapsResponse or error)
+// @Tags task3
+// @Produce text/event-stream
+// @Param id path string true "Job ID"
+// @Success 200 {object} responses.MapJobEvent
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/task3/jobs/{id}/events [get]
+func (h *Task3Handler) GetJobEvents(c *gin.Context) {
+	events, ok := h.task3Service.WatchMapJob(c.Request.Context(), c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Map job not found",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent(event.Event, event)
+		return true
+	})
 }
 
 // GetAvailableDepots возвращает список всех депо
@@ -98,4 +166,236 @@ func (h *Task3Handler) GetDepotInfo(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, info)
-}
\ No newline at end of file
+}
+
+// GetMapJob возвращает задание генерации карт и список его файлов
+// @Summary Get map generation job
+// @Description Returns a map generation job with the list of generated artifacts
+// @Tags task3
+// @Produce json
+// @Param jobId path string true "Job ID"
+// @Success 200 {object} responses.MapJobResponse
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task3/maps/{jobId} [get]
+func (h *Task3Handler) GetMapJob(c *gin.Context) {
+	job, err := h.task3Service.GetMapJob(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get map job: " + err.Error(),
+		})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Map job not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetStationsGeoJSON возвращает станции депо в формате GeoJSON
+// @Summary Get depot stations as GeoJSON
+// @Description Returns visited stations of a depot with visit statistics as a GeoJSON FeatureCollection
+// @Tags task3
+// @Produce json
+// @Param depo path string true "Depot ID"
+// @Success 200 {object} responses.FeatureCollection
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task3/depots/{depo}/stations.geojson [get]
+func (h *Task3Handler) GetStationsGeoJSON(c *gin.Context) {
+	h.respondGeoJSON(c, h.task3Service.GetStationsGeoJSON)
+}
+
+// GetRoutesGeoJSON возвращает маршруты локомотивов депо в формате GeoJSON
+// @Summary Get depot routes as GeoJSON
+// @Description Returns locomotive trip routes of a depot as a GeoJSON FeatureCollection of LineStrings
+// @Tags task3
+// @Produce json
+// @Param depo path string true "Depot ID"
+// @Success 200 {object} responses.FeatureCollection
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task3/depots/{depo}/routes.geojson [get]
+func (h *Task3Handler) GetRoutesGeoJSON(c *gin.Context) {
+	h.respondGeoJSON(c, h.task3Service.GetRoutesGeoJSON)
+}
+
+// GetHeatGeoJSON возвращает точки тепловой карты депо в формате GeoJSON
+// @Summary Get depot heatmap points as GeoJSON
+// @Description Returns weighted heatmap points of a depot as a GeoJSON FeatureCollection
+// @Tags task3
+// @Produce json
+// @Param depo path string true "Depot ID"
+// @Success 200 {object} responses.FeatureCollection
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task3/depots/{depo}/heat.geojson [get]
+func (h *Task3Handler) GetHeatGeoJSON(c *gin.Context) {
+	h.respondGeoJSON(c, h.task3Service.GetHeatGeoJSON)
+}
+
+// GetLocomotiveTrackGeoJSON возвращает посещения станций локомотивом с отметками времени в формате GeoJSON
+// @Summary Get locomotive track as time-stamped GeoJSON
+// @Description Returns station visits of a locomotive (one Point per visit with arrival and departure times) as a GeoJSON FeatureCollection for trip playback
+// @Tags task3
+// @Produce json
+// @Param series path string true "Locomotive series"
+// @Param number path string true "Locomotive number"
+// @Success 200 {object} responses.FeatureCollection
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task3/locomotives/{series}/{number}/track.geojson [get]
+func (h *Task3Handler) GetLocomotiveTrackGeoJSON(c *gin.Context) {
+	locomotiveKey := c.Param("series") + "-" + c.Param("number")
+
+	data, err := h.task3Service.GetLocomotiveTrackGeoJSON(locomotiveKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to build GeoJSON: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Locomotive not found",
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/geo+json; charset=utf-8")
+	c.JSON(http.StatusOK, data)
+}
+
+// respondGeoJSON отдает коллекцию GeoJSON для депо из пути запроса
+func (h *Task3Handler) respondGeoJSON(
+	c *gin.Context,
+	build func(depoID string) (*responses.FeatureCollection, error)) {
+
+	depoID := c.Param("depo")
+
+	data, err := build(depoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to build GeoJSON: " + err.Error(),
+		})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Depot not found",
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/geo+json; charset=utf-8")
+	c.JSON(http.StatusOK, data)
+}
+
+// GetStaticMapPNG возвращает статичную карту депо в формате PNG
+// @Summary Get static depot map as PNG
+// @Description Renders depot stations, visit heat circles and top locomotive routes with a legend to a PNG image
+// @Tags task3
+// @Produce png
+// @Param depo path string true "Depot ID"
+// @Param layers query string false "Comma-separated layers: stations, heat, routes (default all)"
+// @Param width query int false "Image width in pixels (200-4000, default 1200)"
+// @Param height query int false "Image height in pixels (200-4000, default 800)"
+// @Param max query int false "Locomotives in the routes layer (1-20, default 10)"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task3/depots/{depo}/map.png [get]
+func (h *Task3Handler) GetStaticMapPNG(c *gin.Context) {
+	h.respondStaticMap(c, "image/png", (*staticmap.Map).PNG)
+}
+
+// GetStaticMapSVG возвращает статичную карту депо в формате SVG
+// @Summary Get static depot map as SVG
+// @Description Renders depot stations, visit heat circles and top locomotive routes with a legend to an SVG image
+// @Tags task3
+// @Produce image/svg+xml
+// @Param depo path string true "Depot ID"
+// @Param layers query string false "Comma-separated layers: stations, heat, routes (default all)"
+// @Param width query int false "Image width in pixels (200-4000, default 1200)"
+// @Param height query int false "Image height in pixels (200-4000, default 800)"
+// @Param max query int false "Locomotives in the routes layer (1-20, default 10)"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/task3/depots/{depo}/map.svg [get]
+func (h *Task3Handler) GetStaticMapSVG(c *gin.Context) {
+	h.respondStaticMap(c, "image/svg+xml", (*staticmap.Map).SVG)
+}
+
+// respondStaticMap строит статичную карту депо из пути запроса и отдает ее в нужном формате
+func (h *Task3Handler) respondStaticMap(
+	c *gin.Context,
+	contentType string,
+	encode func(m *staticmap.Map, w io.Writer) error) {
+
+	var req requests.StaticMapRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := domain.StaticMapOptions{
+		Width:          req.Width,
+		Height:         req.Height,
+		MaxLocomotives: req.Max,
+	}
+	if opts.Width == 0 {
+		opts.Width = 1200
+	}
+	if opts.Height == 0 {
+		opts.Height = 800
+	}
+	if opts.MaxLocomotives == 0 {
+		opts.MaxLocomotives = 10
+	}
+	for _, layer := range strings.Split(req.Layers, ",") {
+		layer = strings.TrimSpace(layer)
+		switch layer {
+		case "":
+		case domain.StaticLayerStations, domain.StaticLayerHeat, domain.StaticLayerRoutes:
+			opts.Layers = append(opts.Layers, layer)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unknown layer: " + layer,
+			})
+			return
+		}
+	}
+
+	m, err := h.task3Service.RenderStaticMap(c.Param("depo"), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to render map: " + err.Error(),
+		})
+		return
+	}
+	if m == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Depot not found",
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := encode(m, &buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to render map: " + err.Error(),
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/mbtiles"
+)
+
+type TileHandler struct {
+	tiles *mbtiles.Reader
+}
+
+func NewTileHandler(tiles *mbtiles.Reader) *TileHandler {
+	return &TileHandler{
+		tiles: tiles,
+	}
+}
+
+// GetTile возвращает тайл подложки из локального файла MBTiles
+// @Summary Get basemap tile
+// @Description Returns a basemap tile (XYZ scheme) from the MBTiles file configured by MBTILES_PATH
+// @Tags tiles
+// @Produce png
+// @Param z path int true "Zoom level"
+// @Param x path int true "Tile column"
+// @Param y path string true "Tile row with the tileset format extension, e.g. 5.png"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tiles/{z}/{x}/{y}.png [get]
+func (h *TileHandler) GetTile(c *gin.Context) {
+	y := c.Param("y")
+	z, errZ := strconv.Atoi(c.Param("z"))
+	x, errX := strconv.Atoi(c.Param("x"))
+	row, errY := strconv.Atoi(strings.TrimSuffix(y, path.Ext(y)))
+	if errZ != nil || errX != nil || errY != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tile coordinates",
+		})
+		return
+	}
+
+	// Расширение - формат тайлов файла (как в URL подложки), другие форматы не отдаются
+	if !h.tiles.HasExtension(strings.TrimPrefix(path.Ext(y), ".")) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Tile not found",
+		})
+		return
+	}
+
+	data, err := h.tiles.Tile(z, x, row)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to read tile: " + err.Error(),
+		})
+		return
+	}
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Tile not found",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, h.tiles.ContentType(), data)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mihnpro/Hackathon_TMX/internal/mbtiles"
+)
+
+func TestGetTile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Тайлы png, содержимое тайла z/x/y - строка "z/x/y" (см. mbtiles/testdata/make_fixtures.py)
+	tiles, err := mbtiles.Open(filepath.Join("..", "..", "mbtiles", "testdata", "tiles.mbtiles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tiles.Close()
+
+	router := gin.New()
+	router.GET("/tiles/:z/:x/:y", NewTileHandler(tiles).GetTile)
+
+	tests := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{url: "/tiles/1/1/0.png", status: http.StatusOK, body: "1/1/0"},
+		{url: "/tiles/2/3/1.PNG", status: http.StatusOK, body: "2/3/1"},
+		{url: "/tiles/1/1/0.jpg", status: http.StatusNotFound},
+		{url: "/tiles/1/1/0.anything", status: http.StatusNotFound},
+		{url: "/tiles/1/1/0", status: http.StatusNotFound},
+		{url: "/tiles/9/0/1.png", status: http.StatusNotFound},
+		{url: "/tiles/a/1/0.png", status: http.StatusBadRequest},
+		{url: "/tiles/1/1/y.png", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("статус %d (%s), ожидалось %d", w.Code, w.Body.String(), tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if w.Body.String() != tt.body || w.Header().Get("Content-Type") != "image/png" {
+				t.Errorf("тайл %q (%s), ожидалось %q (image/png)", w.Body.String(), w.Header().Get("Content-Type"), tt.body)
+			}
+		})
+	}
+}
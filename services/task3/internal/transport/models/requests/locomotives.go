@@ -0,0 +1,29 @@
+package requests
+// internal/transport/models/requests/locomotives.go
+
+// LocomotiveListRequest поиск по каталогу локомотивов
+type LocomotiveListRequest struct {
+	Series string `form:"series"`
+	Number string `form:"number"`
+	Depo   string `form:"depo"`
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// LocomotiveProfileRequest запрос профиля локомотива
+type LocomotiveProfileRequest struct {
+	Series string `uri:"series" binding:"required"`
+	Number string `uri:"number" binding:"required"`
+}
+
+// SeriesAnalyticsRequest запрос аналитики по серии локомотивов
+type SeriesAnalyticsRequest struct {
+	Series string `uri:"series" binding:"required"`
+}
+
+// UtilizationRequest параметры отчета об использовании парка
+type UtilizationRequest struct {
+	Depo string `form:"depo"`
+	From string `form:"from"`
+	To   string `form:"to"`
+}
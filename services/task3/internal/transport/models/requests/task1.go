@@ -0,0 +1,18 @@
+package requests
+// internal/transport/models/requests/task1.go
+
+// BranchDetailsRequest запрос для получения детализации ветки депо
+type BranchDetailsRequest struct {
+	Depo     string `uri:"depo" binding:"required"`
+	BranchID string `uri:"branchId" binding:"required"`
+}
+
+// BranchDiffRequest запрос на сравнение веток двух прогонов (наборов данных или временных окон)
+type BranchDiffRequest struct {
+	BaseData   string `form:"base_data"`
+	TargetData string `form:"target_data"`
+	BaseFrom   string `form:"base_from"`
+	BaseTo     string `form:"base_to"`
+	TargetFrom string `form:"target_from"`
+	TargetTo   string `form:"target_to"`
+}
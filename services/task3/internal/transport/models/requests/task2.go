@@ -7,12 +7,46 @@ type LocomotiveDirectionRequest struct {
 	Number string `uri:"number" binding:"required"`
 }
 
+// DirectionMatchRequest параметры определения направлений и сопоставления поездок (задача 2)
+type DirectionMatchRequest struct {
+	MinSimilarity float64 `form:"min_similarity" binding:"omitempty,gt=0,lte=1"`
+	SplitVariants bool    `form:"split_variants"`
+	Baseline      string  `form:"baseline" binding:"omitempty,oneof=depot uniform"`
+}
+
+// PopularDirectionsRequest фильтры, сортировка и пагинация списка локомотивов (задача 2)
+type PopularDirectionsRequest struct {
+	Depo      string `form:"depo"`
+	Series    string `form:"series"`
+	MinTrips  int    `form:"min_trips" binding:"omitempty,min=0"`
+	Direction string `form:"direction"`
+	Sort      string `form:"sort" binding:"omitempty,oneof=trips -trips series -series number -number share -share entropy -entropy"`
+	Page      int    `form:"page" binding:"omitempty,min=1"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// DirectionHistoryRequest параметры истории направлений локомотива (задача 2)
+type DirectionHistoryRequest struct {
+	Bucket string `form:"bucket" binding:"omitempty,oneof=week month quarter"`
+}
+
+// NextDirectionRequest параметры прогноза следующего направления (задача 2)
+type NextDirectionRequest struct {
+	Order int `form:"order" binding:"omitempty,oneof=1 2"`
+}
+
+// LocomotiveClustersRequest параметры кластеризации локомотивов по профилю эксплуатации (задача 2)
+type LocomotiveClustersRequest struct {
+	Depo string `form:"depo"`
+	K    int    `form:"k" binding:"omitempty,min=1,max=10"`
+}
+
 // DepoBranchesRequest запрос для получения веток депо (задача 3)
 type DepoBranchesRequest struct {
 	DepoCode string `uri:"depoCode" binding:"required"`
 }
 
-// LocomotiveBranchesRequest запрос для получения веток конкретного локомотива (задача 3)
+// LocomotiveBranchesRequest запрос для получения веток конкретного локомотива (задача 1)
 type LocomotiveBranchesRequest struct {
 	Series string `uri:"series" binding:"required"`
 	Number string `uri:"number" binding:"required"`
@@ -0,0 +1,10 @@
+package requests
+// internal/transport/models/requests/task3.go
+
+// StaticMapRequest параметры статичной карты депо в PNG/SVG (задача 3)
+type StaticMapRequest struct {
+	Layers string `form:"layers"` // слои через запятую: stations, heat, routes (по умолчанию все)
+	Width  int    `form:"width" binding:"omitempty,min=200,max=4000"`
+	Height int    `form:"height" binding:"omitempty,min=200,max=4000"`
+	Max    int    `form:"max" binding:"omitempty,min=1,max=20"` // локомотивов в слое routes
+}
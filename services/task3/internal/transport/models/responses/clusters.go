@@ -0,0 +1,46 @@
+// internal/transport/models/responses/clusters.go
+
+package responses
+
+// ClusterProfile типичный профиль эксплуатации (средние значения признаков)
+type ClusterProfile struct {
+	Directions      []PeriodDirection `json:"directions"`
+	AvgTripStations float64           `json:"avg_trip_stations"`
+	AvgTrips        float64           `json:"avg_trips"`
+	UnmatchedShare  float64           `json:"unmatched_share"`
+}
+
+// ClusterMember локомотив в кластере
+type ClusterMember struct {
+	Model    string  `json:"model"`
+	Number   string  `json:"number"`
+	Trips    int     `json:"trips"`
+	Distance float64 `json:"distance"`
+	Outlier  bool    `json:"outlier"`
+}
+
+// LocomotiveCluster группа локомотивов со схожим профилем
+type LocomotiveCluster struct {
+	ID             int             `json:"id"`
+	Size           int             `json:"size"`
+	Profile        ClusterProfile  `json:"profile"`
+	Representative ClusterMember   `json:"representative"`
+	Locomotives    []ClusterMember `json:"locomotives"`
+}
+
+// DepotClusters кластеры локомотивов депо
+type DepotClusters struct {
+	DepoCode        string              `json:"depo_code"`
+	DepoName        string              `json:"depo_name"`
+	LocomotiveCount int                 `json:"locomotive_count"`
+	K               int                 `json:"k"`
+	OutlierDistance float64             `json:"outlier_distance"`
+	Clusters        []LocomotiveCluster `json:"clusters"`
+	Outliers        []ClusterMember     `json:"outliers"`
+}
+
+// LocomotiveClusters результат кластеризации локомотивов
+type LocomotiveClusters struct {
+	Method string          `json:"method"`
+	Depots []DepotClusters `json:"depots"`
+}
@@ -0,0 +1,22 @@
+// internal/transport/models/responses/geojson.go
+
+package responses
+
+// FeatureCollection коллекция объектов GeoJSON (RFC 7946)
+type FeatureCollection struct {
+	Type     string    `json:"type"` // всегда "FeatureCollection"
+	Features []Feature `json:"features"`
+}
+
+// Feature объект GeoJSON с геометрией и свойствами
+type Feature struct {
+	Type       string                 `json:"type"` // всегда "Feature"
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry геометрия GeoJSON: Point ([lon, lat]) или LineString ([[lon, lat], ...])
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
@@ -0,0 +1,47 @@
+// internal/transport/models/responses/locomotives.go
+
+package responses
+
+// LocomotiveSummary краткая информация о локомотиве в каталоге
+type LocomotiveSummary struct {
+	Series      string `json:"series"`
+	Number      string `json:"number"`
+	Depo        string `json:"depo"`
+	FirstSeen   string `json:"first_seen"`
+	LastSeen    string `json:"last_seen"`
+	RecordCount int    `json:"record_count"`
+	TripCount   int    `json:"trip_count"`
+}
+
+// LocomotiveList каталог локомотивов
+type LocomotiveList struct {
+	Locomotives []LocomotiveSummary `json:"locomotives"`
+	Pagination  Pagination          `json:"pagination"`
+}
+
+// LocomotiveTripSummary агрегаты по поездкам локомотива
+type LocomotiveTripSummary struct {
+	Total            int     `json:"total"`
+	Completed        int     `json:"completed"`
+	AvgDurationHours float64 `json:"avg_duration_hours"`
+	LongestHours     float64 `json:"longest_hours"`
+	LastTripStart    string  `json:"last_trip_start,omitempty"`
+}
+
+// MileageEstimate оценка пробега по прямым между станциями
+type MileageEstimate struct {
+	EstimatedKm      float64 `json:"estimated_km"`
+	Segments         int     `json:"segments"`
+	UnmappedSegments int     `json:"unmapped_segments"`
+}
+
+// LocomotiveProfile профиль локомотива
+type LocomotiveProfile struct {
+	LocomotiveSummary
+	DepoName   string                `json:"depo_name"`
+	Stations   int                   `json:"stations"`
+	Trips      LocomotiveTripSummary `json:"trips"`
+	Mileage    MileageEstimate       `json:"mileage"`
+	Directions *LocomotiveStats      `json:"directions,omitempty"`
+	Branches   *LocomotiveBranches   `json:"branches,omitempty"`
+}
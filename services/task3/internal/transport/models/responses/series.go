@@ -0,0 +1,50 @@
+// internal/transport/models/responses/series.go
+
+package responses
+
+// SeriesSummary сводка по серии локомотивов
+type SeriesSummary struct {
+	Series                string   `json:"series"`
+	LocomotiveCount       int      `json:"locomotive_count"`
+	TotalTrips            int      `json:"total_trips"`
+	AvgTripsPerLocomotive float64  `json:"avg_trips_per_locomotive"`
+	Depots                []string `json:"depots"`
+	DominatedBranches     int      `json:"dominated_branches"`
+}
+
+// SeriesList список серий локомотивов
+type SeriesList struct {
+	Series []SeriesSummary `json:"series"`
+}
+
+// SeriesDepot распределение поездок серии по направлениям депо
+type SeriesDepot struct {
+	DepoCode              string            `json:"depo_code"`
+	DepoName              string            `json:"depo_name"`
+	LocomotiveCount       int               `json:"locomotive_count"`
+	TotalTrips            int               `json:"total_trips"`
+	AvgTripsPerLocomotive float64           `json:"avg_trips_per_locomotive"`
+	Directions            []PeriodDirection `json:"directions"`
+}
+
+// SeriesBranchShare доля серии в поездках по ветке депо
+type SeriesBranchShare struct {
+	DepoCode       string  `json:"depo_code"`
+	BranchID       string  `json:"branch_id"`
+	Series         string  `json:"series"`
+	Trips          int     `json:"trips"`
+	BranchTrips    int     `json:"branch_trips"`
+	Share          float64 `json:"share"`
+	Dominant       bool    `json:"dominant"`
+	DominantSeries string  `json:"dominant_series"`
+}
+
+// SeriesAnalytics аналитика по серии локомотивов
+type SeriesAnalytics struct {
+	Series                string              `json:"series"`
+	LocomotiveCount       int                 `json:"locomotive_count"`
+	TotalTrips            int                 `json:"total_trips"`
+	AvgTripsPerLocomotive float64             `json:"avg_trips_per_locomotive"`
+	Depots                []SeriesDepot       `json:"depots"`
+	Branches              []SeriesBranchShare `json:"branches"`
+}
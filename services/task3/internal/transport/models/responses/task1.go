@@ -7,17 +7,25 @@ type Task1Response struct {
 }
 
 type DepotBranches struct {
-	DepoCode    string        `json:"depo_code"`
-	BranchCount int           `json:"branch_count"`
-	Branches    []BranchInfo  `json:"branches"`
+	DepoCode     string        `json:"depo_code"`
+	BranchCount  int           `json:"branch_count"`
+	QualityScore float64       `json:"quality_score"` // сводная оценка кластеризации (0..1)
+	Branches     []BranchInfo  `json:"branches"`
 }
 
 type BranchInfo struct {
-	BranchID      string            `json:"branch_id"`
+	BranchID      string            `json:"branch_id"` // <первая>_to_<последняя станция>; совпадающие ID в депо получают суффикс _2, _3, ...
 	CoreStations  []string          `json:"core_stations"`
 	StationCount  int               `json:"station_count"`
 	Terminals     []TerminalInfo    `json:"terminals"`
 	ExamplePath   []string          `json:"example_path"`
+	Quality       BranchQualityInfo `json:"quality"`
+}
+
+type BranchQualityInfo struct {
+	Cohesion   float64  `json:"cohesion"`             // средняя схожесть путей внутри ветки
+	Separation *float64 `json:"separation,omitempty"` // отличие от ближайшей другой ветки (нет, если ветка в депо одна)
+	Coverage   float64  `json:"coverage"`             // доля путей, полностью покрытых core_stations
 }
 
 type TerminalInfo struct {
@@ -39,4 +47,107 @@ type LongestBranch struct {
 	Length      int      `json:"length"`
 	Route       []string `json:"route"`
 	RouteString string   `json:"route_string"`
-}
\ No newline at end of file
+}
+
+type BranchDetails struct {
+	DepoCode     string              `json:"depo_code"`
+	BranchID     string              `json:"branch_id"`
+	CoreStations []string            `json:"core_stations"`
+	StationCount int                 `json:"station_count"`
+	TotalTrips   int                 `json:"total_trips"`
+	Locomotives  []BranchLocomotive  `json:"locomotives"`
+	WeeklyUsage  []BranchWeeklyUsage `json:"weekly_usage"`
+	PathVariants []PathVariant       `json:"path_variants"`
+}
+
+type BranchLocomotive struct {
+	Series    string `json:"series"`
+	Number    string `json:"number"`
+	TripCount int    `json:"trip_count"`
+	FirstTrip string `json:"first_trip"`
+	LastTrip  string `json:"last_trip"`
+}
+
+type BranchWeeklyUsage struct {
+	WeekStart   string         `json:"week_start"` // понедельник недели, YYYY-MM-DD
+	Trips       int            `json:"trips"`
+	Locomotives int            `json:"locomotives"`
+	Terminals   []TerminalInfo `json:"terminals"` // распределение конечных станций за неделю
+}
+
+type PathVariant struct {
+	Path      []string `json:"path"`
+	Stations  int      `json:"stations"`
+	Trips     int      `json:"trips"`
+	Frequency float64  `json:"frequency"` // процент поездок ветки по этому варианту
+}
+
+type LocomotiveBranches struct {
+	Series      string                  `json:"series"`
+	Number      string                  `json:"number"`
+	DepoCode    string                  `json:"depo_code"`
+	DepoName    string                  `json:"depo_name"`
+	TotalTrips  int                     `json:"total_trips"`
+	BranchTrips int                     `json:"branch_trips"` // поездки, отнесенные к веткам
+	BranchCount int                     `json:"branch_count"`
+	Branches    []LocomotiveBranchUsage `json:"branches"`
+}
+
+type LocomotiveBranchUsage struct {
+	BranchID     string   `json:"branch_id"`
+	CoreStations []string `json:"core_stations"`
+	StationCount int      `json:"station_count"`
+	Trips        int      `json:"trips"`
+	Share        float64  `json:"share"` // процент поездок локомотива по этой ветке
+	FirstTrip    string   `json:"first_trip"`
+	LastTrip     string   `json:"last_trip"`
+}
+
+type BranchDiff struct {
+	Base    BranchRunInfo     `json:"base"`
+	Target  BranchRunInfo     `json:"target"`
+	Summary BranchDiffSummary `json:"summary"`
+	Depots  []DepotBranchDiff `json:"depots"`
+}
+
+type BranchRunInfo struct {
+	Source        string `json:"source"`
+	From          string `json:"from,omitempty"`
+	To            string `json:"to,omitempty"`
+	TotalBranches int    `json:"total_branches"`
+}
+
+type BranchDiffSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Modified  int `json:"modified"`
+	Unchanged int `json:"unchanged"`
+}
+
+type DepotBranchDiff struct {
+	DepoCode  string          `json:"depo_code"`
+	DepoName  string          `json:"depo_name"`
+	Added     []BranchSummary `json:"added"`
+	Removed   []BranchSummary `json:"removed"`
+	Modified  []BranchChange  `json:"modified"`
+	Unchanged int             `json:"unchanged"`
+}
+
+type BranchSummary struct {
+	BranchID     string   `json:"branch_id"`
+	CoreStations []string `json:"core_stations"`
+	Terminals    []string `json:"terminals"`
+	Trips        int      `json:"trips"`
+}
+
+type BranchChange struct {
+	BaseBranchID     string   `json:"base_branch_id"`
+	TargetBranchID   string   `json:"target_branch_id"`
+	Overlap          float64  `json:"overlap"` // схожесть core_stations двух прогонов (0..1)
+	AddedStations    []string `json:"added_stations"`
+	RemovedStations  []string `json:"removed_stations"`
+	AddedTerminals   []string `json:"added_terminals"`
+	RemovedTerminals []string `json:"removed_terminals"`
+	BaseTrips        int      `json:"base_trips"`
+	TargetTrips      int      `json:"target_trips"`
+}
@@ -9,6 +9,21 @@ type DirectionInfo struct {
     TerminalName   string `json:"terminal_name"`
     Frequency      int    `json:"frequency"`
     LocomotiveCount int    `json:"locomotive_count"`
+    MatchedTrips   int     `json:"matched_trips"`
+    AvgConfidence  float64 `json:"avg_confidence"`
+    ParentID       string  `json:"parent_id,omitempty"`
+    VariantCount   int     `json:"variant_count"`
+    Variants       []RouteVariantInfo `json:"variants"`
+}
+
+// RouteVariantInfo вариант маршрута внутри направления
+type RouteVariantInfo struct {
+    ID              string   `json:"id"`
+    Route           []string `json:"route"`
+    Stations        int      `json:"stations"`
+    Frequency       int      `json:"frequency"`
+    Share           float64  `json:"share"`
+    LocomotiveCount int      `json:"locomotive_count"`
 }
 
 type LocomotiveDirection struct {
@@ -18,6 +33,7 @@ type LocomotiveDirection struct {
     TerminalName string  `json:"terminal_name"`
     Visits       int     `json:"visits"`
     Percentage   float64 `json:"percentage"`
+    AvgConfidence float64 `json:"avg_confidence"`
 }
 
 type MostPopularDirection struct {
@@ -25,16 +41,44 @@ type MostPopularDirection struct {
     DirectionName string  `json:"direction_name"`
     Visits        int     `json:"visits"`
     Percentage    float64 `json:"percentage"`
+    AvgConfidence float64 `json:"avg_confidence"`
+}
+
+// TripMatch поездка локомотива с сопоставленным направлением
+type TripMatch struct {
+    StartTime     string  `json:"start_time"`
+    EndTime       string  `json:"end_time"`
+    Stations      int     `json:"stations"`
+    Terminal      string  `json:"terminal"`
+    DirectionID   string  `json:"direction_id,omitempty"`
+    DirectionName string  `json:"direction_name,omitempty"`
+    VariantID     string  `json:"variant_id,omitempty"`
+    Confidence    float64 `json:"confidence"`
 }
 
 type LocomotiveStats struct {
+    Rank         int                    `json:"rank,omitempty"` // место в отсортированном списке всех депо (только в списке)
     Model        string                 `json:"model"`
     Number       string                 `json:"number"`
     Depo         string                 `json:"depo"`
     DepoName     string                 `json:"depo_name"`
     TotalTrips   int                    `json:"total_trips"`
+    UnmatchedTrips int                  `json:"unmatched_trips"`
     Directions   []LocomotiveDirection  `json:"directions"`
     MostPopular  *MostPopularDirection  `json:"most_popular,omitempty"`
+    Trips        []TripMatch            `json:"trips,omitempty"`
+    FavoriteLabel string                `json:"favorite_label"`
+    Significance FavoriteSignificance   `json:"significance"`
+}
+
+// FavoriteSignificance статистическая значимость любимого направления
+type FavoriteSignificance struct {
+    Baseline          string  `json:"baseline"`
+    ChiSquare         float64 `json:"chi_square"`
+    DegreesOfFreedom  int     `json:"degrees_of_freedom"`
+    PValue            float64 `json:"p_value"`
+    Entropy           float64 `json:"entropy"`
+    NormalizedEntropy float64 `json:"normalized_entropy"`
 }
 
 type DepotResponse struct {
@@ -53,9 +97,95 @@ type OverallStats struct {
     LocomotivesWithFavoritePercent float64 `json:"locomotives_with_favorite_percent"`
     LocomotivesSingleDirection int `json:"locomotives_single_direction"`
     LocomotivesSingleDirectionPercent float64 `json:"locomotives_single_direction_percent"`
+    StrongFavorite        int     `json:"strong_favorite"`
+    StrongFavoritePercent float64 `json:"strong_favorite_percent"`
+    WeakFavorite          int     `json:"weak_favorite"`
+    WeakFavoritePercent   float64 `json:"weak_favorite_percent"`
+    NoFavorite            int     `json:"no_favorite"`
+    NoFavoritePercent     float64 `json:"no_favorite_percent"`
+    AvgEntropy            float64 `json:"avg_entropy"`
 }
 
 type Task2Response struct {
     Depots       []DepotResponse `json:"depots"`
     OverallStats OverallStats    `json:"overall_stats"`
-}
\ No newline at end of file
+    MatchThreshold float64       `json:"match_threshold"`
+    SplitVariants  bool          `json:"split_variants"`
+    AvailableDepots []string     `json:"available_depots"`
+    Pagination     Pagination    `json:"pagination"`
+}
+
+// Pagination сведения о странице списка локомотивов
+type Pagination struct {
+    Page       int    `json:"page"`
+    Limit      int    `json:"limit"`
+    Total      int    `json:"total"`
+    TotalPages int    `json:"total_pages"`
+    Sort       string `json:"sort"`
+}
+
+// PeriodDirection направление локомотива за период
+type PeriodDirection struct {
+    ID     string  `json:"id"`
+    Name   string  `json:"name"`
+    Visits int     `json:"visits"`
+    Share  float64 `json:"share"`
+}
+
+// DirectionPeriod распределение поездок локомотива по направлениям за период
+type DirectionPeriod struct {
+    PeriodStart    string            `json:"period_start"`
+    Trips          int               `json:"trips"`
+    UnmatchedTrips int               `json:"unmatched_trips"`
+    Favorite       *PeriodDirection  `json:"favorite,omitempty"`
+    Directions     []PeriodDirection `json:"directions"`
+}
+
+// DirectionSwitch смена любимого направления локомотива
+type DirectionSwitch struct {
+    PeriodStart string  `json:"period_start"`
+    FromID      string  `json:"from_id"`
+    FromName    string  `json:"from_name"`
+    ToID        string  `json:"to_id"`
+    ToName      string  `json:"to_name"`
+    Share       float64 `json:"share"`
+}
+
+// LocomotiveDirectionHistory изменение любимого направления локомотива во времени
+type LocomotiveDirectionHistory struct {
+    Model    string            `json:"model"`
+    Number   string            `json:"number"`
+    Depo     string            `json:"depo"`
+    DepoName string            `json:"depo_name"`
+    Bucket   string            `json:"bucket"`
+    Periods  []DirectionPeriod `json:"periods"`
+    Switches []DirectionSwitch `json:"switches"`
+}
+
+// DirectionRef ссылка на направление
+type DirectionRef struct {
+    ID   string `json:"id"`
+    Name string `json:"name"`
+}
+
+// DirectionProbability вероятность следующего направления
+type DirectionProbability struct {
+    ID           string  `json:"id"`
+    Name         string  `json:"name"`
+    Probability  float64 `json:"probability"`
+    Observations int     `json:"observations"`
+}
+
+// NextDirectionPrediction прогноз следующего направления локомотива (цепь Маркова)
+type NextDirectionPrediction struct {
+    Model          string                 `json:"model"`
+    Number         string                 `json:"number"`
+    Depo           string                 `json:"depo"`
+    DepoName       string                 `json:"depo_name"`
+    Order          int                    `json:"order"`
+    Source         string                 `json:"source"`
+    HistoryLength  int                    `json:"history_length"`
+    LastDirections []DirectionRef         `json:"last_directions"`
+    MostLikely     *DirectionProbability  `json:"most_likely,omitempty"`
+    Predictions    []DirectionProbability `json:"predictions"`
+}
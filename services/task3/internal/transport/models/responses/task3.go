@@ -3,26 +3,87 @@ package responses
 type GenerateMapsRequest struct {
 	DepoID         string `json:"depo_id" binding:"required"`
 	MaxLocomotives int    `json:"max_locomotives" binding:"min=1,max=20"`
+	Basemap        string `json:"basemap" binding:"omitempty,oneof=osm local"` // osm (по умолчанию) или local - подложка без тайлов
+
+	// Тепловая карта
+	HeatMetric string   `json:"heat_metric" binding:"omitempty,oneof=visits total_visits locomotives dwell trips_per_day"`
+	From       string   `json:"from"`   // начало периода (YYYY-MM-DD или RFC3339)
+	To         string   `json:"to"`     // конец периода, не включительно
+	Series     []string `json:"series"` // серии локомотивов, пусто - все
+
+	TopSegments int `json:"top_segments" binding:"omitempty,min=1,max=100"` // размер таблицы загруженных перегонов (по умолчанию 20)
+
+	Strict bool `json:"strict"` // не выдумывать координаты: без station_info.csv - ошибка
 }
 
 type GenerateMapsResponse struct {
+	JobID       string    `json:"job_id"`               // задание генерации, карты лежат в /maps/{job_id}/
 	DepotID     string    `json:"depot_id"`
 	GeneratedAt string    `json:"generated_at"`
+	ExpiresAt   string    `json:"expires_at,omitempty"` // после этого момента карты будут удалены
+	HeatMetric  string    `json:"heat_metric"`          // метрика тепловой карты
 	Maps        MapsList  `json:"maps"`
+
+	CoordinatesGenerated bool              `json:"coordinates_generated"` // координаты станций вымышленные (нестрогий режим без station_info.csv)
+	UnmappedStations     []UnmappedStation `json:"unmapped_stations"`     // станции без координат, не показанные на картах
+	UnmappedRecords      int               `json:"unmapped_records"`      // отметок на станциях без координат
+}
+
+// UnmappedStation - станция из отметок локомотивов, для которой нет координат
+type UnmappedStation struct {
+	StationID   string `json:"station_id"`
+	Records     int    `json:"records"`     // отметок на станции
+	Locomotives int    `json:"locomotives"` // разных локомотивов
+}
+
+// MapJobAccepted - задание генерации карт поставлено в очередь
+type MapJobAccepted struct {
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	EventsURL string `json:"events_url"` // прогресс генерации (Server-Sent Events)
+	JobURL    string `json:"job_url"`    // файлы задания после завершения
+}
+
+// MapJobEvent - событие задания генерации карт: progress, done (с результатом) или error
+type MapJobEvent struct {
+	Event   string                `json:"event"`
+	Stage   int                   `json:"stage,omitempty"` // номер этапа генерации
+	Total   int                   `json:"total,omitempty"` // всего этапов
+	Message string                `json:"message,omitempty"`
+	Result  *GenerateMapsResponse `json:"result,omitempty"`
+	Error   string                `json:"error,omitempty"`
+}
+
+// MapJobResponse - задание генерации карт и его файлы
+type MapJobResponse struct {
+	GenerateMapsResponse
+	Status    string        `json:"status"`          // running, done или failed
+	Error     string        `json:"error,omitempty"` // причина ошибки для failed
+	Artifacts []MapArtifact `json:"artifacts"`
+}
+
+type MapArtifact struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"` // байт
 }
 
 type MapsList struct {
 	Overview    string          `json:"overview"`     // ссылка на общую карту
 	Heatmap     string          `json:"heatmap"`      // ссылка на тепловую карту
+	Branches    string          `json:"branches"`     // ссылка на карту веток депо (задача 1)
+	Flow        string          `json:"flow"`         // ссылка на карту потоков по перегонам
+	Segments    string          `json:"segments"`     // ссылка на таблицу самых загруженных перегонов (CSV)
 	Locomotives []LocomotiveMap `json:"locomotives"`  // карты отдельных локомотивов
 }
 
 type LocomotiveMap struct {
-	Key       string `json:"key"`        // "ВЛ80С_12453"
-	Model     string `json:"model"`
-	Number    string `json:"number"`
-	URL       string `json:"url"`        // ссылка на HTML карту
-	TripCount int    `json:"trip_count"`
+	Key         string `json:"key"` // "ВЛ80С_12453"
+	Model       string `json:"model"`
+	Number      string `json:"number"`
+	URL         string `json:"url"`                    // ссылка на HTML карту
+	PlaybackURL string `json:"playback_url,omitempty"` // ссылка на карту воспроизведения перемещений
+	TripCount   int    `json:"trip_count"`
 }
 
 type DepotInfo struct {
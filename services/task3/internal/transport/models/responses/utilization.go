@@ -0,0 +1,49 @@
+// internal/transport/models/responses/utilization.go
+
+package responses
+
+// LocomotiveUtilization использование локомотива за период
+type LocomotiveUtilization struct {
+	Series            string  `json:"series"`
+	Number            string  `json:"number"`
+	Depo              string  `json:"depo"`
+	ObservedDays      int     `json:"observed_days"`
+	ActiveDays        int     `json:"active_days"`
+	DepotDays         int     `json:"depot_days"`
+	ActiveShare       float64 `json:"active_share"`
+	Trips             int     `json:"trips"`
+	TripsPerActiveDay float64 `json:"trips_per_active_day"`
+	LongestIdleHours  float64 `json:"longest_idle_hours"`
+	LongestIdleStart  string  `json:"longest_idle_start,omitempty"`
+}
+
+// DailyUtilization количество локомотивов депо на линии и в депо за день
+type DailyUtilization struct {
+	Date    string `json:"date"`
+	OnLine  int    `json:"on_line"`
+	AtDepot int    `json:"at_depot"`
+}
+
+// DepotUtilization использование парка депо за период
+type DepotUtilization struct {
+	DepoCode              string                  `json:"depo_code"`
+	DepoName              string                  `json:"depo_name"`
+	LocomotiveCount       int                     `json:"locomotive_count"`
+	ObservedDays          int                     `json:"observed_days"`
+	ActiveDays            int                     `json:"active_days"`
+	DepotDays             int                     `json:"depot_days"`
+	ActiveShare           float64                 `json:"active_share"`
+	Trips                 int                     `json:"trips"`
+	TripsPerActiveDay     float64                 `json:"trips_per_active_day"`
+	LongestIdleHours      float64                 `json:"longest_idle_hours"`
+	LongestIdleLocomotive string                  `json:"longest_idle_locomotive,omitempty"`
+	Daily                 []DailyUtilization      `json:"daily"`
+	Locomotives           []LocomotiveUtilization `json:"locomotives"`
+}
+
+// UtilizationReport отчет об использовании парка
+type UtilizationReport struct {
+	From   string             `json:"from,omitempty"`
+	To     string             `json:"to,omitempty"`
+	Depots []DepotUtilization `json:"depots"`
+}
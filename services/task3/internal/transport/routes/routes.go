@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	
+	"github.com/mihnpro/Hackathon_TMX/internal/mapassets"
 	"github.com/mihnpro/Hackathon_TMX/internal/transport/handlers"
 )
 
@@ -12,17 +13,27 @@ func SetupAllRoutes(
 	task1Handler *handlers.Task1Handler,
 	task2Handler *handlers.Task2Handler,
 	task3Handler *handlers.Task3Handler,
+	locomotiveHandler *handlers.LocomotiveHandler,
 	mlHandler *handlers.MLHandler, // НОВОЕ: добавляем ML handler
+	tileHandler *handlers.TileHandler, // nil - локальные тайлы не настроены
 	mapsDir string,
 ) {
 	// Настраиваем API маршруты
-	setupAPIRoutes(router, task1Handler, task2Handler, task3Handler, mlHandler)
+	setupAPIRoutes(router, task1Handler, task2Handler, task3Handler, locomotiveHandler, mlHandler)
 	
 	// Настраиваем фронтенд маршруты
 	setupFrontendRoutes(router)
 	
 	// Раздаем сгенерированные карты из временной директории
 	router.Static("/maps", mapsDir)
+
+	// Встроенные ресурсы Leaflet для карт (без обращения к CDN)
+	router.GET("/static/map/*filepath", gin.WrapH(mapassets.Handler("/static/map")))
+
+	// Тайлы подложки из локального MBTiles
+	if tileHandler != nil {
+		router.GET("/tiles/:z/:x/:y", tileHandler.GetTile)
+	}
 	
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -38,6 +49,7 @@ func setupAPIRoutes(
 	task1Handler *handlers.Task1Handler,
 	task2Handler *handlers.Task2Handler,
 	task3Handler *handlers.Task3Handler,
+	locomotiveHandler *handlers.LocomotiveHandler,
 	mlHandler *handlers.MLHandler, // НОВОЕ
 ) {
 	api := router.Group("/api/v1")
@@ -48,18 +60,39 @@ func setupAPIRoutes(
 			task1.GET("/branches", task1Handler.GetBranchAnalysis)
 			task1.GET("/depots", task1Handler.GetAllDepots)
 			task1.GET("/depots/:depo/branches", task1Handler.GetDepotBranches)
+			task1.GET("/depots/:depo/branches/:branchId", task1Handler.GetBranchDetails)
+			task1.GET("/locomotives/:series/:number/branches", task1Handler.GetLocomotiveBranches)
+			task1.GET("/diff", task1Handler.CompareBranches)
 		}
 		
 		// ========== ЗАДАНИЕ 2 ==========
 		api.GET("/popular-direction", task2Handler.GetPopularDirections)
 		api.GET("/locomotives/:series/:number/popular-direction", task2Handler.GetLocomotivePopularDirection)
+		api.GET("/locomotives/:series/:number/direction-history", task2Handler.GetLocomotiveDirectionHistory)
+		api.GET("/locomotives/:series/:number/next-direction", task2Handler.GetLocomotiveNextDirection)
+		api.GET("/locomotive-clusters", task2Handler.GetLocomotiveClusters)
+		
+		// ========== КАТАЛОГ ЛОКОМОТИВОВ ==========
+		api.GET("/locomotives", locomotiveHandler.ListLocomotives)
+		api.GET("/locomotives/:series/:number", locomotiveHandler.GetLocomotiveProfile)
+		api.GET("/series", locomotiveHandler.ListSeries)
+		api.GET("/series/:series", locomotiveHandler.GetSeriesAnalytics)
+		api.GET("/utilization", locomotiveHandler.GetUtilizationReport)
 		
 		// ========== ЗАДАНИЕ 3 ==========
 		task3 := api.Group("/task3")
 		{
 			task3.GET("/depots", task3Handler.GetAvailableDepots)
 			task3.GET("/depots/:depo", task3Handler.GetDepotInfo)
+			task3.GET("/depots/:depo/stations.geojson", task3Handler.GetStationsGeoJSON)
+			task3.GET("/depots/:depo/routes.geojson", task3Handler.GetRoutesGeoJSON)
+			task3.GET("/depots/:depo/heat.geojson", task3Handler.GetHeatGeoJSON)
+			task3.GET("/depots/:depo/map.png", task3Handler.GetStaticMapPNG)
+			task3.GET("/depots/:depo/map.svg", task3Handler.GetStaticMapSVG)
+			task3.GET("/locomotives/:series/:number/track.geojson", task3Handler.GetLocomotiveTrackGeoJSON)
 			task3.POST("/generate", task3Handler.GenerateMaps)
+			task3.GET("/maps/:jobId", task3Handler.GetMapJob)
+			task3.GET("/jobs/:id/events", task3Handler.GetJobEvents)
 		}
 		
 		// ========== НОВОЕ: ML INTEGRATION ==========
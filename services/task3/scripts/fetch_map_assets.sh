@@ -0,0 +1,23 @@
+#!/bin/sh
+# Скачивает Leaflet и leaflet.heat в internal/mapassets/vendor для встраивания в бинарник.
+# Запускать из services/task3 до go build; скачанные файлы коммитятся в репозиторий.
+set -e
+
+DIR="internal/mapassets/vendor"
+mkdir -p "$DIR"
+
+fetch() {
+    echo "⬇️  $2"
+    if command -v curl >/dev/null 2>&1; then
+        curl -fsSL -o "$DIR/$1" "$2"
+    else
+        wget -q -O "$DIR/$1" "$2"
+    fi
+}
+
+fetch leaflet.css "https://unpkg.com/leaflet@1.9.4/dist/leaflet.css"
+fetch leaflet.js "https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"
+fetch leaflet-heat.js "https://unpkg.com/leaflet.heat@0.2.0/dist/leaflet-heat.js"
+fetch LICENSE-leaflet "https://unpkg.com/leaflet@1.9.4/LICENSE"
+
+echo "✅ Ресурсы карт сохранены в $DIR"